@@ -1,37 +1,105 @@
+/*
+Copyright © 2022 NAME HERE <EMAIL ADDRESS>
+
+*/
+
 // Package cmd implements commands.
 package cmd
 
 import (
 	"fmt"
 
+	"github.com/sboehler/got/pkg/repository"
 	"github.com/spf13/cobra"
 )
 
-// addCmd represents the add command
-var addCmd = &cobra.Command{
-	Use:   "add",
-	Short: "A brief description of your command",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
+var (
+	addAll    bool
+	addUpdate bool
 
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("add called")
-	},
-}
+	// addCmd represents the add command
+	addCmd = &cobra.Command{
+		Use:   "add [-A | -u] [PATH...]",
+		Short: "Add file contents to the index",
+		Long: `Add the current content of the given files to the index. Directories are
+added recursively, and files which were deleted from the worktree are
+removed from the index. With -A, the whole worktree is added. With -u,
+only files already in the index are updated or removed, and untracked
+files are left alone; without paths, it applies to the whole worktree.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if addAll && addUpdate {
+				return fmt.Errorf("-A and -u are mutually exclusive")
+			}
+			if len(args) == 0 && !addAll && !addUpdate {
+				return fmt.Errorf("nothing specified, nothing added")
+			}
+			r, err := openRepository()
+			if err != nil {
+				return err
+			}
+			if err := r.RequireWorktree(); err != nil {
+				return err
+			}
+			specs := []string{""}
+			if len(args) > 0 {
+				specs = specs[:0]
+				for _, arg := range args {
+					spec, err := r.RelPath(arg)
+					if err != nil {
+						return err
+					}
+					specs = append(specs, spec)
+				}
+			}
+			idx, err := r.ReadIndex()
+			if err != nil {
+				return err
+			}
+			tracked := make(map[string]bool)
+			for _, e := range idx.Entries {
+				tracked[e.Path] = true
+			}
+			for _, spec := range specs {
+				files, err := r.WorktreeFiles(spec, idx)
+				if err != nil {
+					return err
+				}
+				present := make(map[string]bool)
+				for _, f := range files {
+					present[f] = true
+					if addUpdate && !tracked[f] {
+						continue
+					}
+					changed, err := r.StageFile(idx, f)
+					if err != nil {
+						return err
+					}
+					if changed {
+						verbosef("add '%s'\n", f)
+					}
+				}
+				var matched bool
+				for _, e := range append(idx.Entries[:0:0], idx.Entries...) {
+					if !repository.InPath(e.Path, spec) {
+						continue
+					}
+					matched = true
+					if !present[e.Path] && idx.Remove(e.Path) {
+						verbosef("remove '%s'\n", e.Path)
+					}
+				}
+				// the whole worktree matches even if it is empty
+				if len(files) == 0 && !matched && spec != "" {
+					return fmt.Errorf("pathspec '%s' did not match any files", spec)
+				}
+			}
+			return r.WriteIndex(idx)
+		},
+	}
+)
 
 func init() {
+	addCmd.Flags().BoolVarP(&addAll, "all", "A", false, "add all files in the worktree")
+	addCmd.Flags().BoolVarP(&addUpdate, "update", "u", false, "only update files which are already in the index")
 	rootCmd.AddCommand(addCmd)
-
-	// Here you will define your flags and configuration settings.
-
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// addCmd.PersistentFlags().String("foo", "", "A help for foo")
-
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// addCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAddAllEmpty(t *testing.T) {
+	r := newCmdTestRepo(t)
+	mustRunGot(t, r.Worktree, "add", "-A")
+	if paths := indexPaths(t, r); len(paths) != 0 {
+		t.Errorf("got index %v, want it empty", paths)
+	}
+	if _, err := runGot(t, r.Worktree, "", "add", "missing"); err == nil || err.Error() != "pathspec 'missing' did not match any files" {
+		t.Errorf("got error %v for a missing path", err)
+	}
+}
+
+func TestAddAllDeleted(t *testing.T) {
+	r := newCmdTestRepo(t)
+	writeFile(t, r, "a", "a\n")
+	writeFile(t, r, "dir/b", "b\n")
+	mustRunGot(t, r.Worktree, "add", "-A")
+	if got, want := indexPaths(t, r), []string{"a", "dir/b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got index %v, want %v", got, want)
+	}
+	for _, p := range []string{"a", "dir"} {
+		if err := os.RemoveAll(filepath.Join(r.Worktree, p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustRunGot(t, r.Worktree, "add", "-A")
+	if paths := indexPaths(t, r); len(paths) != 0 {
+		t.Errorf("got index %v, want all files removed", paths)
+	}
+}
+
+func TestAddUpdate(t *testing.T) {
+	r := newCmdTestRepo(t)
+	writeFile(t, r, "deleted", "deleted\n")
+	writeFile(t, r, "modified", "old\n")
+	mustRunGot(t, r.Worktree, "add", "deleted", "modified")
+	if err := os.Remove(filepath.Join(r.Worktree, "deleted")); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, r, "modified", "new\n")
+	writeFile(t, r, "untracked", "untracked\n")
+	before, err := r.ReadIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mustRunGot(t, r.Worktree, "add", "-u")
+	idx, err := r.ReadIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := indexPaths(t, r), []string{"modified"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got index %v, want %v", got, want)
+	}
+	if e, ok := idx.Entry("modified", 0); !ok || e.SHA == before.Entries[1].SHA {
+		t.Errorf("the modified file was not staged")
+	}
+	// with paths, -u applies to them only
+	mustRunGot(t, r.Worktree, "add", "-u", "untracked")
+	if got, want := indexPaths(t, r), []string{"modified"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got index %v after adding an untracked path, want %v", got, want)
+	}
+	if _, err := runGot(t, r.Worktree, "", "add", "-A", "-u"); err == nil {
+		t.Error("add -A -u succeeded")
+	}
+}
@@ -0,0 +1,101 @@
+// Package cmd implements commands.
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/sboehler/got/pkg/patch"
+	"github.com/sboehler/got/pkg/repository"
+	"github.com/spf13/cobra"
+)
+
+var (
+	applyStrip int
+
+	// applyCmd represents the apply command
+	applyCmd = &cobra.Command{
+		Use:   "apply [PATCH]",
+		Short: "Apply a patch to files in the working tree",
+		Long: `Read a unified diff from the given file or from stdin and apply it to
+the working tree. Either all files are patched or, if a hunk does not
+apply, none are.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			in := cmd.InOrStdin()
+			if len(args) == 1 && args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				in = f
+			}
+			files, err := patch.Parse(in, applyStrip)
+			if err != nil {
+				return err
+			}
+			r, err := openRepository()
+			if err != nil {
+				return err
+			}
+			if err := r.RequireWorktree(); err != nil {
+				return err
+			}
+			results := make([][]byte, len(files))
+			for i, f := range files {
+				if results[i], err = applyFile(r, f); err != nil {
+					return err
+				}
+			}
+			for i, f := range files {
+				if f.NewPath == "" {
+					if err := r.RemoveWorktreeFile(f.OldPath); err != nil {
+						return err
+					}
+					continue
+				}
+				if err := r.WriteWorktreeFile(f.NewPath, results[i]); err != nil {
+					return err
+				}
+				if f.OldPath != "" && f.OldPath != f.NewPath {
+					if err := r.RemoveWorktreeFile(f.OldPath); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+		Args: cobra.MaximumNArgs(1),
+	}
+)
+
+// applyFile computes the patched content of a single file.
+func applyFile(r *repository.Repository, f *patch.File) ([]byte, error) {
+	var src []byte
+	if f.OldPath != "" {
+		var err error
+		if src, err = os.ReadFile(filepath.Join(r.Worktree, f.OldPath)); err != nil {
+			return nil, err
+		}
+	} else if _, err := os.Stat(filepath.Join(r.Worktree, f.NewPath)); err == nil {
+		return nil, fmt.Errorf("%s: already exists in working directory", f.NewPath)
+	}
+	res, err := f.Apply(src)
+	if err != nil {
+		name := f.NewPath
+		if name == "" {
+			name = f.OldPath
+		}
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	if f.NewPath == "" && len(res) > 0 {
+		return nil, fmt.Errorf("%s: deleted file still has contents", f.OldPath)
+	}
+	return res, nil
+}
+
+func init() {
+	applyCmd.Flags().IntVarP(&applyStrip, "strip", "p", 1, "remove the given number of leading path components")
+	rootCmd.AddCommand(applyCmd)
+}
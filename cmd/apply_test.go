@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// applyTestPatch is the output of git diff between the files of
+// TestApply.
+const applyTestPatch = `diff --git a/deleted b/deleted
+deleted file mode 100644
+index 286c5f5..0000000
+--- a/deleted
++++ /dev/null
+@@ -1 +0,0 @@
+-gone
+diff --git a/dir/new b/dir/new
+new file mode 100644
+index 0000000..3e75765
+--- /dev/null
++++ b/dir/new
+@@ -0,0 +1 @@
++new
+diff --git a/file b/file
+index e031777..086b3da 100644
+--- a/file
++++ b/file
+@@ -1,5 +1,5 @@
+ one
+-two
++2
+ three
+ four
+ five
+@@ -10,3 +10,4 @@ nine
+ ten
+ eleven
+ twelve
++thirteen
+`
+
+func TestApply(t *testing.T) {
+	r := newCmdTestRepo(t)
+	lines := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven\ntwelve\n"
+	writeFile(t, r, "file", lines)
+	writeFile(t, r, "deleted", "gone\n")
+	out, err := runGot(t, r.Worktree, applyTestPatch, "apply")
+	if err != nil {
+		t.Fatalf("got apply: %v\n%s", err, out)
+	}
+	want := map[string]string{
+		"file":    strings.Replace(lines, "two\n", "2\n", 1) + "thirteen\n",
+		"dir/new": "new\n",
+	}
+	for p, content := range want {
+		if bs, err := os.ReadFile(filepath.Join(r.Worktree, p)); err != nil || string(bs) != content {
+			t.Errorf("%s: got %q, %v, want %q", p, bs, err, content)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(r.Worktree, "deleted")); !os.IsNotExist(err) {
+		t.Errorf("deleted file still exists: %v", err)
+	}
+}
+
+func TestApplyMismatch(t *testing.T) {
+	r := newCmdTestRepo(t)
+	writeFile(t, r, "file", "one\nTWO\nthree\nfour\n")
+	writeFile(t, r, "deleted", "gone\n")
+	if _, err := runGot(t, r.Worktree, applyTestPatch, "apply"); err == nil {
+		t.Fatal("apply of a mismatching hunk succeeded")
+	}
+	// no file is patched if one hunk does not apply
+	if _, err := os.Stat(filepath.Join(r.Worktree, "deleted")); err != nil {
+		t.Errorf("deleted was removed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(r.Worktree, "dir")); !os.IsNotExist(err) {
+		t.Errorf("dir/new was created: %v", err)
+	}
+	if bs, _ := os.ReadFile(filepath.Join(r.Worktree, "file")); string(bs) != "one\nTWO\nthree\nfour\n" {
+		t.Errorf("file was changed to %q", bs)
+	}
+}
@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sboehler/got/pkg/repository"
+	"github.com/spf13/cobra"
+)
+
+var (
+	branchContains    string
+	branchMerged      string
+	branchNoMerged    string
+	branchDelete      bool
+	branchForceDelete bool
+	branchMove        bool
+
+	// branchCmd represents the branch command
+	branchCmd = &cobra.Command{
+		Use:   "branch [NAME [START-POINT]] | -d|-D NAME... | -m [OLD] NEW",
+		Short: "List, create, delete or rename branches",
+		Long: `Without arguments, list the local branches, marking the current one. With
+a name, create a branch at the given start point, which defaults to HEAD.
+-d deletes branches which are merged into HEAD, -D deletes them
+regardless. -m renames a branch, by default the current one.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := openRepository()
+			if err != nil {
+				return err
+			}
+			switch {
+			case branchDelete || branchForceDelete:
+				return deleteBranches(r, args, branchForceDelete)
+			case branchMove:
+				return renameBranch(r, args)
+			case len(args) > 0:
+				return createBranch(r, args)
+			}
+			defer maybePager(cmd, r)()
+			return listBranches(cmd, r)
+		},
+	}
+)
+
+// createBranch creates the branch args[0] at the start point args[1],
+// or at HEAD.
+func createBranch(r *repository.Repository, args []string) error {
+	if len(args) > 2 {
+		return fmt.Errorf("too many arguments")
+	}
+	start := "HEAD"
+	if len(args) == 2 {
+		start = args[1]
+	}
+	name := "refs/heads/" + args[0]
+	if _, err := r.ResolveRef(name); err == nil {
+		return fmt.Errorf("a branch named '%s' already exists", args[0])
+	}
+	sha, err := r.Find(start, "commit", true)
+	if errors.Cause(err) == repository.ErrRefNotFound {
+		// HEAD is unborn
+		return fmt.Errorf("not a valid object name: '%s'", start)
+	}
+	if err != nil {
+		return err
+	}
+	tx := r.NewRefTransaction()
+	tx.Message = "branch: Created from " + start
+	if err := tx.Update(name, sha, repository.ZeroSHA); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// deleteBranches deletes the given branches in a single transaction.
+// Unless force is set, only branches merged into HEAD are deleted. The
+// current branch cannot be deleted.
+func deleteBranches(r *repository.Repository, names []string, force bool) error {
+	if len(names) == 0 {
+		return fmt.Errorf("branch name required")
+	}
+	current, _ := r.SymbolicRef("HEAD")
+	var head string
+	if !force {
+		head, _ = r.ResolveRef("HEAD")
+	}
+	tx := r.NewRefTransaction()
+	defer tx.Abort()
+	shas := make([]string, len(names))
+	for i, name := range names {
+		ref := "refs/heads/" + name
+		if ref == current {
+			return fmt.Errorf("cannot delete branch '%s' checked out at '%s'", name, r.Worktree)
+		}
+		sha, err := r.ResolveRef(ref)
+		if err != nil {
+			return fmt.Errorf("branch '%s' not found", name)
+		}
+		if !force {
+			merged := false
+			if head != "" {
+				if merged, err = r.IsAncestor(sha, head); err != nil {
+					return err
+				}
+			}
+			if !merged {
+				return fmt.Errorf("the branch '%s' is not fully merged; use -D to delete it anyway", name)
+			}
+		}
+		if err := tx.Delete(ref, sha); err != nil {
+			return err
+		}
+		shas[i] = sha
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if err := r.PruneEmptyDirs(); err != nil {
+		return err
+	}
+	for i, name := range names {
+		abbrev, err := r.Abbreviate(shas[i])
+		if err != nil {
+			return err
+		}
+		infof("Deleted branch %s (was %s).\n", name, abbrev)
+	}
+	return nil
+}
+
+// renameBranch renames the branch args[0] to args[1], or the current
+// branch to args[0], and updates HEAD if it points to the branch.
+func renameBranch(r *repository.Repository, args []string) error {
+	current, err := r.SymbolicRef("HEAD")
+	if err != nil && errors.Cause(err) != repository.ErrNotSymbolicRef {
+		return err
+	}
+	var oldName, newName string
+	switch len(args) {
+	case 1:
+		if current == "" {
+			return fmt.Errorf("cannot rename the current branch while not on any")
+		}
+		oldName, newName = current, "refs/heads/"+args[0]
+	case 2:
+		oldName, newName = "refs/heads/"+args[0], "refs/heads/"+args[1]
+	default:
+		return fmt.Errorf("branch rename requires one or two names")
+	}
+	if _, err := r.ResolveRef(oldName); err != nil {
+		return fmt.Errorf("branch '%s' not found", strings.TrimPrefix(oldName, "refs/heads/"))
+	}
+	if _, err := r.ResolveRef(newName); err == nil {
+		return fmt.Errorf("a branch named '%s' already exists", strings.TrimPrefix(newName, "refs/heads/"))
+	}
+	if err := r.RenameRef(oldName, newName, fmt.Sprintf("Branch: renamed %s to %s", oldName, newName)); err != nil {
+		return err
+	}
+	if err := r.PruneEmptyDirs(); err != nil {
+		return err
+	}
+	if oldName != current {
+		return nil
+	}
+	return r.SetSymbolicRef("HEAD", newName, "")
+}
+
+// listBranches prints the local branches, filtered by --contains,
+// --merged and --no-merged, marking the current branch.
+func listBranches(cmd *cobra.Command, r *repository.Repository) error {
+	var filters []func(sha string) (bool, error)
+	if cmd.Flags().Changed("contains") {
+		commit, err := r.Resolve(branchContains)
+		if err != nil {
+			return err
+		}
+		filters = append(filters, func(sha string) (bool, error) {
+			return r.IsAncestor(commit, sha)
+		})
+	}
+	for _, f := range []struct {
+		flag, rev string
+		merged    bool
+	}{{"merged", branchMerged, true}, {"no-merged", branchNoMerged, false}} {
+		if !cmd.Flags().Changed(f.flag) {
+			continue
+		}
+		commit, err := r.Resolve(f.rev)
+		if err != nil {
+			return err
+		}
+		merged := f.merged
+		filters = append(filters, func(sha string) (bool, error) {
+			ok, err := r.IsAncestor(sha, commit)
+			return ok == merged, err
+		})
+	}
+	branches, err := r.RefsWithPrefix("refs/heads/")
+	if err != nil {
+		return err
+	}
+	head, _ := r.SymbolicRef("HEAD")
+	w := cmd.OutOrStdout()
+outer:
+	for _, b := range branches {
+		for _, f := range filters {
+			if ok, err := f(b.SHA); err != nil {
+				return err
+			} else if !ok {
+				continue outer
+			}
+		}
+		marker := " "
+		if head == b.Name {
+			marker = "*"
+		}
+		fmt.Fprintf(w, "%s %s\n", marker, strings.TrimPrefix(b.Name, "refs/heads/"))
+	}
+	return nil
+}
+
+func init() {
+	branchCmd.Flags().StringVar(&branchContains, "contains", "", "only list branches which contain the commit")
+	branchCmd.Flags().StringVar(&branchMerged, "merged", "HEAD", "only list branches whose tips are reachable from the commit")
+	branchCmd.Flags().StringVar(&branchNoMerged, "no-merged", "HEAD", "only list branches whose tips are not reachable from the commit")
+	branchCmd.Flags().Lookup("merged").NoOptDefVal = "HEAD"
+	branchCmd.Flags().Lookup("no-merged").NoOptDefVal = "HEAD"
+	branchCmd.Flags().BoolVarP(&branchDelete, "delete", "d", false, "delete merged branches")
+	branchCmd.Flags().BoolVarP(&branchForceDelete, "force-delete", "D", false, "delete branches, even if they are not merged")
+	branchCmd.Flags().BoolVarP(&branchMove, "move", "m", false, "rename a branch")
+	rootCmd.AddCommand(branchCmd)
+}
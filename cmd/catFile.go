@@ -2,37 +2,192 @@
 package cmd
 
 import (
+	"bufio"
 	"bytes"
+	"fmt"
 	"io"
-	"os"
+	"strings"
 
+	"github.com/sboehler/got/pkg/object"
 	"github.com/sboehler/got/pkg/repository"
 	"github.com/spf13/cobra"
 )
 
-// catFileCmd represents the catFile command
-var catFileCmd = &cobra.Command{
-	Use:   "cat-file TYPE OBJECT",
-	Short: "Provide content of repository objects",
-	RunE: func(cmd *cobra.Command, args []string) error {
-		wd, err := os.Getwd()
+var (
+	catFileBatch      bool
+	catFileBatchCheck bool
+	catFileBuffer     bool
+	catFileAllObjects bool
+	catFileFollow     bool
+	catFilePretty     bool
+
+	// catFileCmd represents the catFile command
+	catFileCmd = &cobra.Command{
+		Use:   "cat-file (TYPE OBJECT | -p OBJECT | --batch | --batch-check) [--batch-all-objects] [--follow-symlinks]",
+		Short: "Provide content of repository objects",
+		Long: `Print the content of an object, or the objects named on stdin in batch
+mode. With -p, the content is printed according to the object's type:
+trees are listed like ls-tree, and tags are checked to point to an
+object of the type they record. With --follow-symlinks, objects named as
+REVISION:PATH are looked up following the symbolic links in the tree of
+the revision.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			batch := catFileBatch || catFileBatchCheck
+			if catFileAllObjects && !batch {
+				return fmt.Errorf("--batch-all-objects requires --batch or --batch-check")
+			}
+			switch {
+			case batch && catFilePretty:
+				return fmt.Errorf("-p cannot be combined with a batch option")
+			case batch && len(args) != 0,
+				catFilePretty && len(args) != 1,
+				!batch && !catFilePretty && len(args) != 2:
+				return fmt.Errorf("expected either TYPE and OBJECT, -p and OBJECT or a batch option")
+			}
+			r, err := openRepository()
+			if err != nil {
+				return err
+			}
+			if batch && catFileAllObjects {
+				return catFileAllObjectsMode(r, cmd.OutOrStdout())
+			}
+			if batch {
+				// batch input often names many paths in the same tree
+				r.UseTreeCache(catFileTreeCache)
+				return catFileBatchMode(r, cmd.InOrStdin(), cmd.OutOrStdout())
+			}
+			if catFilePretty {
+				return catFilePrettyPrint(r, cmd.OutOrStdout(), args[0])
+			}
+			sha, err := catFileResolveName(r, args[1])
+			if err != nil {
+				return err
+			}
+			o, err := r.LoadObject(sha, args[0])
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(cmd.OutOrStdout(), bytes.NewReader(o.Serialize()))
+			return err
+		},
+		Args: cobra.MaximumNArgs(2),
+	}
+)
+
+// catFilePrettyPrint prints the named object according to its type.
+func catFilePrettyPrint(r *repository.Repository, w io.Writer, name string) error {
+	sha, err := catFileResolveName(r, name)
+	if err != nil {
+		return err
+	}
+	of, err := r.ReadObject(sha)
+	if err != nil {
+		return err
+	}
+	switch of.ObjectType {
+	case "tree":
+		return lsTree(r, w, sha, "")
+	case "tag":
+		o, err := r.LoadObject(sha, "tag")
 		if err != nil {
 			return err
 		}
-		r, err := repository.Find(wd)
+		if err := r.CheckTag(o.(*object.Tag)); err != nil {
+			return err
+		}
+	}
+	_, err = w.Write(of.Data)
+	return err
+}
+
+// catFileTreeCache is the number of trees kept in memory for path lookups
+// in batch mode.
+const catFileTreeCache = 1024
+
+// catFileBatchMode reads object names from in, one per line, and prints
+// the header and, unless in --batch-check mode, the content of each
+// object to out. Unless --buffer is given, output is flushed after each
+// object.
+func catFileBatchMode(r *repository.Repository, in io.Reader, out io.Writer) error {
+	var (
+		s  = bufio.NewScanner(in)
+		w  = bufio.NewWriter(out)
+		or = r.NewObjectReader()
+	)
+	for s.Scan() {
+		name := strings.TrimSpace(s.Text())
+		sha, err := catFileResolveName(r, name)
+		if err == nil {
+			err = catFileBatchObject(r, or, w, sha)
+		}
 		if err != nil {
+			fmt.Fprintf(w, "%s missing\n", name)
+		}
+		if !catFileBuffer {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// catFileAllObjectsMode prints every object in the object store in sorted
+// order, in the same format as catFileBatchMode.
+func catFileAllObjectsMode(r *repository.Repository, out io.Writer) error {
+	w := bufio.NewWriter(out)
+	or := r.NewObjectReader()
+	shas, err := r.Objects()
+	if err != nil {
+		return err
+	}
+	for _, sha := range shas {
+		if err := catFileBatchObject(r, or, w, sha); err != nil {
 			return err
 		}
-		o, err := r.LoadObject(r.Find(args[1], args[0], false), args[0])
+	}
+	return w.Flush()
+}
+
+// catFileBatchObject prints the header and, unless in --batch-check
+// mode, the content of an object. In --batch-check mode, only the
+// object's header is decompressed.
+func catFileBatchObject(r *repository.Repository, or *repository.ObjectReader, w io.Writer, sha string) error {
+	if catFileBatchCheck {
+		typ, size, err := r.ReadObjectHeader(sha)
 		if err != nil {
 			return err
 		}
-		_, err = io.Copy(cmd.OutOrStdout(), bytes.NewReader(o.Serialize()))
+		fmt.Fprintf(w, "%s %s %d\n", sha, typ, size)
+		return nil
+	}
+	of, err := or.Read(sha)
+	if err != nil {
 		return err
-	},
-	Args: cobra.ExactArgs(2),
+	}
+	fmt.Fprintf(w, "%s %s %d\n%s\n", sha, of.ObjectType, len(of.Data), of.Data)
+	return nil
+}
+
+// catFileResolveName resolves an object name, following symbolic links
+// in REVISION:PATH names with --follow-symlinks.
+func catFileResolveName(r *repository.Repository, name string) (string, error) {
+	if i := strings.IndexByte(name, ':'); catFileFollow && i > 0 {
+		sha, _, err := r.FollowSymlinks(name[:i], name[i+1:])
+		return sha, err
+	}
+	return r.Resolve(name)
 }
 
 func init() {
+	catFileCmd.Flags().BoolVar(&catFileBatch, "batch", false, "print header and content of objects named on stdin")
+	catFileCmd.Flags().BoolVar(&catFileBatchCheck, "batch-check", false, "print the header of objects named on stdin")
+	catFileCmd.Flags().BoolVar(&catFileBuffer, "buffer", false, "buffer batch output instead of flushing after each object")
+	catFileCmd.Flags().BoolVar(&catFileAllObjects, "batch-all-objects", false, "process all objects in the repository instead of reading stdin")
+	catFileCmd.Flags().BoolVarP(&catFilePretty, "pretty", "p", false, "print the object according to its type")
+	catFileCmd.Flags().BoolVar(&catFileFollow, "follow-symlinks", false, "follow symbolic links in REVISION:PATH names")
 	rootCmd.AddCommand(catFileCmd)
 }
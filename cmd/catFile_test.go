@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sboehler/got/pkg/repository"
+)
+
+func TestCatFileFollowSymlinks(t *testing.T) {
+	r := newCmdTestRepo(t)
+	writeFile(t, r, "file", "target\n")
+	if err := os.Mkdir(filepath.Join(r.Worktree, "dir"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../file", filepath.Join(r.Worktree, "dir", "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../outside", filepath.Join(r.Worktree, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	mustRunGot(t, r.Worktree, "add", "-A")
+	mustRunGot(t, r.Worktree, "commit", "-m", "links")
+
+	if got := mustRunGot(t, r.Worktree, "cat-file", "blob", "HEAD:dir/link"); got != "../file" {
+		t.Errorf("got %q, want the link target", got)
+	}
+	if got := mustRunGot(t, r.Worktree, "cat-file", "--follow-symlinks", "blob", "HEAD:dir/link"); got != "target\n" {
+		t.Errorf("got %q, want the linked file", got)
+	}
+	if _, err := runGot(t, r.Worktree, "", "cat-file", "--follow-symlinks", "blob", "HEAD:escape"); err == nil {
+		t.Error("a link leaving the tree was followed")
+	}
+}
+
+func TestCatFilePretty(t *testing.T) {
+	r := newCmdTestRepo(t)
+	writeFile(t, r, "file", "content\n")
+	mustRunGot(t, r.Worktree, "add", "file")
+	mustRunGot(t, r.Worktree, "commit", "-m", "commit")
+	blob := strings.TrimSpace(mustRunGot(t, r.Worktree, "rev-parse", "HEAD:file"))
+
+	if got, want := mustRunGot(t, r.Worktree, "cat-file", "-p", "HEAD^{tree}"), "100644 blob "+blob+"\tfile\n"; got != want {
+		t.Errorf("got tree %q, want %q", got, want)
+	}
+	if got := mustRunGot(t, r.Worktree, "cat-file", "-p", "HEAD:file"); got != "content\n" {
+		t.Errorf("got blob %q", got)
+	}
+	// a tag which claims to point to a commit, but points to a blob
+	data := fmt.Sprintf("object %s\ntype commit\ntag bad\ntagger A U Thor <author@example.com> 1600000000 +0200\n\nbad\n", blob)
+	tag, err := r.WriteObject(&repository.ObjectFile{ObjectType: "tag", Data: []byte(data)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runGot(t, r.Worktree, "", "cat-file", "-p", tag); err == nil || !strings.Contains(err.Error(), "is a blob") {
+		t.Errorf("got error %v for a tag of the wrong type", err)
+	}
+	// the raw content can still be read
+	if got := mustRunGot(t, r.Worktree, "cat-file", "tag", tag); got != data {
+		t.Errorf("got tag %q, want %q", got, data)
+	}
+}
+
+func TestCatFileBatchCheck(t *testing.T) {
+	r := newCmdTestRepo(t)
+	writeFile(t, r, "file", "content\n")
+	mustRunGot(t, r.Worktree, "add", "file")
+	blob := strings.TrimSpace(mustRunGot(t, r.Worktree, "rev-parse", ":file"))
+	out, err := runGot(t, r.Worktree, blob+"\nmissing\n", "cat-file", "--batch-check")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := blob + " blob 8\nmissing missing\n"; out != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+	if got, want := mustRunGot(t, r.Worktree, "cat-file", "--batch-check", "--batch-all-objects"), blob+" blob 8\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := mustRunGot(t, r.Worktree, "cat-file", "--batch", "--batch-all-objects"), blob+" blob 8\ncontent\n\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
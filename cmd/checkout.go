@@ -8,35 +8,128 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/sboehler/got/pkg/repository"
 	"github.com/spf13/cobra"
 )
 
-// checkoutCmd represents the checkout command
-var checkoutCmd = &cobra.Command{
-	Use:   "checkout",
-	Short: "A brief description of your command",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
-
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("checkout called")
-	},
+var (
+	checkoutNewBranch string
+
+	// checkoutCmd represents the checkout command
+	checkoutCmd = &cobra.Command{
+		Use:   "checkout [-b NEW-BRANCH] BRANCH|COMMIT",
+		Short: "Switch branches or check out commits",
+		Long: `Update the index and the worktree to the tree of the given branch or
+commit and point HEAD to it. Checking out a commit which is not a branch
+detaches HEAD. With -b, a new branch is created at the given commit, or
+at HEAD, and checked out. Files with local changes are only touched if
+they are the same in both trees; otherwise, the checkout is refused.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && checkoutNewBranch == "" {
+				return fmt.Errorf("branch or commit required")
+			}
+			r, err := openRepository()
+			if err != nil {
+				return err
+			}
+			if err := r.RequireWorktree(); err != nil {
+				return err
+			}
+			rev := "HEAD"
+			if len(args) > 0 {
+				rev = args[0]
+			}
+			var branch string
+			if checkoutNewBranch != "" {
+				branch = "refs/heads/" + checkoutNewBranch
+				if _, err := r.ResolveRef(branch); err == nil {
+					return fmt.Errorf("a branch named '%s' already exists", checkoutNewBranch)
+				}
+			} else if _, err := r.ResolveRef("refs/heads/" + rev); err == nil {
+				branch = "refs/heads/" + rev
+			}
+			sha, err := r.Find(rev, "commit", true)
+			if err != nil {
+				return err
+			}
+			current, _ := r.SymbolicRef("HEAD")
+			if branch != "" && branch == current {
+				infof("Already on '%s'\n", rev)
+				return nil
+			}
+			from, err := r.HeadTree()
+			if err != nil {
+				return err
+			}
+			c, err := r.ReadCommit(sha)
+			if err != nil {
+				return err
+			}
+			idx, err := r.ReadIndex()
+			if err != nil {
+				return err
+			}
+			if err := r.CheckoutTree(idx, from, c.Tree()); err != nil {
+				return err
+			}
+			if err := r.WriteIndex(idx); err != nil {
+				return err
+			}
+			msg := fmt.Sprintf("checkout: moving from %s to %s", headName(r), rev)
+			if checkoutNewBranch != "" {
+				msg = fmt.Sprintf("checkout: moving from %s to %s", headName(r), checkoutNewBranch)
+				tx := r.NewRefTransaction()
+				tx.Message = "branch: Created from " + rev
+				if err := tx.Update(branch, sha, repository.ZeroSHA); err != nil {
+					return err
+				}
+				if err := tx.Commit(); err != nil {
+					return err
+				}
+			}
+			if branch != "" {
+				if err := r.SetSymbolicRef("HEAD", branch, msg); err != nil {
+					return err
+				}
+				if checkoutNewBranch != "" {
+					infof("Switched to a new branch '%s'\n", checkoutNewBranch)
+				} else {
+					infof("Switched to branch '%s'\n", rev)
+				}
+				return nil
+			}
+			tx := r.NewRefTransaction()
+			tx.Message = msg
+			if err := tx.UpdateNoDeref("HEAD", sha, ""); err != nil {
+				return err
+			}
+			if err := tx.Commit(); err != nil {
+				return err
+			}
+			abbrev, err := r.Abbreviate(sha)
+			if err != nil {
+				return err
+			}
+			infof("HEAD is now at %s %s\n", abbrev, strings.SplitN(c.Message(), "\n", 2)[0])
+			return nil
+		},
+	}
+)
+
+// headName returns the name of the current branch, or the SHA of the
+// commit HEAD points to if it is detached.
+func headName(r *repository.Repository) string {
+	if branch, err := r.SymbolicRef("HEAD"); err == nil {
+		return strings.TrimPrefix(branch, "refs/heads/")
+	}
+	sha, _ := r.ResolveRef("HEAD")
+	return sha
 }
 
 func init() {
+	checkoutCmd.Flags().StringVarP(&checkoutNewBranch, "branch", "b", "", "create and check out a new branch")
 	rootCmd.AddCommand(checkoutCmd)
-
-	// Here you will define your flags and configuration settings.
-
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// checkoutCmd.PersistentFlags().String("foo", "", "A help for foo")
-
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// checkoutCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
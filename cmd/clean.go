@@ -0,0 +1,72 @@
+// Package cmd implements commands.
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	cleanForce   bool
+	cleanDirs    bool
+	cleanIgnored bool
+	cleanDryRun  bool
+
+	// cleanCmd represents the clean command
+	cleanCmd = &cobra.Command{
+		Use:   "clean (-f | -n) [-d] [-x]",
+		Short: "Remove untracked files from the worktree",
+		Long: `Remove the files in the worktree which are not tracked in the index. As
+this cannot be undone, either -f must be given to remove the files, or
+-n to only list what would be removed. Untracked directories are only
+removed with -d, and ignored files only with -x. Tracked files are
+never touched.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cleanForce && !cleanDryRun {
+				return fmt.Errorf("refusing to clean without -f or -n")
+			}
+			r, err := openRepository()
+			if err != nil {
+				return err
+			}
+			idx, err := r.ReadIndex()
+			if err != nil {
+				return err
+			}
+			files, err := r.UntrackedFiles(idx, cleanIgnored)
+			if err != nil {
+				return err
+			}
+			w := cmd.OutOrStdout()
+			for _, f := range files {
+				// without -d, untracked directories are not entered
+				if dir := path.Dir(f); !cleanDirs && (strings.HasSuffix(f, "/") || dir != "." && !idx.HasDir(dir)) {
+					continue
+				}
+				if cleanDryRun {
+					fmt.Fprintf(w, "Would remove %s\n", f)
+					continue
+				}
+				fmt.Fprintf(w, "Removing %s\n", f)
+				if err := os.RemoveAll(filepath.Join(r.Worktree, filepath.FromSlash(f))); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Args: cobra.NoArgs,
+	}
+)
+
+func init() {
+	cleanCmd.Flags().BoolVarP(&cleanForce, "force", "f", false, "remove the untracked files")
+	cleanCmd.Flags().BoolVarP(&cleanDirs, "dirs", "d", false, "remove untracked directories as well")
+	cleanCmd.Flags().BoolVarP(&cleanIgnored, "ignored", "x", false, "remove ignored files as well")
+	cleanCmd.Flags().BoolVarP(&cleanDryRun, "dry-run", "n", false, "only list the files which would be removed")
+	rootCmd.AddCommand(cleanCmd)
+}
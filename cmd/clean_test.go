@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// setupClean creates a worktree with tracked, untracked and ignored files.
+func setupClean(t *testing.T) (string, []string) {
+	t.Helper()
+	r := newCmdTestRepo(t)
+	tracked := []string{".gitignore", "dir/tracked", "tracked"}
+	writeFile(t, r, ".gitignore", "*.o\nbuild/\n")
+	writeFile(t, r, "dir/tracked", "tracked\n")
+	writeFile(t, r, "tracked", "tracked\n")
+	mustRunGot(t, r.Worktree, "add", "-A")
+	for _, p := range []string{"untracked", "dir/untracked", "newdir/a", "newdir/sub/b", "newdir2/z", "newdir2/y.o", "x.o", "build/out"} {
+		writeFile(t, r, p, "untracked\n")
+	}
+	if err := os.Mkdir(filepath.Join(r.Worktree, "empty"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	return r.Worktree, tracked
+}
+
+// worktreeFiles returns the files and empty directories in dir.
+func worktreeFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var res []string
+	err := filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, p)
+		rel = filepath.ToSlash(rel)
+		if fi.IsDir() {
+			if rel == ".git" {
+				return filepath.SkipDir
+			}
+			if entries, _ := os.ReadDir(p); len(entries) == 0 {
+				res = append(res, rel+"/")
+			}
+			return nil
+		}
+		res = append(res, rel)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		flags  []string
+		remove []string
+	}{
+		{nil, []string{"dir/untracked", "untracked"}},
+		{[]string{"-d"}, []string{"dir/untracked", "empty/", "newdir/", "newdir2/z", "untracked"}},
+		{[]string{"-x"}, []string{"dir/untracked", "untracked", "x.o"}},
+		{[]string{"-d", "-x"}, []string{"build/", "dir/untracked", "empty/", "newdir/", "newdir2/", "untracked", "x.o"}},
+	}
+	for _, test := range tests {
+		dir, tracked := setupClean(t)
+		before := worktreeFiles(t, dir)
+		if _, err := runGot(t, dir, "", append([]string{"clean"}, test.flags...)...); err == nil {
+			t.Errorf("%v: clean without -f or -n succeeded", test.flags)
+		}
+		var want string
+		for _, p := range test.remove {
+			want += "Would remove " + p + "\n"
+		}
+		if got := mustRunGot(t, dir, append([]string{"clean", "-n"}, test.flags...)...); got != want {
+			t.Errorf("%v: got\n%s\nwant\n%s", test.flags, got, want)
+		}
+		if got := worktreeFiles(t, dir); strings.Join(got, ",") != strings.Join(before, ",") {
+			t.Errorf("%v: clean -n changed the worktree to %v", test.flags, got)
+		}
+
+		mustRunGot(t, dir, append([]string{"clean", "-f"}, test.flags...)...)
+		removed := func(p string) bool {
+			for _, r := range test.remove {
+				if p == r || strings.HasSuffix(r, "/") && strings.HasPrefix(p, r) {
+					return true
+				}
+			}
+			return false
+		}
+		var kept []string
+		for _, p := range before {
+			if !removed(p) {
+				kept = append(kept, p)
+			}
+		}
+		if got := worktreeFiles(t, dir); strings.Join(got, ",") != strings.Join(kept, ",") {
+			t.Errorf("%v: got worktree %v, want %v", test.flags, got, kept)
+		}
+		for _, p := range tracked {
+			if _, err := os.Stat(filepath.Join(dir, p)); err != nil {
+				t.Errorf("%v: tracked file %s was removed", test.flags, p)
+			}
+		}
+	}
+}
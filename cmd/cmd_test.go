@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sboehler/got/pkg/repository"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// newCmdTestRepo creates a repository in a temporary directory, with a
+// fixed identity and fixed commit dates.
+func newCmdTestRepo(t *testing.T) *repository.Repository {
+	t.Helper()
+	r, err := repository.Init(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Config.Section("user").Key("name").SetValue("A U Thor")
+	r.Config.Section("user").Key("email").SetValue("author@example.com")
+	if err := r.WriteConfig(); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GIT_AUTHOR_DATE", "1600000000 +0200")
+	t.Setenv("GIT_COMMITTER_DATE", "1600000000 +0200")
+	return r
+}
+
+// resetFlags resets the flags of the command and its subcommands to
+// their defaults, as they keep their values between runs.
+func resetFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if v, ok := f.Value.(pflag.SliceValue); ok {
+			v.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	for _, c := range cmd.Commands() {
+		resetFlags(c)
+	}
+}
+
+// runGot runs got with the given arguments and stdin in dir, and returns
+// its output.
+func runGot(t *testing.T, dir, stdin string, args ...string) (string, error) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	resetFlags(rootCmd)
+	quiet = true
+	var out bytes.Buffer
+	rootCmd.SetArgs(args)
+	rootCmd.SetIn(strings.NewReader(stdin))
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&out)
+	err = rootCmd.Execute()
+	return out.String(), err
+}
+
+// mustRunGot runs got like runGot and fails the test on errors.
+func mustRunGot(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := runGot(t, dir, "", args...)
+	if err != nil {
+		t.Fatalf("got %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return out
+}
+
+func writeFile(t *testing.T, r *repository.Repository, p, content string) {
+	t.Helper()
+	abs := filepath.Join(r.Worktree, filepath.FromSlash(p))
+	if err := os.MkdirAll(filepath.Dir(abs), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(abs, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// indexPaths returns the paths in the index of the repository.
+func indexPaths(t *testing.T, r *repository.Repository) []string {
+	t.Helper()
+	idx, err := r.ReadIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res []string
+	for _, e := range idx.Entries {
+		res = append(res, e.Path)
+	}
+	return res
+}
@@ -8,35 +8,203 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/pkg/errors"
+	"github.com/sboehler/got/pkg/object"
+	"github.com/sboehler/got/pkg/repository"
+	"github.com/sboehler/got/pkg/timefmt"
 	"github.com/spf13/cobra"
 )
 
-// commitCmd represents the commit command
-var commitCmd = &cobra.Command{
-	Use:   "commit",
-	Short: "A brief description of your command",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
-
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("commit called")
-	},
-}
+var (
+	commitMessages []string
+	commitSign     bool
+	commitNoSign   bool
+	commitAuthor   string
+	commitDate     string
+	commitNoVerify bool
 
-func init() {
-	rootCmd.AddCommand(commitCmd)
+	// commitCmd represents the commit command
+	commitCmd = &cobra.Command{
+		Use:   "commit -m MESSAGE [--author AUTHOR] [--date DATE] [--no-verify]",
+		Short: "Record changes to the repository",
+		Long: `Create a new commit from the contents of the index, with the current HEAD
+as its parent, and advance the current branch to it. Multiple -m options
+are joined as separate paragraphs. --author "Name <email>" and --date
+override the author identity and date; dates are accepted in git's
+internal format, RFC 2822 or ISO 8601. The pre-commit hook runs before
+the commit is created, and the commit-msg hook with the path of a file
+holding the message, which it may edit or reject; --no-verify skips
+both.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			msg := cleanMessage(strings.Join(commitMessages, "\n\n"))
+			if msg == "" {
+				return fmt.Errorf("aborting commit due to empty commit message")
+			}
+			r, err := openRepository()
+			if err != nil {
+				return err
+			}
+			if err := r.RequireWorktree(); err != nil {
+				return err
+			}
+			state, target, err := r.CheckHead()
+			if err != nil {
+				return err
+			}
+			var parents []string
+			switch state {
+			case repository.HeadBranch:
+				sha, err := r.ResolveRef(target)
+				if err != nil {
+					return err
+				}
+				parents = append(parents, sha)
+			case repository.HeadDetached:
+				parents = append(parents, target)
+			case repository.HeadDangling:
+				return fmt.Errorf("HEAD points to invalid commit %s", target)
+			}
+			signer, err := commitSigner(r, commitSign, commitNoSign)
+			if err != nil {
+				return err
+			}
+			if !r.ReadOnly && !commitNoVerify {
+				if err := r.RunHook("pre-commit"); err != nil {
+					return err
+				}
+				if msg, err = r.RunCommitMsgHook(msg); err != nil {
+					return err
+				}
+				if msg = cleanMessage(msg); msg == "" {
+					return fmt.Errorf("aborting commit due to empty commit message")
+				}
+			}
+			idx, err := r.ReadIndex()
+			if err != nil {
+				return err
+			}
+			tree, err := r.WriteTree(idx)
+			if err != nil {
+				return err
+			}
+			if err := r.WriteIndex(idx); err != nil {
+				return err
+			}
+			if unchanged, err := sameTree(r, tree, parents); err != nil {
+				return err
+			} else if unchanged {
+				return fmt.Errorf("nothing to commit")
+			}
+			author, err := commitAuthorSignature(r)
+			if err != nil {
+				return err
+			}
+			sha, err := r.CommitTreeAs(author, tree, parents, msg, signer)
+			if err != nil {
+				return err
+			}
+			subject := strings.SplitN(msg, "\n", 2)[0]
+			tx := r.NewRefTransaction()
+			tx.Message = "commit: " + subject
+			old := repository.ZeroSHA
+			if len(parents) > 0 {
+				old = parents[0]
+			} else {
+				tx.Message = "commit (initial): " + subject
+			}
+			if err := tx.Update("HEAD", sha, old); err != nil {
+				return err
+			}
+			if err := tx.Commit(); err != nil {
+				return errors.Wrap(err, "error updating HEAD")
+			}
+			abbrev, err := r.Abbreviate(sha)
+			if err != nil {
+				return err
+			}
+			branch := "detached HEAD"
+			if state != repository.HeadDetached {
+				branch = strings.TrimPrefix(target, "refs/heads/")
+			}
+			if len(parents) == 0 {
+				branch += " (root-commit)"
+			}
+			infof("[%s %s] %s\n", branch, abbrev, subject)
+			if !r.ReadOnly {
+				return r.RunHook("post-commit")
+			}
+			return nil
+		},
+	}
+)
+
+// cleanMessage strips trailing whitespace from the lines of a commit
+// message, removes leading and trailing blank lines and terminates it
+// with a newline. An empty message remains empty.
+func cleanMessage(msg string) string {
+	lines := strings.Split(msg, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t\r")
+	}
+	msg = strings.Trim(strings.Join(lines, "\n"), "\n")
+	if msg == "" {
+		return ""
+	}
+	return msg + "\n"
+}
 
-	// Here you will define your flags and configuration settings.
+// commitAuthorSignature returns the configured author signature with the
+// overrides given by --author and --date. With --author, the configured
+// identity may be missing.
+func commitAuthorSignature(r *repository.Repository) (object.Signature, error) {
+	author, err := r.Signature("author")
+	if err != nil && commitAuthor == "" {
+		return object.Signature{}, err
+	}
+	if commitAuthor != "" {
+		lt, gt := strings.IndexByte(commitAuthor, '<'), strings.LastIndexByte(commitAuthor, '>')
+		if lt < 0 || gt < lt || strings.TrimSpace(commitAuthor[gt+1:]) != "" {
+			return object.Signature{}, fmt.Errorf("--author '%s' is not 'Name <email>'", commitAuthor)
+		}
+		author.Name, author.Email = strings.TrimSpace(commitAuthor[:lt]), commitAuthor[lt+1:gt]
+	}
+	switch {
+	case commitDate != "":
+		if author.When, err = timefmt.Parse(commitDate); err != nil {
+			return object.Signature{}, err
+		}
+	case author.When.IsZero():
+		author.When = time.Now()
+	}
+	return author, nil
+}
 
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// commitCmd.PersistentFlags().String("foo", "", "A help for foo")
+// sameTree returns whether the given tree is the tree of the only parent,
+// or empty if there are no parents.
+func sameTree(r *repository.Repository, tree string, parents []string) (bool, error) {
+	if len(parents) == 0 {
+		return tree == repository.EmptyTree, nil
+	}
+	if len(parents) > 1 {
+		return false, nil
+	}
+	c, err := r.ReadCommit(parents[0])
+	if err != nil {
+		return false, err
+	}
+	return c.Tree() == tree, nil
+}
 
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// commitCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+func init() {
+	commitCmd.Flags().StringArrayVarP(&commitMessages, "message", "m", nil, "use the given commit message")
+	commitCmd.Flags().BoolVarP(&commitSign, "gpg-sign", "S", false, "sign the commit")
+	commitCmd.Flags().BoolVar(&commitNoSign, "no-gpg-sign", false, "do not sign the commit, overriding commit.gpgSign")
+	commitCmd.Flags().StringVar(&commitAuthor, "author", "", "override the commit author, as 'Name <email>'")
+	commitCmd.Flags().StringVar(&commitDate, "date", "", "override the author date")
+	commitCmd.Flags().BoolVarP(&commitNoVerify, "no-verify", "n", false, "skip the pre-commit and commit-msg hooks")
+	rootCmd.AddCommand(commitCmd)
 }
@@ -0,0 +1,90 @@
+/*
+Copyright © 2022 NAME HERE <EMAIL ADDRESS>
+
+*/
+
+// Package cmd implements commands.
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sboehler/got/pkg/repository"
+	"github.com/spf13/cobra"
+)
+
+var (
+	commitTreeParents  []string
+	commitTreeMessages []string
+	commitTreeSign     bool
+	commitTreeNoSign   bool
+
+	// commitTreeCmd represents the commit-tree command
+	commitTreeCmd = &cobra.Command{
+		Use:   "commit-tree TREE [-p PARENT]... [-m MESSAGE]...",
+		Short: "Create a new commit object",
+		Long: `Create a commit of the given tree with the given parents and print its
+SHA. The author and committer are read from the GIT_AUTHOR_* and
+GIT_COMMITTER_* environment variables, or from user.name and user.email.
+Without -m, the message is read from stdin.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := openRepository()
+			if err != nil {
+				return err
+			}
+			tree, err := r.Find(args[0], "tree", true)
+			if err != nil {
+				return err
+			}
+			var parents []string
+			for _, p := range commitTreeParents {
+				sha, err := r.Find(p, "commit", true)
+				if err != nil {
+					return err
+				}
+				parents = append(parents, sha)
+			}
+			msg := strings.Join(commitTreeMessages, "\n\n")
+			if len(commitTreeMessages) > 0 {
+				msg += "\n"
+			} else {
+				bs, err := io.ReadAll(cmd.InOrStdin())
+				if err != nil {
+					return err
+				}
+				msg = string(bs)
+			}
+			signer, err := commitSigner(r, commitTreeSign, commitTreeNoSign)
+			if err != nil {
+				return err
+			}
+			sha, err := r.CommitTree(tree, parents, msg, signer)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), sha)
+			return nil
+		},
+	}
+)
+
+// commitSigner returns the signer for a new commit, or nil if it is not
+// to be signed. Commits are signed if sign is set, or if commit.gpgSign
+// is configured and noSign is not set.
+func commitSigner(r *repository.Repository, sign, noSign bool) (repository.Signer, error) {
+	if !sign && (noSign || !r.SignByDefault()) {
+		return nil, nil
+	}
+	return r.Signer()
+}
+
+func init() {
+	commitTreeCmd.Flags().StringArrayVarP(&commitTreeParents, "parent", "p", nil, "add a parent commit")
+	commitTreeCmd.Flags().StringArrayVarP(&commitTreeMessages, "message", "m", nil, "use the given commit message")
+	commitTreeCmd.Flags().BoolVarP(&commitTreeSign, "gpg-sign", "S", false, "sign the commit")
+	commitTreeCmd.Flags().BoolVar(&commitTreeNoSign, "no-gpg-sign", false, "do not sign the commit, overriding commit.gpgSign")
+	rootCmd.AddCommand(commitTreeCmd)
+}
@@ -0,0 +1,79 @@
+// Package cmd implements commands.
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/sboehler/got/pkg/repository"
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffTreeRecursive bool
+	diffTreeRoot      bool
+
+	// diffTreeCmd represents the diff-tree command
+	diffTreeCmd = &cobra.Command{
+		Use:   "diff-tree [-r] [--root] TREE-ISH [TREE-ISH]",
+		Short: "Compare the content and mode of two trees",
+		Long: `Compare two trees and print the entries which differ in git's raw diff
+format, with the status A, D, M or T for added, deleted, modified and
+type-changed entries. Given a single commit, its SHA is printed and the
+commit is compared to its first parent; root commits are only compared
+to the empty tree with --root. With -r, subtrees are compared
+recursively instead of being listed as changed entries.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := openRepository()
+			if err != nil {
+				return err
+			}
+			w := cmd.OutOrStdout()
+			var from, to string
+			if len(args) == 2 {
+				if from, err = r.Find(args[0], "tree", true); err != nil {
+					return err
+				}
+				if to, err = r.Find(args[1], "tree", true); err != nil {
+					return err
+				}
+			} else {
+				sha, err := r.Find(args[0], "commit", true)
+				if err != nil {
+					return err
+				}
+				c, err := r.ReadCommit(sha)
+				if err != nil {
+					return err
+				}
+				if parents := c.Parents(); len(parents) > 0 {
+					p, err := r.ReadCommit(parents[0])
+					if err != nil {
+						return err
+					}
+					from = p.Tree()
+				} else if !diffTreeRoot {
+					return nil
+				}
+				to = c.Tree()
+				fmt.Fprintln(w, sha)
+			}
+			var changes []repository.TreeChange
+			if diffTreeRecursive {
+				changes, err = r.DiffTrees(from, to)
+			} else {
+				changes, err = r.DiffTreeEntries(from, to)
+			}
+			if err != nil {
+				return err
+			}
+			return printRawChanges(w, r, changes, false)
+		},
+		Args: cobra.RangeArgs(1, 2),
+	}
+)
+
+func init() {
+	diffTreeCmd.Flags().BoolVarP(&diffTreeRecursive, "recursive", "r", false, "compare subtrees recursively")
+	diffTreeCmd.Flags().BoolVar(&diffTreeRoot, "root", false, "compare root commits to the empty tree")
+	rootCmd.AddCommand(diffTreeCmd)
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiffTree(t *testing.T) {
+	r := newCmdTestRepo(t)
+	revParse := func(rev string) string {
+		return strings.TrimSpace(mustRunGot(t, r.Worktree, "rev-parse", rev))
+	}
+	writeFile(t, r, "a", "a\n")
+	writeFile(t, r, "deleted", "deleted\n")
+	writeFile(t, r, "dir/x", "x\n")
+	if err := os.Symlink("a", filepath.Join(r.Worktree, "link")); err != nil {
+		t.Fatal(err)
+	}
+	mustRunGot(t, r.Worktree, "add", "-A")
+	mustRunGot(t, r.Worktree, "commit", "-m", "first")
+	first := revParse("HEAD")
+	old := map[string]string{"a": revParse("HEAD:a"), "deleted": revParse("HEAD:deleted"), "dir": revParse("HEAD:dir"), "dir/x": revParse("HEAD:dir/x"), "link": revParse("HEAD:link")}
+
+	writeFile(t, r, "a", "changed\n")
+	writeFile(t, r, "added", "added\n")
+	writeFile(t, r, "dir/x", "changed\n")
+	if err := os.Remove(filepath.Join(r.Worktree, "deleted")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(r.Worktree, "link")); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, r, "link", "a\n")
+	mustRunGot(t, r.Worktree, "add", "-A")
+	mustRunGot(t, r.Worktree, "commit", "-m", "second")
+	second := revParse("HEAD")
+	cur := map[string]string{"a": revParse("HEAD:a"), "added": revParse("HEAD:added"), "dir": revParse("HEAD:dir"), "dir/x": revParse("HEAD:dir/x"), "link": revParse("HEAD:link")}
+
+	zero := strings.Repeat("0", 40)
+	entries := []string{
+		":100644 100644 " + old["a"] + " " + cur["a"] + " M\ta\n",
+		":000000 100644 " + zero + " " + cur["added"] + " A\tadded\n",
+		":100644 000000 " + old["deleted"] + " " + zero + " D\tdeleted\n",
+		":040000 040000 " + old["dir"] + " " + cur["dir"] + " M\tdir\n",
+		":120000 100644 " + old["link"] + " " + cur["link"] + " T\tlink\n",
+	}
+	recursive := append(append(entries[:3:3], ":100644 100644 "+old["dir/x"]+" "+cur["dir/x"]+" M\tdir/x\n"), entries[4])
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{first, second}, strings.Join(entries, "")},
+		{[]string{"-r", first + "^{tree}", second + "^{tree}"}, strings.Join(recursive, "")},
+		{[]string{"-r", "HEAD"}, second + "\n" + strings.Join(recursive, "")},
+		{[]string{first}, ""},
+		{[]string{"--root", first}, first + "\n" +
+			":000000 100644 " + zero + " " + old["a"] + " A\ta\n" +
+			":000000 100644 " + zero + " " + old["deleted"] + " A\tdeleted\n" +
+			":000000 040000 " + zero + " " + old["dir"] + " A\tdir\n" +
+			":000000 120000 " + zero + " " + old["link"] + " A\tlink\n"},
+	}
+	for _, test := range tests {
+		if got := mustRunGot(t, r.Worktree, append([]string{"diff-tree"}, test.args...)...); got != test.want {
+			t.Errorf("diff-tree %v: got\n%s\nwant\n%s", test.args, got, test.want)
+		}
+	}
+}
+
+func TestDiffTreeFileToDirectory(t *testing.T) {
+	r := newCmdTestRepo(t)
+	writeFile(t, r, "a", "a\n")
+	mustRunGot(t, r.Worktree, "add", "-A")
+	mustRunGot(t, r.Worktree, "commit", "-m", "file")
+	if err := os.Remove(filepath.Join(r.Worktree, "a")); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, r, "a/b", "b\n")
+	mustRunGot(t, r.Worktree, "add", "-A")
+	mustRunGot(t, r.Worktree, "commit", "-m", "directory")
+	var statuses []string
+	for _, line := range strings.Split(strings.TrimSpace(mustRunGot(t, r.Worktree, "diff-tree", "HEAD^", "HEAD")), "\n") {
+		statuses = append(statuses, line[strings.IndexByte(line, '\t')-1:])
+	}
+	if got, want := strings.Join(statuses, ","), "D\ta,A\ta"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,101 @@
+// Package cmd implements commands.
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sboehler/got/pkg/index"
+	"github.com/spf13/cobra"
+)
+
+// dumpIndexCmd represents the dump-index command
+var dumpIndexCmd = &cobra.Command{
+	Use:    "dump-index",
+	Short:  "Print the parsed index for debugging",
+	Hidden: true,
+	Long: `Print the index as it is stored: its version and number of entries, the
+stat data, mode, stage, flags, SHA and path of each entry, its
+extensions, and its checksum and whether it matches the content. The
+index is parsed even if its checksum does not match.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := openRepository()
+		if err != nil {
+			return err
+		}
+		if err := r.RequireWorktree(); err != nil {
+			return err
+		}
+		bs, err := os.ReadFile(r.IndexPath())
+		if err != nil {
+			return errors.Wrap(err, "error reading index")
+		}
+		idx, err := index.ParseUnchecked(bs)
+		if err != nil {
+			return errors.Wrap(err, "index is corrupt")
+		}
+		w := cmd.OutOrStdout()
+		fmt.Fprintf(w, "version %d\nentries %d\n", idx.Version, len(idx.Entries))
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ctime\tmtime\tdev\tino\tmode\tuid\tgid\tsize\tstage\tflags\tsha\tpath")
+		for _, e := range idx.Entries {
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%o\t%d\t%d\t%d\t%d\t%s\t%s\t%s\n",
+				dumpIndexTime(e.CTime), dumpIndexTime(e.MTime), e.Dev, e.Ino, e.Mode, e.UID, e.GID, e.Size, e.Stage, dumpIndexFlags(e), e.SHA, e.Path)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		var exts []string
+		if idx.Tree != nil {
+			exts = append(exts, "TREE")
+		}
+		for _, ext := range idx.Extensions {
+			exts = append(exts, ext.Signature)
+		}
+		if len(exts) > 0 {
+			fmt.Fprintf(w, "extensions %s\n", strings.Join(exts, " "))
+		}
+		sum, ok := index.Checksum(bs)
+		status := "valid"
+		if !ok {
+			status = "invalid"
+		}
+		fmt.Fprintf(w, "checksum %s %s\n", sum, status)
+		return nil
+	},
+	Args: cobra.NoArgs,
+}
+
+// dumpIndexTime formats a timestamp as seconds and nanoseconds.
+func dumpIndexTime(t time.Time) string {
+	return fmt.Sprintf("%d.%09d", t.Unix(), t.Nanosecond())
+}
+
+// dumpIndexFlags lists the flags of an index entry.
+func dumpIndexFlags(e index.Entry) string {
+	var flags []string
+	for _, f := range []struct {
+		set  bool
+		name string
+	}{
+		{e.AssumeValid, "assume-valid"},
+		{e.SkipWorktree, "skip-worktree"},
+		{e.IntentToAdd, "intent-to-add"},
+	} {
+		if f.set {
+			flags = append(flags, f.name)
+		}
+	}
+	if len(flags) == 0 {
+		return "-"
+	}
+	return strings.Join(flags, ",")
+}
+
+func init() {
+	rootCmd.AddCommand(dumpIndexCmd)
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sboehler/got/pkg/index"
+)
+
+func TestDumpIndex(t *testing.T) {
+	r := newCmdTestRepo(t)
+	idx := &index.Index{
+		Version: 3,
+		Entries: []index.Entry{
+			{
+				CTime: time.Unix(1600000000, 1), MTime: time.Unix(1600000001, 999999999),
+				Dev: 1, Ino: 2, Mode: 0100644, UID: 1000, GID: 100, Size: 3,
+				SHA: strings.Repeat("a", 40), Path: "README",
+			},
+			{
+				CTime: time.Unix(1600000000, 0), MTime: time.Unix(1600000000, 0),
+				Mode: 0100755, SHA: strings.Repeat("b", 40), Path: "bin/run",
+				AssumeValid: true, SkipWorktree: true,
+			},
+		},
+		Extensions: []index.Extension{{Signature: "REUC", Data: []byte("resolve undo")}},
+	}
+	var buf bytes.Buffer
+	if err := idx.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(r.IndexPath(), buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum, _ := index.Checksum(buf.Bytes())
+	want := `version 3
+entries 2
+ctime                 mtime                 dev  ino  mode    uid   gid  size  stage  flags                       sha                                       path
+1600000000.000000001  1600000001.999999999  1    2    100644  1000  100  3     0      -                           aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa  README
+1600000000.000000000  1600000000.000000000  0    0    100755  0     0    0     0      assume-valid,skip-worktree  bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb  bin/run
+extensions REUC
+checksum ` + sum + " valid\n"
+	if got := mustRunGot(t, r.Worktree, "dump-index"); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if bs, err := os.ReadFile(r.IndexPath()); err != nil || !bytes.Equal(bs, buf.Bytes()) {
+		t.Errorf("dump-index changed the index")
+	}
+
+	// a damaged checksum is reported, and the entries are still shown
+	bs := append([]byte(nil), buf.Bytes()...)
+	bs[len(bs)-1] ^= 1
+	if err := os.WriteFile(r.IndexPath(), bs, 0644); err != nil {
+		t.Fatal(err)
+	}
+	got := mustRunGot(t, r.Worktree, "dump-index")
+	sum, _ = index.Checksum(bs)
+	if !strings.HasSuffix(got, "checksum "+sum+" invalid\n") || !strings.Contains(got, "bin/run") {
+		t.Errorf("got\n%s\nwant an invalid checksum", got)
+	}
+}
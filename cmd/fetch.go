@@ -0,0 +1,113 @@
+// Package cmd implements commands.
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/sboehler/got/pkg/repository"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fetchName string
+
+	// fetchCmd represents the fetch command
+	fetchCmd = &cobra.Command{
+		Use:   "fetch [--name NAME] [REMOTE|PATH]",
+		Short: "Download objects and refs from a local repository",
+		Long: `Copy the objects of a local repository which are missing locally, and
+update the remote-tracking refs under refs/remotes/<remote>/. A path is
+recorded as the remote given by --name, which defaults to "origin"; an
+existing remote with a different URL is not changed. Local branches and the
+working tree are not touched.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := openRepository()
+			if err != nil {
+				return err
+			}
+			name, url := fetchName, ""
+			if len(args) == 1 {
+				if u := r.ConfigValue(`remote "`+args[0]+`"`, "url"); u != "" {
+					name, url = args[0], u
+				} else if url, err = filepath.Abs(args[0]); err != nil {
+					return err
+				} else if u := r.ConfigValue(`remote "`+name+`"`, "url"); u != "" && u != url {
+					return fmt.Errorf("remote %s already exists with URL %s; use --name to fetch %s as another remote", name, u, url)
+				}
+			} else if url = r.ConfigValue(`remote "`+name+`"`, "url"); url == "" {
+				return fmt.Errorf("no remote repository specified")
+			}
+			remote, err := repository.Load(url)
+			if err != nil {
+				return err
+			}
+			defer remote.Close()
+			refs, err := remote.Refs()
+			if err != nil {
+				return err
+			}
+			var shas []string
+			for _, ref := range refs {
+				if strings.HasPrefix(ref.Name, "refs/heads/") {
+					shas = append(shas, ref.SHA)
+				}
+			}
+			r.UseBloomFilter()
+			n, err := r.Fetch(remote, shas)
+			if err != nil {
+				return err
+			}
+			if r.ConfigValue(`remote "`+name+`"`, "url") != url {
+				section := r.Config.Section(`remote "` + name + `"`)
+				section.Key("url").SetValue(url)
+				section.Key("fetch").SetValue("+refs/heads/*:refs/remotes/" + name + "/*")
+				if err := r.WriteConfig(); err != nil {
+					return err
+				}
+			}
+			infof("From %s\n", url)
+			tx := r.NewRefTransaction()
+			tx.Message = "fetch: " + url
+			var updated []string
+			for _, ref := range refs {
+				if !strings.HasPrefix(ref.Name, "refs/heads/") {
+					continue
+				}
+				branch := strings.TrimPrefix(ref.Name, "refs/heads/")
+				local := "refs/remotes/" + name + "/" + branch
+				old, err := r.ResolveRef(local)
+				if err == nil && old == ref.SHA {
+					continue
+				} else if err != nil {
+					old = repository.ZeroSHA
+				}
+				if err := tx.Update(local, ref.SHA, old); err != nil {
+					tx.Abort()
+					return err
+				}
+				abbrev, err := r.Abbreviate(ref.SHA)
+				if err != nil {
+					tx.Abort()
+					return err
+				}
+				updated = append(updated, fmt.Sprintf(" %s\t%s -> %s/%s\n", abbrev, branch, name, branch))
+			}
+			if err := tx.Commit(); err != nil {
+				return err
+			}
+			for _, line := range updated {
+				infof("%s", line)
+			}
+			verbosef("%d objects fetched\n", n)
+			return nil
+		},
+		Args: cobra.MaximumNArgs(1),
+	}
+)
+
+func init() {
+	fetchCmd.Flags().StringVar(&fetchName, "name", "origin", "the name of the remote to record a path as")
+	rootCmd.AddCommand(fetchCmd)
+}
@@ -0,0 +1,131 @@
+// Package cmd implements commands.
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	fsckUnreachable      bool
+	fsckConnectivityOnly bool
+
+	// fsckCmd represents the fsck command
+	fsckCmd = &cobra.Command{
+		Use:   "fsck",
+		Short: "Verify the connectivity and validity of objects",
+		Long: `Check all objects in the object store for corruption, and report
+objects which are not reachable from HEAD, refs or reflogs. Dangling
+objects are unreachable objects which are not referenced by any other
+unreachable object.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := openRepository()
+			if err != nil {
+				return err
+			}
+			var (
+				w       = cmd.OutOrStdout()
+				errors  int
+				types   = make(map[string]string)
+				shas    []string
+				corrupt = make(map[string]bool)
+			)
+			err = r.ForEachObject(func(sha, typ string) error {
+				if typ == "" {
+					_, _, err := r.ReadObjectHeader(sha)
+					fmt.Fprintf(w, "error: %v\n", err)
+					corrupt[sha] = true
+					errors++
+					return nil
+				}
+				types[sha] = typ
+				shas = append(shas, sha)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if !fsckConnectivityOnly {
+				p := newProgress("Checking objects", len(shas))
+				for _, sha := range shas {
+					if err := r.CheckObject(sha); err != nil {
+						fmt.Fprintf(w, "error: %v\n", err)
+						corrupt[sha] = true
+						errors++
+					}
+					p.Inc()
+				}
+				p.Done()
+			}
+			roots, err := r.Roots()
+			if err != nil {
+				return err
+			}
+			r.UseBloomFilter()
+			reachable, missing, broken := r.Reachable(roots)
+			for _, sha := range missing {
+				fmt.Fprintf(w, "missing object %s\n", sha)
+				errors++
+			}
+			var unreadable []string
+			for sha := range broken {
+				if !corrupt[sha] {
+					unreadable = append(unreadable, sha)
+				}
+			}
+			sort.Strings(unreadable)
+			for _, sha := range unreadable {
+				fmt.Fprintf(w, "error: %v\n", broken[sha])
+				errors++
+			}
+			referenced := make(map[string]bool)
+			for _, sha := range shas {
+				if reachable[sha] {
+					continue
+				}
+				refs, err := r.References(sha)
+				if err != nil {
+					continue
+				}
+				for _, ref := range refs {
+					referenced[ref] = true
+				}
+			}
+			counts := map[bool]map[string]int{true: {}, false: {}}
+			for _, sha := range shas {
+				typ := types[sha]
+				counts[reachable[sha]][typ]++
+				switch {
+				case reachable[sha]:
+				case fsckUnreachable:
+					fmt.Fprintf(w, "unreachable %s %s\n", typ, sha)
+				case !referenced[sha]:
+					fmt.Fprintf(w, "dangling %s %s\n", typ, sha)
+				}
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "reachable: %s\nunreachable: %s\n", formatCounts(counts[true]), formatCounts(counts[false]))
+			if errors > 0 {
+				return fmt.Errorf("%d errors found", errors)
+			}
+			return nil
+		},
+		Args: cobra.NoArgs,
+	}
+)
+
+func formatCounts(counts map[string]int) string {
+	var parts []string
+	for _, typ := range []string{"commit", "tree", "blob", "tag"} {
+		parts = append(parts, fmt.Sprintf("%d %ss", counts[typ], typ))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func init() {
+	fsckCmd.Flags().BoolVar(&fsckUnreachable, "unreachable", false, "list all unreachable objects, not only dangling ones")
+	fsckCmd.Flags().BoolVar(&fsckConnectivityOnly, "connectivity-only", false, "only check connectivity, skip checking object contents")
+	rootCmd.AddCommand(fsckCmd)
+}
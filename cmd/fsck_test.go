@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFsckCorruptReachableBlob(t *testing.T) {
+	r := newCmdTestRepo(t)
+	writeFile(t, r, "a", "a\n")
+	writeFile(t, r, "b", "b\n")
+	mustRunGot(t, r.Worktree, "add", "a", "b")
+	mustRunGot(t, r.Worktree, "commit", "-m", "initial")
+	sha := strings.TrimSpace(mustRunGot(t, r.Worktree, "rev-parse", "HEAD:a"))
+	p := filepath.Join(r.ObjectDir(), sha[:2], sha[2:])
+	if err := os.Chmod(p, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte("this is not zlib data"), 0444); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"fsck"}, {"fsck", "--connectivity-only"}} {
+		out, err := runGot(t, r.Worktree, "", args...)
+		if err == nil || err.Error() != "1 errors found" {
+			t.Errorf("%v: got error %v, want 1 error", args, err)
+		}
+		if n := strings.Count(out, "error: "); n != 1 || !strings.Contains(out, sha) {
+			t.Errorf("%v: got output\n%s\nwant one error for %s", args, out, sha)
+		}
+		// the other objects are still traversed
+		if want := "reachable: 1 commits, 1 trees, 1 blobs, 0 tags\n"; !strings.Contains(out, want) {
+			t.Errorf("%v: got output\n%s\nwant %q", args, out, want)
+		}
+	}
+}
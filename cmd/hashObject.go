@@ -8,8 +8,11 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 
+	"github.com/pkg/errors"
 	"github.com/sboehler/got/pkg/object"
 	"github.com/sboehler/got/pkg/repository"
 	"github.com/spf13/cobra"
@@ -19,52 +22,117 @@ import (
 var (
 	objectType string
 	write      bool
+	literally  bool
+	hashStdin  bool
+	hashPath   string
 
 	hashObjectCmd = &cobra.Command{
-		Use:   "hash-object OBJECT",
+		Use:   "hash-object (OBJECT | --stdin)",
 		Short: "Provide content of repository objects",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			f, err := os.ReadFile(args[0])
-			if err != nil {
+			if hashStdin != (len(args) == 0) {
+				return fmt.Errorf("expected either a file or --stdin")
+			}
+			name := hashPath
+			if name == "" && !hashStdin {
+				name = args[0]
+			}
+			r, err := openRepository()
+			if err != nil && write {
 				return err
 			}
-			var o repository.Object
-			switch objectType {
-			case "blob":
-				o = object.NewBlob(f)
-			default:
-				return fmt.Errorf("invalid object type: %s", objectType)
+			var p string
+			if r != nil && name != "" {
+				if p, err = r.RelPath(name); err != nil {
+					p = filepath.ToSlash(name)
+				}
+			}
+			filter := p != "" && !literally
+			if !hashStdin && !write && (literally || objectType == "blob") && (!filter || !r.ConvertsContent(p)) {
+				return hashObjectStream(args[0])
+			}
+			var f []byte
+			if hashStdin {
+				f, err = io.ReadAll(cmd.InOrStdin())
+			} else {
+				f, err = os.ReadFile(args[0])
+			}
+			if err != nil {
+				return err
 			}
 			of := &repository.ObjectFile{
-				Data:       o.Serialize(),
+				Data:       f,
 				ObjectType: objectType,
 			}
+			if !literally {
+				var o repository.Object
+				switch objectType {
+				case "blob":
+					if filter {
+						if f, err = r.CleanContent(p, f); err != nil {
+							return err
+						}
+					}
+					o = object.NewBlob(f)
+				default:
+					if !repository.IsValidObjectType(objectType) {
+						return fmt.Errorf("invalid object type: %s", objectType)
+					}
+					if err := object.Validate(objectType, f); err != nil {
+						return errors.Wrapf(err, "invalid %s object", objectType)
+					}
+					if o, err = object.Parse(objectType, f); err != nil {
+						return err
+					}
+				}
+				of.Data = o.Serialize()
+			}
 			var hash string
 			if write {
-				wd, err := os.Getwd()
-				if err != nil {
-					return err
-				}
-				r, err := repository.Find(wd)
-				if err != nil {
-					return err
-				}
 				if hash, err = r.WriteObject(of); err != nil {
 					return err
 				}
+				if hashStdin {
+					verbosef("wrote stdin as %s\n", hash)
+				} else {
+					verbosef("wrote %s as %s\n", args[0], hash)
+				}
 			} else {
 				hash = repository.Hash(of)
 			}
 			fmt.Println(hash)
 			return nil
 		},
-		Args: cobra.ExactArgs(1),
+		Args: cobra.MaximumNArgs(1),
 	}
 )
 
+// hashObjectStream prints the hash of the given file's content without
+// reading it into memory.
+func hashObjectStream(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	hash, err := object.HashStream(objectType, info.Size(), f)
+	if err != nil {
+		return errors.Wrapf(err, "error hashing %s", path)
+	}
+	fmt.Println(hash)
+	return nil
+}
+
 func init() {
 	hashObjectCmd.Flags().StringVarP(&objectType, "type", "t", "blob", "specify tye type")
 	hashObjectCmd.Flags().BoolVarP(&write, "write", "w", false, "write the file to the object database")
+	hashObjectCmd.Flags().BoolVar(&literally, "literally", false, "hash the content as is, without checking the type")
+	hashObjectCmd.Flags().BoolVar(&hashStdin, "stdin", false, "read the content from stdin instead of a file")
+	hashObjectCmd.Flags().StringVar(&hashPath, "path", "", "hash the content as if it were located at the given path, for choosing filters")
 	rootCmd.AddCommand(hashObjectCmd)
 
 	// Here you will define your flags and configuration settings.
@@ -9,6 +9,12 @@ package cmd
 import (
 	"github.com/sboehler/got/pkg/repository"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var (
+	initBare          bool
+	initInitialBranch string
 )
 
 // initCmd represents the init command
@@ -22,12 +28,30 @@ Cobra is a CLI library for Go that empowers applications.
 This application is a tool to generate the needed files
 to quickly create a Cobra application.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		_, err := repository.Init(args[0])
+		branch := initInitialBranch
+		if branch == "" {
+			branch = defaultBranch()
+		}
+		_, err := repository.InitWith(args[0], repository.InitOptions{
+			Bare:          initBare,
+			InitialBranch: branch,
+		})
 		return err
 	},
 }
 
+// defaultBranch returns the branch configured in init.defaultBranch, or
+// repository.DefaultBranch.
+func defaultBranch() string {
+	if b := viper.GetString("init.defaultBranch"); b != "" {
+		return b
+	}
+	return repository.DefaultBranch
+}
+
 func init() {
+	initCmd.Flags().BoolVar(&initBare, "bare", false, "create a bare repository")
+	initCmd.Flags().StringVarP(&initInitialBranch, "initial-branch", "b", "", "name of the initial branch (default init.defaultBranch or master)")
 	rootCmd.AddCommand(initCmd)
 
 	// Here you will define your flags and configuration settings.
@@ -7,36 +7,239 @@ Copyright © 2022 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"strings"
 
+	"github.com/sboehler/got/pkg/object"
+	"github.com/sboehler/got/pkg/repository"
 	"github.com/spf13/cobra"
 )
 
-// logCmd represents the log command
-var logCmd = &cobra.Command{
-	Use:   "log",
-	Short: "A brief description of your command",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
-
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("log called")
-	},
-}
+var (
+	logOneline  bool
+	logMaxCount int
+	logPretty   string
+	logFormat   string
+	logDepth    int
+	logGraph    bool
+	logTopo     bool
 
-func init() {
-	rootCmd.AddCommand(logCmd)
+	// logCmd represents the log command
+	logCmd = &cobra.Command{
+		Use:   "log [--oneline] [--format FORMAT] [--graph] [--topo-order] [-n N] [--depth N] [REVISION...] [-- PATH...]",
+		Short: "Show commit logs",
+		Long: `Show the commits reachable from the given revisions, or from HEAD, newest
+first. Revisions can be excluded as in rev-list. --pretty selects the
+format, which is one of medium (the default), oneline, short, full or raw;
+--oneline is short for --pretty=oneline with abbreviated SHAs. --format
+accepts the same names, or a template with the placeholders %H, %h, %an,
+%ae, %ad, %cn, %ce, %s, %b and %n, which is printed for each commit
+followed by a newline. --depth stops the walk
+after the given number of commits along each path; commits whose parents
+are cut off are marked as (grafted). Paths after -- limit the log to the
+commits which change them, following only the first parent of merges.
+--graph draws the commit graph to the left of the log, and implies
+--topo-order, which shows no commit before its children.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := openRepository()
+			if err != nil {
+				return err
+			}
+			format := logPretty
+			if logOneline {
+				format = "oneline"
+			}
+			var template repository.CommitFormat
+			if logFormat != "" {
+				format = logFormat
+				if !logFormats[format] {
+					template = repository.ParseCommitFormat(format)
+				}
+			} else if !logFormats[format] {
+				return fmt.Errorf("invalid --pretty format: %s", format)
+			}
+			walker, err := newLogWalker(cmd, r, args)
+			if err != nil {
+				return err
+			}
+			walker.Depth = logDepth
+			if logTopo || logGraph {
+				// the graph cannot draw a commit before its children
+				walker.Order = repository.Topological
+			}
+			var graph *repository.Graph
+			if logGraph {
+				if len(walker.Paths) > 0 {
+					return fmt.Errorf("--graph cannot be combined with paths")
+				}
+				graph = &repository.Graph{}
+			}
+			defer maybePager(cmd, r)()
+			w := cmd.OutOrStdout()
+			var (
+				buf     bytes.Buffer
+				padding string
+			)
+			for n := 0; logMaxCount < 0 || n < logMaxCount; n++ {
+				sha, c, err := walker.Next()
+				if err != nil {
+					return err
+				}
+				if sha == "" {
+					break
+				}
+				buf.Reset()
+				if template != nil {
+					line, err := r.FormatCommit(template, sha, c)
+					if err != nil {
+						return err
+					}
+					fmt.Fprintln(&buf, line)
+				} else {
+					if format != "oneline" && n > 0 {
+						fmt.Fprintln(w, strings.TrimRight(padding, " "))
+					}
+					if err := printCommit(&buf, r, format, logOneline, sha, c, walker.IsBoundary(sha)); err != nil {
+						return err
+					}
+				}
+				if graph == nil {
+					w.Write(buf.Bytes())
+					continue
+				}
+				lines := graph.Add(sha, walker.Parents(sha, c))
+				printGraphed(w, lines, buf.String())
+				padding = lines.Padding
+			}
+			return nil
+		},
+	}
 
-	// Here you will define your flags and configuration settings.
+	// logFormats are the named formats accepted by --pretty and --format.
+	logFormats = map[string]bool{"oneline": true, "short": true, "medium": true, "full": true, "raw": true}
+)
 
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// logCmd.PersistentFlags().String("foo", "", "A help for foo")
+// newLogWalker creates a commit walker for log-like commands from
+// arguments of the form [REVISION...] [-- PATH...]. Without revisions,
+// the walk starts at HEAD. Paths limit the walk to the commits which
+// change them, along the first parent.
+func newLogWalker(cmd *cobra.Command, r *repository.Repository, args []string) (*repository.CommitWalker, error) {
+	revs, pathArgs := args, []string(nil)
+	if i := cmd.ArgsLenAtDash(); i >= 0 {
+		revs, pathArgs = args[:i], args[i:]
+	}
+	if len(revs) == 0 {
+		state, target, err := r.CheckHead()
+		if err != nil {
+			return nil, err
+		}
+		if state == repository.HeadUnborn {
+			return nil, fmt.Errorf("your current branch '%s' does not have any commits yet", strings.TrimPrefix(target, "refs/heads/"))
+		}
+		revs = []string{"HEAD"}
+	}
+	walker := r.NewCommitWalker()
+	for _, arg := range pathArgs {
+		p, err := r.RelPath(arg)
+		if err != nil {
+			return nil, err
+		}
+		walker.Paths = append(walker.Paths, p)
+	}
+	walker.FirstParent = len(walker.Paths) > 0
+	if err := pushRevisions(r, walker, revs); err != nil {
+		return nil, err
+	}
+	return walker, nil
+}
 
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// logCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+// printGraphed prints the output of a commit with the graph drawn to its
+// left.
+func printGraphed(w io.Writer, lines repository.GraphLines, text string) {
+	prefixes := append([]string{lines.Row}, lines.Connectors...)
+	textLines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for i, line := range textLines {
+		prefix := lines.Padding
+		if i < len(prefixes) {
+			prefix = prefixes[i]
+		}
+		if line == "" {
+			fmt.Fprintln(w, strings.TrimRight(prefix, " "))
+		} else {
+			fmt.Fprintf(w, "%s %s\n", prefix, line)
+		}
+	}
+	for i := len(textLines); i < len(prefixes); i++ {
+		fmt.Fprintln(w, strings.TrimRight(prefixes[i], " "))
+	}
+}
+
+// printCommit prints a commit in the given format. If abbrev is set, the
+// oneline format shows abbreviated SHAs. Grafted commits, whose parents
+// were cut off the walk, are marked as such.
+func printCommit(w io.Writer, r *repository.Repository, format string, abbrev bool, sha string, c *object.Commit, grafted bool) error {
+	var decoration string
+	if grafted {
+		decoration = " (grafted)"
+	}
+	switch format {
+	case "oneline":
+		id := sha
+		if abbrev {
+			var err error
+			if id, err = r.Abbreviate(sha); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintf(w, "%s%s %s\n", id, decoration, strings.SplitN(c.Message(), "\n", 2)[0])
+		return nil
+	case "raw":
+		bs := c.Serialize()
+		headers := bs[:len(bs)-len(c.Message())-1]
+		fmt.Fprintf(w, "commit %s%s\n%s\n", sha, decoration, headers)
+	default:
+		fmt.Fprintf(w, "commit %s%s\n", sha, decoration)
+		if parents := c.Parents(); len(parents) > 1 {
+			abbrevs := make([]string, len(parents))
+			for i, p := range parents {
+				abbrev, err := r.Abbreviate(p)
+				if err != nil {
+					return err
+				}
+				abbrevs[i] = abbrev
+			}
+			fmt.Fprintf(w, "Merge: %s\n", strings.Join(abbrevs, " "))
+		}
+		author := c.Author()
+		fmt.Fprintf(w, "Author: %s <%s>\n", author.Name, author.Email)
+		switch format {
+		case "medium":
+			fmt.Fprintf(w, "Date:   %s\n", author.When.Format(repository.DateFormat))
+		case "full":
+			committer := c.Committer()
+			fmt.Fprintf(w, "Commit: %s <%s>\n", committer.Name, committer.Email)
+		}
+		fmt.Fprintln(w)
+	}
+	msg := c.Message()
+	if format == "short" {
+		msg = strings.SplitN(msg, "\n\n", 2)[0]
+	}
+	for _, line := range strings.Split(strings.TrimRight(msg, "\n"), "\n") {
+		fmt.Fprintf(w, "    %s\n", line)
+	}
+	return nil
+}
+
+func init() {
+	logCmd.Flags().BoolVar(&logOneline, "oneline", false, "show each commit on a single line")
+	logCmd.Flags().IntVarP(&logMaxCount, "max-count", "n", -1, "limit the number of commits to show")
+	logCmd.Flags().StringVar(&logPretty, "pretty", "medium", "the output format: oneline, short, medium, full or raw")
+	logCmd.Flags().StringVar(&logFormat, "format", "", "the output format: a named format or a template")
+	logCmd.Flags().BoolVar(&logGraph, "graph", false, "draw the commit graph to the left of the log")
+	logCmd.Flags().BoolVar(&logTopo, "topo-order", false, "show no commit before its children")
+	logCmd.Flags().IntVar(&logDepth, "depth", 0, "limit the walk to the given number of commits along each path")
+	rootCmd.AddCommand(logCmd)
 }
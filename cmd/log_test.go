@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLogGraphSkewedDates(t *testing.T) {
+	r := newCmdTestRepo(t)
+	tree := strings.TrimSpace(mustRunGot(t, r.Worktree, "write-tree"))
+	commit := func(msg string, date int, parents ...string) string {
+		t.Setenv("GIT_COMMITTER_DATE", fmt.Sprintf("@%d", date))
+		args := []string{"commit-tree", tree, "-m", msg}
+		for _, p := range parents {
+			args = append(args, "-p", p)
+		}
+		return strings.TrimSpace(mustRunGot(t, r.Worktree, args...))
+	}
+	root := commit("root", 1600000100)
+	// the side branch is dated before its parent
+	side := commit("side", 1600000050, root)
+	main := commit("main", 1600000200, root)
+	merge := commit("merge", 1600000300, main, side)
+
+	got := mustRunGot(t, r.Worktree, "log", "--graph", "--format=%s", merge)
+	if want := "*   merge\n|\\\n* | main\n| * side\n|/\n* root\n"; got != want {
+		t.Errorf("got graph\n%s\nwant\n%s", got, want)
+	}
+	got = mustRunGot(t, r.Worktree, "log", "--format=%s", merge)
+	if want := "merge\nmain\nroot\nside\n"; got != want {
+		t.Errorf("got log\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,51 @@
+// Package cmd implements commands.
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sboehler/got/pkg/repository"
+	"github.com/spf13/cobra"
+)
+
+// lsRemoteCmd represents the lsRemote command
+var lsRemoteCmd = &cobra.Command{
+	Use:   "ls-remote REPOSITORY",
+	Short: "List references in a local repository",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := repository.Load(args[0])
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		w := cmd.OutOrStdout()
+		if head, err := r.ReadRef("HEAD"); err == nil {
+			if strings.HasPrefix(head, "ref: ") {
+				fmt.Fprintf(w, "%s\tHEAD\n", head)
+			}
+			if sha, err := r.ResolveRef("HEAD"); err == nil {
+				fmt.Fprintf(w, "%s\tHEAD\n", sha)
+			}
+		}
+		refs, err := r.Refs()
+		if err != nil {
+			return err
+		}
+		for _, ref := range refs {
+			fmt.Fprintf(w, "%s\t%s\n", ref.SHA, ref.Name)
+			if !strings.HasPrefix(ref.Name, "refs/tags/") {
+				continue
+			}
+			if peeled, err := r.Peel(ref.SHA, ""); err == nil && peeled != ref.SHA {
+				fmt.Fprintf(w, "%s\t%s^{}\n", peeled, ref.Name)
+			}
+		}
+		return nil
+	},
+	Args: cobra.ExactArgs(1),
+}
+
+func init() {
+	rootCmd.AddCommand(lsRemoteCmd)
+}
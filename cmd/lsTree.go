@@ -8,35 +8,61 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"path"
 
+	"github.com/sboehler/got/pkg/repository"
 	"github.com/spf13/cobra"
 )
 
-// lsTreeCmd represents the lsTree command
-var lsTreeCmd = &cobra.Command{
-	Use:   "lsTree",
-	Short: "A brief description of your command",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
-
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("lsTree called")
-	},
+var (
+	lsTreeRecursive bool
+
+	// lsTreeCmd represents the lsTree command
+	lsTreeCmd = &cobra.Command{
+		Use:   "ls-tree [-r] TREE-ISH",
+		Short: "List the contents of a tree object",
+		Long: `List the entries of the given tree, or of the tree of the given commit,
+with their mode, type, SHA and path. With -r, subtrees are listed
+recursively instead.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := openRepository()
+			if err != nil {
+				return err
+			}
+			sha, err := r.Find(args[0], "tree", true)
+			if err != nil {
+				return err
+			}
+			return lsTree(r, cmd.OutOrStdout(), sha, "")
+		},
+		Args: cobra.ExactArgs(1),
+	}
+)
+
+// lsTree prints the entries of the given tree, prefixing their names with
+// dir.
+func lsTree(r *repository.Repository, w io.Writer, sha string, dir string) error {
+	t, err := r.ReadTree(sha)
+	if err != nil {
+		return err
+	}
+	for _, e := range t.Entries() {
+		p := path.Join(dir, e.Name)
+		if lsTreeRecursive && e.Type() == "tree" {
+			if err := lsTree(r, w, e.SHA, p); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "%06s %s %s\t%s\n", e.Mode, e.Type(), e.SHA, p); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func init() {
+	lsTreeCmd.Flags().BoolVarP(&lsTreeRecursive, "recursive", "r", false, "recurse into subtrees")
 	rootCmd.AddCommand(lsTreeCmd)
-
-	// Here you will define your flags and configuration settings.
-
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// lsTreeCmd.PersistentFlags().String("foo", "", "A help for foo")
-
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// lsTreeCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
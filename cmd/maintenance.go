@@ -0,0 +1,64 @@
+// Package cmd implements commands.
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/sboehler/got/pkg/repository"
+	"github.com/spf13/cobra"
+)
+
+var (
+	maintenanceRepair bool
+
+	// maintenanceCmd represents the maintenance command
+	maintenanceCmd = &cobra.Command{
+		Use:   "maintenance",
+		Short: "Check and repair the repository",
+	}
+
+	// maintenanceCheckHeadCmd represents the maintenance check-head command
+	maintenanceCheckHeadCmd = &cobra.Command{
+		Use:   "check-head",
+		Short: "Check what HEAD points to",
+		Long: `Check what HEAD points to. HEAD either points to an existing branch, to
+a branch which does not exist yet (before the first commit), or directly
+to a commit. A detached HEAD pointing to a missing object is reported as
+an error; with --repair, it is reset to the default branch if that
+branch exists.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := openRepository()
+			if err != nil {
+				return err
+			}
+			state, target, err := r.CheckHead()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "HEAD: %s %s\n", state, target)
+			if state != repository.HeadDangling {
+				return nil
+			}
+			if !maintenanceRepair {
+				return fmt.Errorf("HEAD points to %s, which is not an existing commit", target)
+			}
+			branch := "refs/heads/" + defaultBranch()
+			if _, err := r.ResolveRef(branch); err != nil {
+				return errors.Wrapf(err, "cannot repair HEAD")
+			}
+			if err := r.SetSymbolicRef("HEAD", branch, ""); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "HEAD reset to %s\n", branch)
+			return nil
+		},
+		Args: cobra.NoArgs,
+	}
+)
+
+func init() {
+	maintenanceCheckHeadCmd.Flags().BoolVar(&maintenanceRepair, "repair", false, "reset a dangling detached HEAD to the default branch")
+	maintenanceCmd.AddCommand(maintenanceCheckHeadCmd)
+	rootCmd.AddCommand(maintenanceCmd)
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sboehler/got/pkg/repository"
+)
+
+func TestMaintenanceCheckHead(t *testing.T) {
+	r := newCmdTestRepo(t)
+	branch := "refs/heads/" + repository.DefaultBranch
+	missing := strings.Repeat("0123456789", 4)
+	setHead := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(r.GitPath("HEAD"), []byte(content+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// before the first commit, the unborn branch is legal
+	if got, want := mustRunGot(t, r.Worktree, "maintenance", "check-head"), "HEAD: unborn branch "+branch+"\n"; got != want {
+		t.Errorf("unborn: got %q, want %q", got, want)
+	}
+	// dangling HEAD cannot be repaired before the default branch exists
+	setHead(missing)
+	if _, err := runGot(t, r.Worktree, "", "maintenance", "check-head", "--repair"); err == nil {
+		t.Error("unborn: repaired HEAD without a default branch")
+	}
+	setHead("ref: " + branch)
+
+	writeFile(t, r, "a", "a\n")
+	mustRunGot(t, r.Worktree, "add", "a")
+	mustRunGot(t, r.Worktree, "commit", "-m", "first")
+	commit := strings.TrimSpace(mustRunGot(t, r.Worktree, "rev-parse", "HEAD"))
+	if got, want := mustRunGot(t, r.Worktree, "maintenance", "check-head"), "HEAD: branch "+branch+"\n"; got != want {
+		t.Errorf("valid: got %q, want %q", got, want)
+	}
+
+	setHead(commit)
+	if got, want := mustRunGot(t, r.Worktree, "maintenance", "check-head"), "HEAD: detached "+commit+"\n"; got != want {
+		t.Errorf("detached: got %q, want %q", got, want)
+	}
+
+	setHead(missing)
+	out, err := runGot(t, r.Worktree, "", "maintenance", "check-head")
+	if err == nil || !strings.HasPrefix(out, "HEAD: dangling detached "+missing+"\n") {
+		t.Errorf("dangling: got %q, %v, want an error", out, err)
+	}
+	if head, err := os.ReadFile(r.GitPath("HEAD")); err != nil || string(head) != missing+"\n" {
+		t.Errorf("dangling: HEAD was changed to %q without --repair", head)
+	}
+	out = mustRunGot(t, r.Worktree, "maintenance", "check-head", "--repair")
+	if want := "HEAD: dangling detached " + missing + "\nHEAD reset to " + branch + "\n"; out != want {
+		t.Errorf("repair: got %q, want %q", out, want)
+	}
+	if got := mustRunGot(t, r.Worktree, "maintenance", "check-head"); got != "HEAD: branch "+branch+"\n" {
+		t.Errorf("after repair: got %q", got)
+	}
+}
@@ -0,0 +1,173 @@
+// Package cmd implements commands.
+package cmd
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sboehler/got/pkg/pack"
+	"github.com/sboehler/got/pkg/repository"
+	"github.com/spf13/cobra"
+)
+
+var (
+	packWindow int
+	packDepth  int
+
+	// packObjectsCmd represents the packObjects command
+	packObjectsCmd = &cobra.Command{
+		Use:   "pack-objects [--window N] [--depth N] BASE-NAME",
+		Short: "Create a packed archive of objects",
+		Long: `Read object SHAs from stdin, one per line, and write a packfile and its
+index to BASE-NAME-<sha>.pack and BASE-NAME-<sha>.idx, where <sha> is the
+checksum of the pack. The checksum is printed on stdout.
+
+Objects are stored as deltas against similar objects where this saves
+space. Each object is compared with the --window objects preceding it in an
+order which groups objects by type, path and size. A SHA may be followed by
+a space and the path of the object, which helps to find similar objects.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := openRepository()
+			if err != nil {
+				return err
+			}
+			var (
+				objs []packInput
+				seen = make(map[string]bool)
+				s    = bufio.NewScanner(cmd.InOrStdin())
+			)
+			for s.Scan() {
+				fields := strings.SplitN(strings.TrimSpace(s.Text()), " ", 2)
+				if fields[0] == "" || seen[fields[0]] {
+					continue
+				}
+				seen[fields[0]] = true
+				in := packInput{sha: fields[0]}
+				if len(fields) == 2 {
+					in.path = fields[1]
+				}
+				objs = append(objs, in)
+			}
+			if err := s.Err(); err != nil {
+				return err
+			}
+			sum, err := writePack(r, args[0], objs)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), sum)
+			return nil
+		},
+		Args: cobra.ExactArgs(1),
+	}
+)
+
+// packInput is an object to be packed and the path it was found at.
+type packInput struct {
+	sha, path string
+}
+
+// writePack writes the given objects to a pack and index with the given
+// base name, and returns the checksum of the pack. With --dry-run, only
+// the checksum is computed.
+func writePack(r *repository.Repository, base string, objs []packInput) (string, error) {
+	if r.ReadOnly {
+		_, sum, err := encodePack(r, io.Discard, objs)
+		if err != nil {
+			return "", err
+		}
+		name := hex.EncodeToString(sum)
+		fmt.Fprintf(os.Stderr, "would write %s-%s.pack and .idx\n", base, name)
+		return name, nil
+	}
+	dir := filepath.Dir(base)
+	packFile, err := os.CreateTemp(dir, "tmp_pack_")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(packFile.Name())
+	defer packFile.Close()
+	bw := bufio.NewWriter(packFile)
+	entries, sum, err := encodePack(r, bw, objs)
+	if err != nil {
+		return "", err
+	}
+	if err := bw.Flush(); err != nil {
+		return "", err
+	}
+	if err := packFile.Close(); err != nil {
+		return "", err
+	}
+	idxFile, err := os.CreateTemp(dir, "tmp_idx_")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(idxFile.Name())
+	defer idxFile.Close()
+	if err := pack.WriteIndex(idxFile, entries, sum); err != nil {
+		return "", err
+	}
+	if err := idxFile.Close(); err != nil {
+		return "", err
+	}
+	name := hex.EncodeToString(sum)
+	// packs are immutable, and git creates them read-only
+	for _, f := range []*os.File{packFile, idxFile} {
+		if err := os.Chmod(f.Name(), 0444); err != nil {
+			return "", err
+		}
+	}
+	if err := os.Rename(packFile.Name(), base+"-"+name+".pack"); err != nil {
+		return "", err
+	}
+	if err := os.Rename(idxFile.Name(), base+"-"+name+".idx"); err != nil {
+		return "", err
+	}
+	r.ReloadPacks()
+	return name, nil
+}
+
+// encodePack writes a pack of the given objects to w, deltifying them as
+// configured by --window and --depth. It returns the index entries of the
+// objects and the pack checksum.
+func encodePack(r *repository.Repository, w io.Writer, objs []packInput) ([]pack.Entry, []byte, error) {
+	packed := make([]*pack.Object, 0, len(objs))
+	for _, in := range objs {
+		of, err := r.ReadObject(in.sha)
+		if err != nil {
+			return nil, nil, err
+		}
+		packed = append(packed, &pack.Object{SHA: in.sha, Type: of.ObjectType, Path: in.path, Data: of.Data})
+	}
+	if packWindow > 0 && packDepth > 0 {
+		pack.FindDeltas(packed, packWindow, packDepth)
+	}
+	pw, err := pack.NewWriter(w, len(packed))
+	if err != nil {
+		return nil, nil, err
+	}
+	p := newProgress("Writing objects", len(packed))
+	for _, o := range packed {
+		if err := pw.AddObject(o); err != nil {
+			return nil, nil, err
+		}
+		p.Inc()
+	}
+	p.Done()
+	sum, err := pw.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	return pw.Entries(), sum, nil
+}
+
+func init() {
+	packObjectsCmd.Flags().IntVar(&packWindow, "window", 10, "the number of objects to compare each object with")
+	packObjectsCmd.Flags().IntVar(&packDepth, "depth", 50, "the maximum length of delta chains")
+	rootCmd.AddCommand(packObjectsCmd)
+}
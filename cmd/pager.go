@@ -0,0 +1,83 @@
+// Package cmd implements commands.
+package cmd
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/sboehler/got/pkg/repository"
+	"github.com/spf13/cobra"
+)
+
+var noPager bool
+
+// maybePager redirects the output of the command to a pager if stdout is
+// a terminal and paging is not disabled. The pager is taken from
+// core.pager, GIT_PAGER or PAGER, in that order, and defaults to less.
+// The returned function closes the pager's input and waits for it to
+// exit.
+func maybePager(cmd *cobra.Command, r *repository.Repository) func() {
+	if noPager || !isTerminal(os.Stdout) {
+		return func() {}
+	}
+	pager := r.ConfigValue("core", "pager")
+	for _, env := range []string{"GIT_PAGER", "PAGER"} {
+		if pager == "" {
+			pager = os.Getenv(env)
+		}
+	}
+	if pager == "" {
+		pager = "less"
+	}
+	if pager == "cat" {
+		return func() {}
+	}
+	c := exec.Command("sh", "-c", pager)
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	c.Env = os.Environ()
+	if os.Getenv("LESS") == "" {
+		c.Env = append(c.Env, "LESS=FRX")
+	}
+	in, err := c.StdinPipe()
+	if err != nil {
+		return func() {}
+	}
+	if err := c.Start(); err != nil {
+		return func() {}
+	}
+	wait := func() {
+		in.Close()
+		c.Wait()
+	}
+	cmd.SetOut(&pagerWriter{w: in, wait: wait})
+	return wait
+}
+
+// pagerWriter writes to a pager. If the pager exits before all output is
+// written, the process exits quietly, like git does on SIGPIPE.
+type pagerWriter struct {
+	w    io.Writer
+	wait func()
+}
+
+func (pw *pagerWriter) Write(bs []byte) (int, error) {
+	n, err := pw.w.Write(bs)
+	if errors.Is(err, syscall.EPIPE) || errors.Is(err, os.ErrClosed) {
+		pw.wait()
+		os.Exit(0)
+	}
+	return n, err
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&noPager, "no-pager", false, "do not pipe output into a pager")
+}
@@ -0,0 +1,67 @@
+// Package cmd implements commands.
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+var (
+	forceProgress bool
+	noProgress    bool
+)
+
+// progress reports the progress of a long-running operation on stderr.
+type progress struct {
+	w     io.Writer
+	phase string
+	total int
+	n     int
+}
+
+// newProgress creates a progress reporter for the given phase. It is a
+// no-op unless progress is requested or stderr is a terminal.
+func newProgress(phase string, total int) *progress {
+	p := &progress{phase: phase, total: total}
+	if progressEnabled() {
+		p.w = os.Stderr
+	}
+	return p
+}
+
+func progressEnabled() bool {
+	if noProgress || quiet {
+		return false
+	}
+	if forceProgress {
+		return true
+	}
+	return isTerminal(os.Stderr)
+}
+
+// Inc advances the progress by one.
+func (p *progress) Inc() {
+	p.n++
+	if p.w == nil {
+		return
+	}
+	if p.total > 0 {
+		fmt.Fprintf(p.w, "\r%s: %d/%d", p.phase, p.n, p.total)
+	} else {
+		fmt.Fprintf(p.w, "\r%s: %d", p.phase, p.n)
+	}
+}
+
+// Done terminates the progress line.
+func (p *progress) Done() {
+	if p.w == nil || p.n == 0 {
+		return
+	}
+	fmt.Fprintln(p.w, ", done.")
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&forceProgress, "progress", false, "force progress reporting")
+	rootCmd.PersistentFlags().BoolVar(&noProgress, "no-progress", false, "disable progress reporting")
+}
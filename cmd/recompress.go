@@ -0,0 +1,55 @@
+// Package cmd implements commands.
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	recompressLevel int
+
+	// recompressCmd represents the recompress command
+	recompressCmd = &cobra.Command{
+		Use:   "recompress",
+		Short: "Rewrite loose objects at a different compression level",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := openRepository()
+			if err != nil {
+				return err
+			}
+			level := r.CompressionLevel()
+			if cmd.Flags().Changed("level") {
+				level = recompressLevel
+			}
+			if level < -1 || level > 9 {
+				return fmt.Errorf("invalid compression level: %d", level)
+			}
+			shas, err := r.LooseObjects()
+			if err != nil {
+				return err
+			}
+			var before, after int64
+			p := newProgress("Recompressing objects", len(shas))
+			for _, sha := range shas {
+				b, a, err := r.RecompressObject(sha, level)
+				if err != nil {
+					return err
+				}
+				before += b
+				after += a
+				p.Inc()
+			}
+			p.Done()
+			fmt.Fprintf(cmd.OutOrStdout(), "%d objects, %d bytes -> %d bytes\n", len(shas), before, after)
+			return nil
+		},
+		Args: cobra.NoArgs,
+	}
+)
+
+func init() {
+	recompressCmd.Flags().IntVar(&recompressLevel, "level", -1, "zlib compression level (0-9, -1 for the default)")
+	rootCmd.AddCommand(recompressCmd)
+}
@@ -0,0 +1,120 @@
+// Package cmd implements commands.
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	reflogExpire            string
+	reflogExpireUnreachable string
+	reflogAll               bool
+
+	// reflogCmd represents the reflog command
+	reflogCmd = &cobra.Command{
+		Use:   "reflog",
+		Short: "Manage reflog information",
+	}
+
+	// reflogExpireCmd represents the reflog expire command
+	reflogExpireCmd = &cobra.Command{
+		Use:   "expire [--all | REF...]",
+		Short: "Prune old reflog entries",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := openRepository()
+			if err != nil {
+				return err
+			}
+			now := time.Now()
+			expire, err := parseExpiry(reflogExpire, r.ConfigValue("gc", "reflogExpire"), "90.days.ago", now)
+			if err != nil {
+				return err
+			}
+			expireUnreachable, err := parseExpiry(reflogExpireUnreachable, r.ConfigValue("gc", "reflogExpireUnreachable"), "30.days.ago", now)
+			if err != nil {
+				return err
+			}
+			refs := args
+			if reflogAll {
+				if refs, err = r.Reflogs(); err != nil {
+					return err
+				}
+			} else if len(refs) == 0 {
+				return fmt.Errorf("no reflog specified, use --all for all reflogs")
+			}
+			for _, ref := range refs {
+				n, err := r.ExpireReflog(ref, expire, expireUnreachable)
+				if err != nil {
+					return err
+				}
+				if n > 0 {
+					fmt.Fprintf(cmd.OutOrStdout(), "%s: removed %d entries\n", ref, n)
+				}
+			}
+			return nil
+		},
+	}
+)
+
+var relativeDate = regexp.MustCompile(`^(\d+)\.(second|minute|hour|day|week|month|year)s?\.ago$`)
+
+// parseExpiry parses an expiry time given as a flag, falling back to the
+// configured value and a default. Besides absolute dates, relative dates
+// like "2.weeks.ago" as well as "now", "all" and "never" are accepted.
+func parseExpiry(flag, config, def string, now time.Time) (time.Time, error) {
+	s := flag
+	if s == "" {
+		s = config
+	}
+	if s == "" {
+		s = def
+	}
+	switch s {
+	case "now", "all":
+		return now.Add(time.Second), nil
+	case "never", "false":
+		return time.Time{}, nil
+	}
+	if m := relativeDate.FindStringSubmatch(s); m != nil {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return time.Time{}, err
+		}
+		switch m[2] {
+		case "second":
+			return now.Add(-time.Duration(n) * time.Second), nil
+		case "minute":
+			return now.Add(-time.Duration(n) * time.Minute), nil
+		case "hour":
+			return now.Add(-time.Duration(n) * time.Hour), nil
+		case "day":
+			return now.AddDate(0, 0, -n), nil
+		case "week":
+			return now.AddDate(0, 0, -7*n), nil
+		case "month":
+			return now.AddDate(0, -n, 0), nil
+		default:
+			return now.AddDate(-n, 0, 0), nil
+		}
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, strings.TrimSpace(s), time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid expiry date %q", s)
+}
+
+func init() {
+	reflogExpireCmd.Flags().StringVar(&reflogExpire, "expire", "", "prune entries older than the given time")
+	reflogExpireCmd.Flags().StringVar(&reflogExpireUnreachable, "expire-unreachable", "", "prune entries older than the given time which are unreachable from the current tip")
+	reflogExpireCmd.Flags().BoolVar(&reflogAll, "all", false, "process the reflogs of all refs")
+	reflogCmd.AddCommand(reflogExpireCmd)
+	rootCmd.AddCommand(reflogCmd)
+}
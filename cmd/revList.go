@@ -0,0 +1,140 @@
+/*
+Copyright © 2022 NAME HERE <EMAIL ADDRESS>
+
+*/
+
+// Package cmd implements commands.
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sboehler/got/pkg/repository"
+	"github.com/spf13/cobra"
+)
+
+var (
+	revListAll      bool
+	revListCount    bool
+	revListMaxCount int
+	revListDepth    int
+	revListTopo     bool
+
+	// revListCmd represents the rev-list command
+	revListCmd = &cobra.Command{
+		Use:   "rev-list [--all] [--count] [--depth N] [--topo-order] REVISION...",
+		Short: "List commits in reverse chronological order",
+		Long: `List the commits reachable from the given revisions, newest first. A
+revision prefixed with ^ excludes the commits reachable from it, and A..B
+is short for ^A B. --depth stops the walk after the given number of
+commits along each path. --topo-order lists no commit before its
+children.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 && !revListAll {
+				return fmt.Errorf("no revisions given")
+			}
+			r, err := openRepository()
+			if err != nil {
+				return err
+			}
+			walker := r.NewCommitWalker()
+			walker.Depth = revListDepth
+			if revListTopo {
+				walker.Order = repository.Topological
+			}
+			if revListAll {
+				if err := pushAllRefs(r, walker); err != nil {
+					return err
+				}
+			}
+			if err := pushRevisions(r, walker, args); err != nil {
+				return err
+			}
+			w := cmd.OutOrStdout()
+			n := 0
+			for ; revListMaxCount < 0 || n < revListMaxCount; n++ {
+				sha, _, err := walker.Next()
+				if err != nil {
+					return err
+				}
+				if sha == "" {
+					break
+				}
+				if !revListCount {
+					fmt.Fprintln(w, sha)
+				}
+			}
+			if revListCount {
+				fmt.Fprintln(w, n)
+			}
+			return nil
+		},
+	}
+)
+
+// pushRevisions adds the commits named by the revisions to the walk. A
+// revision prefixed with ^ hides the commits reachable from it, and A..B
+// hides A and adds B, where an omitted side stands for HEAD.
+func pushRevisions(r *repository.Repository, walker *repository.CommitWalker, revs []string) error {
+	for _, rev := range revs {
+		if i := strings.Index(rev, ".."); i >= 0 {
+			from, to := rev[:i], rev[i+2:]
+			if from == "" {
+				from = "HEAD"
+			}
+			if to == "" {
+				to = "HEAD"
+			}
+			if err := pushRevisions(r, walker, []string{"^" + from, to}); err != nil {
+				return err
+			}
+			continue
+		}
+		hide := strings.HasPrefix(rev, "^")
+		sha, err := r.Find(strings.TrimPrefix(rev, "^"), "commit", true)
+		if err != nil {
+			return err
+		}
+		if hide {
+			err = walker.Hide(sha)
+		} else {
+			err = walker.Push(sha)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pushAllRefs adds HEAD and all refs pointing to commits to the walk.
+func pushAllRefs(r *repository.Repository, walker *repository.CommitWalker) error {
+	refs, err := r.Refs()
+	if err != nil {
+		return err
+	}
+	if sha, err := r.ResolveRef("HEAD"); err == nil {
+		refs = append(refs, repository.Ref{Name: "HEAD", SHA: sha})
+	}
+	for _, ref := range refs {
+		sha, err := r.Peel(ref.SHA, "commit")
+		if err != nil {
+			// refs may point to trees and blobs
+			continue
+		}
+		if err := walker.Push(sha); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func init() {
+	revListCmd.Flags().BoolVar(&revListAll, "all", false, "walk from all refs and HEAD")
+	revListCmd.Flags().BoolVar(&revListCount, "count", false, "print the number of commits instead of listing them")
+	revListCmd.Flags().IntVarP(&revListMaxCount, "max-count", "n", -1, "limit the number of commits")
+	revListCmd.Flags().IntVar(&revListDepth, "depth", 0, "limit the walk to the given number of commits along each path")
+	revListCmd.Flags().BoolVar(&revListTopo, "topo-order", false, "list no commit before its children")
+	rootCmd.AddCommand(revListCmd)
+}
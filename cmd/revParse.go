@@ -8,35 +8,79 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
-// revParseCmd represents the revParse command
-var revParseCmd = &cobra.Command{
-	Use:   "revParse",
-	Short: "A brief description of your command",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
+var (
+	revParseVerify bool
+	revParseShort  bool
 
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("revParse called")
-	},
-}
+	// revParseCmd represents the rev-parse command
+	revParseCmd = &cobra.Command{
+		Use:   "rev-parse [--verify] [--short] REVISION...",
+		Short: "Resolve revisions to object names",
+		Long: `Print the SHA of the object each revision names. Revisions are of the
+forms described in git-rev-parse(1), e.g. HEAD~2, master^2, v1.0^{tree},
+HEAD:path/to/file, :path/in/index or master@{1}. ^REV and A..B are
+printed as exclusions. With --verify, exactly one revision must be given.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if revParseVerify && len(args) != 1 {
+				return fmt.Errorf("needed a single revision")
+			}
+			r, err := openRepository()
+			if err != nil {
+				return err
+			}
+			w := cmd.OutOrStdout()
+			show := func(prefix, rev string) error {
+				sha, err := r.Resolve(rev)
+				if err != nil {
+					return err
+				}
+				if revParseShort {
+					if sha, err = r.Abbreviate(sha); err != nil {
+						return err
+					}
+				}
+				fmt.Fprintf(w, "%s%s\n", prefix, sha)
+				return nil
+			}
+			for _, arg := range args {
+				if i := strings.Index(arg, ".."); i >= 0 && !revParseVerify {
+					from, to := arg[:i], arg[i+2:]
+					if from == "" {
+						from = "HEAD"
+					}
+					if to == "" {
+						to = "HEAD"
+					}
+					if err := show("", to); err != nil {
+						return err
+					}
+					if err := show("^", from); err != nil {
+						return err
+					}
+					continue
+				}
+				if strings.HasPrefix(arg, "^") && !revParseVerify {
+					if err := show("^", arg[1:]); err != nil {
+						return err
+					}
+					continue
+				}
+				if err := show("", arg); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+)
 
 func init() {
+	revParseCmd.Flags().BoolVar(&revParseVerify, "verify", false, "verify that exactly one revision is given and can be resolved")
+	revParseCmd.Flags().BoolVar(&revParseShort, "short", false, "print abbreviated SHAs")
 	rootCmd.AddCommand(revParseCmd)
-
-	// Here you will define your flags and configuration settings.
-
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// revParseCmd.PersistentFlags().String("foo", "", "A help for foo")
-
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// revParseCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
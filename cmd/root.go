@@ -21,11 +21,15 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/sboehler/got/pkg/repository"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
-var cfgFile string
+var (
+	cfgFile string
+	dryRun  bool
+)
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -61,6 +65,7 @@ func init() {
 	// will be global for your application.
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.got.yaml)")
+	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "report changes to the repository without making them")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -90,3 +95,18 @@ func initConfig() {
 		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 	}
 }
+
+// openRepository finds the repository containing the working directory
+// and applies the global --dry-run flag to it.
+func openRepository() (*repository.Repository, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	r, err := repository.Find(wd)
+	if err != nil {
+		return nil, err
+	}
+	r.ReadOnly = dryRun
+	return r, nil
+}
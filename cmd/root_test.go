@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// snapshot returns the contents of all files and symlinks below dir.
+func snapshot(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	res := make(map[string]string)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		var content []byte
+		if d.Type()&fs.ModeSymlink != 0 {
+			target, err := os.Readlink(p)
+			content = []byte(target)
+			if err != nil {
+				return err
+			}
+		} else if content, err = os.ReadFile(p); err != nil {
+			return err
+		}
+		res[p] = d.Type().String() + " " + string(content)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+// captureStderr returns what fn writes to os.Stderr.
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	rd, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = w
+	out := make(chan string)
+	go func() {
+		bs, _ := io.ReadAll(rd)
+		out <- string(bs)
+	}()
+	defer func() { os.Stderr = stderr }()
+	fn()
+	w.Close()
+	return <-out
+}
+
+func TestDryRunCommit(t *testing.T) {
+	r := newCmdTestRepo(t)
+	writeFile(t, r, "a", "a\n")
+	mustRunGot(t, r.Worktree, "add", "a")
+	before := snapshot(t, r.Worktree)
+	stderr := captureStderr(t, func() {
+		mustRunGot(t, r.Worktree, "--dry-run", "commit", "-m", "first")
+	})
+	if after := snapshot(t, r.Worktree); !reflect.DeepEqual(after, before) {
+		t.Errorf("commit --dry-run changed the repository")
+	}
+	if _, err := runGot(t, r.Worktree, "", "rev-parse", "HEAD"); err == nil {
+		t.Errorf("commit --dry-run created a commit")
+	}
+
+	mustRunGot(t, r.Worktree, "commit", "-m", "first")
+	head := strings.TrimSpace(mustRunGot(t, r.Worktree, "rev-parse", "HEAD"))
+	tree := strings.TrimSpace(mustRunGot(t, r.Worktree, "rev-parse", "HEAD^{tree}"))
+	for _, want := range []string{"would write object " + tree, "would write object " + head, "would update HEAD to " + head} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("commit --dry-run did not report %q:\n%s", want, stderr)
+		}
+	}
+}
+
+func TestDryRunReflogExpire(t *testing.T) {
+	r := newCmdTestRepo(t)
+	writeFile(t, r, "a", "a\n")
+	mustRunGot(t, r.Worktree, "add", "a")
+	mustRunGot(t, r.Worktree, "commit", "-m", "first")
+	writeFile(t, r, "a", "b\n")
+	mustRunGot(t, r.Worktree, "add", "a")
+	mustRunGot(t, r.Worktree, "commit", "-m", "second")
+	before := snapshot(t, r.Worktree)
+	var out string
+	stderr := captureStderr(t, func() {
+		out = mustRunGot(t, r.Worktree, "--dry-run", "reflog", "expire", "--all", "--expire=all")
+	})
+	if after := snapshot(t, r.Worktree); !reflect.DeepEqual(after, before) {
+		t.Errorf("reflog expire --dry-run changed the repository")
+	}
+	if want := "HEAD: removed 1 entries\nrefs/heads/master: removed 1 entries\n"; out != want {
+		t.Errorf("got output %q, want %q", out, want)
+	}
+	for _, ref := range []string{"HEAD", "refs/heads/master"} {
+		if want := "would write " + filepath.Join(r.GitDir, "logs", filepath.FromSlash(ref)); !strings.Contains(stderr, want) {
+			t.Errorf("reflog expire --dry-run did not report %q:\n%s", want, stderr)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Package cmd implements commands.
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	shortlogSummary  bool
+	shortlogNumbered bool
+	shortlogEmail    bool
+
+	// shortlogCmd represents the shortlog command
+	shortlogCmd = &cobra.Command{
+		Use:   "shortlog [-s] [-n] [-e] [REVISION...] [-- PATH...]",
+		Short: "Summarize the log by author",
+		Long: `Group the commits reachable from the given revisions, or from HEAD, by
+author, and print each author with the number and the subjects of their
+commits. Authors are sorted by name, or by the number of commits with
+-n. -s prints only the numbers, and -e adds the authors' emails.
+Revisions and paths are given as in log.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := openRepository()
+			if err != nil {
+				return err
+			}
+			walker, err := newLogWalker(cmd, r, args)
+			if err != nil {
+				return err
+			}
+			subjects := make(map[string][]string)
+			for {
+				sha, c, err := walker.Next()
+				if err != nil {
+					return err
+				}
+				if sha == "" {
+					break
+				}
+				author := c.Author().Name
+				if shortlogEmail {
+					author = fmt.Sprintf("%s <%s>", author, c.Author().Email)
+				}
+				subject := strings.SplitN(c.Message(), "\n", 2)[0]
+				subjects[author] = append(subjects[author], subject)
+			}
+			authors := make([]string, 0, len(subjects))
+			for a := range subjects {
+				authors = append(authors, a)
+			}
+			sort.Slice(authors, func(i, j int) bool {
+				a, b := authors[i], authors[j]
+				if shortlogNumbered && len(subjects[a]) != len(subjects[b]) {
+					return len(subjects[a]) > len(subjects[b])
+				}
+				return a < b
+			})
+			defer maybePager(cmd, r)()
+			w := cmd.OutOrStdout()
+			for _, a := range authors {
+				if shortlogSummary {
+					fmt.Fprintf(w, "%6d\t%s\n", len(subjects[a]), a)
+					continue
+				}
+				fmt.Fprintf(w, "%s (%d):\n", a, len(subjects[a]))
+				// the oldest commit first
+				for i := len(subjects[a]) - 1; i >= 0; i-- {
+					fmt.Fprintf(w, "      %s\n", subjects[a][i])
+				}
+				fmt.Fprintln(w)
+			}
+			return nil
+		},
+	}
+)
+
+func init() {
+	shortlogCmd.Flags().BoolVarP(&shortlogSummary, "summary", "s", false, "print only the number of commits per author")
+	shortlogCmd.Flags().BoolVarP(&shortlogNumbered, "numbered", "n", false, "sort authors by their number of commits")
+	shortlogCmd.Flags().BoolVarP(&shortlogEmail, "email", "e", false, "show the email of each author")
+	rootCmd.AddCommand(shortlogCmd)
+}
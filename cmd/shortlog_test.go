@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestShortlog(t *testing.T) {
+	r := newCmdTestRepo(t)
+	for i, author := range []string{"Zoe <zoe@example.com>", "Adam <adam@example.com>", "Zoe <zoe@example.com>"} {
+		writeFile(t, r, "file", fmt.Sprintf("version %d\n", i))
+		mustRunGot(t, r.Worktree, "add", "file")
+		mustRunGot(t, r.Worktree, "commit", "-m", fmt.Sprintf("commit %d\n\nbody", i), "--author", author)
+	}
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "Adam (1):\n      commit 1\n\nZoe (2):\n      commit 0\n      commit 2\n\n"},
+		{[]string{"-s"}, "     1\tAdam\n     2\tZoe\n"},
+		{[]string{"-s", "-n"}, "     2\tZoe\n     1\tAdam\n"},
+		{[]string{"-s", "-e", "HEAD^"}, "     1\tAdam <adam@example.com>\n     1\tZoe <zoe@example.com>\n"},
+	}
+	for _, test := range tests {
+		if got := mustRunGot(t, r.Worktree, append([]string{"shortlog"}, test.args...)...); got != test.want {
+			t.Errorf("shortlog %v: got\n%q\nwant\n%q", test.args, got, test.want)
+		}
+	}
+}
@@ -8,35 +8,89 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
-// showRefCmd represents the showRef command
-var showRefCmd = &cobra.Command{
-	Use:   "showRef",
-	Short: "A brief description of your command",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
-
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("showRef called")
-	},
+var (
+	showRefHeads       bool
+	showRefTags        bool
+	showRefHash        bool
+	showRefDereference bool
+
+	// showRefCmd represents the show-ref command
+	showRefCmd = &cobra.Command{
+		Use:   "show-ref [--heads] [--tags] [--hash] [-d] [PATTERN...]",
+		Short: "List references in the local repository",
+		Long: `List the loose and packed refs of the repository as "<sha> <refname>".
+If patterns are given, only refs whose name equals a pattern or ends with
+"/" followed by a pattern are shown.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := openRepository()
+			if err != nil {
+				return err
+			}
+			refs, err := r.Refs()
+			if err != nil {
+				return err
+			}
+			w := cmd.OutOrStdout()
+			var found bool
+			for _, ref := range refs {
+				if !showRefMatches(ref.Name, args) {
+					continue
+				}
+				found = true
+				if showRefHash {
+					fmt.Fprintln(w, ref.SHA)
+				} else {
+					fmt.Fprintf(w, "%s %s\n", ref.SHA, ref.Name)
+				}
+				if !showRefDereference || !strings.HasPrefix(ref.Name, "refs/tags/") {
+					continue
+				}
+				peeled, err := r.Peel(ref.SHA, "")
+				if err != nil || peeled == ref.SHA {
+					continue
+				}
+				if showRefHash {
+					fmt.Fprintln(w, peeled)
+				} else {
+					fmt.Fprintf(w, "%s %s^{}\n", peeled, ref.Name)
+				}
+			}
+			if !found {
+				return fmt.Errorf("no matching refs")
+			}
+			return nil
+		},
+	}
+)
+
+// showRefMatches returns whether the ref passes the --heads and --tags
+// filters and matches one of the patterns.
+func showRefMatches(name string, patterns []string) bool {
+	if showRefHeads || showRefTags {
+		if !(showRefHeads && strings.HasPrefix(name, "refs/heads/") || showRefTags && strings.HasPrefix(name, "refs/tags/")) {
+			return false
+		}
+	}
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if name == p || strings.HasSuffix(name, "/"+p) {
+			return true
+		}
+	}
+	return false
 }
 
 func init() {
+	showRefCmd.Flags().BoolVar(&showRefHeads, "heads", false, "only show branches")
+	showRefCmd.Flags().BoolVar(&showRefTags, "tags", false, "only show tags")
+	showRefCmd.Flags().BoolVar(&showRefHash, "hash", false, "only show the SHAs")
+	showRefCmd.Flags().BoolVarP(&showRefDereference, "dereference", "d", false, "also show the objects annotated tags point to")
 	rootCmd.AddCommand(showRefCmd)
-
-	// Here you will define your flags and configuration settings.
-
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// showRefCmd.PersistentFlags().String("foo", "", "A help for foo")
-
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// showRefCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 }
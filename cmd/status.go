@@ -0,0 +1,122 @@
+/*
+Copyright © 2022 NAME HERE <EMAIL ADDRESS>
+
+*/
+
+// Package cmd implements commands.
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sboehler/got/pkg/repository"
+	"github.com/spf13/cobra"
+)
+
+var (
+	statusPorcelain bool
+	statusNul       bool
+
+	// statusCmd represents the status command
+	statusCmd = &cobra.Command{
+		Use:   "status [--porcelain] [-z] [PATH...]",
+		Short: "Show the working tree status",
+		Long: `Show the paths which differ between HEAD and the index, and between the
+index and the worktree, as well as untracked files. With --porcelain, each
+path is printed with a stable two-letter status for scripts: the first
+letter describes the staged change, the second the unstaged change. -z
+terminates entries with NUL instead of newlines and implies --porcelain.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := openRepository()
+			if err != nil {
+				return err
+			}
+			if err := r.RequireWorktree(); err != nil {
+				return err
+			}
+			var paths []string
+			for _, arg := range args {
+				p, err := r.RelPath(arg)
+				if err != nil {
+					return err
+				}
+				paths = append(paths, p)
+			}
+			idx, err := r.ReadIndex()
+			if err != nil {
+				return err
+			}
+			statuses, err := r.Status(idx, paths...)
+			if err != nil {
+				return err
+			}
+			if statusPorcelain || statusNul {
+				return repository.WritePorcelain(cmd.OutOrStdout(), statuses, statusNul)
+			}
+			return printStatus(cmd.OutOrStdout(), r, statuses)
+		},
+	}
+)
+
+// statusNames describes the status letters in the human-readable output.
+var statusNames = map[byte]string{'A': "new file", 'M': "modified", 'D': "deleted"}
+
+// printStatus prints the statuses grouped into staged, unstaged, unmerged
+// and untracked paths, as git status does.
+func printStatus(w io.Writer, r *repository.Repository, statuses []repository.FileStatus) error {
+	state, target, err := r.CheckHead()
+	if err != nil {
+		return err
+	}
+	switch state {
+	case repository.HeadBranch, repository.HeadUnborn:
+		fmt.Fprintf(w, "On branch %s\n", strings.TrimPrefix(target, "refs/heads/"))
+	default:
+		fmt.Fprintf(w, "HEAD detached at %s\n", target[:7])
+	}
+	var staged, unstaged, unmerged, untracked []string
+	for _, s := range statuses {
+		switch {
+		case s.Staged == '?':
+			untracked = append(untracked, s.Path)
+		case s.Staged == 'U':
+			unmerged = append(unmerged, s.Path)
+		default:
+			if s.Staged != ' ' {
+				staged = append(staged, fmt.Sprintf("%-12s%s", statusNames[s.Staged]+":", s.Path))
+			}
+			if s.Unstaged != ' ' {
+				unstaged = append(unstaged, fmt.Sprintf("%-12s%s", statusNames[s.Unstaged]+":", s.Path))
+			}
+		}
+	}
+	for _, group := range []struct {
+		title string
+		paths []string
+	}{
+		{"Changes to be committed:", staged},
+		{"Unmerged paths:", unmerged},
+		{"Changes not staged for commit:", unstaged},
+		{"Untracked files:", untracked},
+	} {
+		if len(group.paths) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s\n", group.title)
+		for _, p := range group.paths {
+			fmt.Fprintf(w, "\t%s\n", p)
+		}
+	}
+	if len(statuses) == 0 {
+		fmt.Fprintln(w, "nothing to commit, working tree clean")
+	}
+	return nil
+}
+
+func init() {
+	statusCmd.Flags().BoolVar(&statusPorcelain, "porcelain", false, "give the output in a stable format for scripts")
+	statusCmd.Flags().BoolVarP(&statusNul, "null", "z", false, "terminate entries with NUL")
+	rootCmd.AddCommand(statusCmd)
+}
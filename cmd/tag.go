@@ -8,35 +8,136 @@ package cmd
 
 import (
 	"fmt"
+	"path"
+	"strings"
 
+	"github.com/sboehler/got/pkg/repository"
 	"github.com/spf13/cobra"
 )
 
-// tagCmd represents the tag command
-var tagCmd = &cobra.Command{
-	Use:   "tag",
-	Short: "A brief description of your command",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
-
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("tag called")
-	},
-}
+var (
+	tagAnnotate bool
+	tagMessages []string
+	tagDelete   bool
+	tagList     bool
 
-func init() {
-	rootCmd.AddCommand(tagCmd)
+	// tagCmd represents the tag command
+	tagCmd = &cobra.Command{
+		Use:   "tag [-l [PATTERN]] | [-a -m MESSAGE] NAME [OBJECT] | -d NAME...",
+		Short: "List, create or delete tags",
+		Long: `Without arguments or with -l, list the tags, optionally only those
+matching a glob pattern. With a name, create a lightweight tag for the
+given object, which defaults to HEAD. With -a or -m, an annotated tag
+object is written. -d deletes tags.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := openRepository()
+			if err != nil {
+				return err
+			}
+			switch {
+			case tagDelete:
+				return deleteTags(r, args)
+			case tagList || len(args) == 0:
+				return listTags(cmd, r, args)
+			}
+			return createTag(r, args)
+		},
+	}
+)
+
+// listTags prints the names of the tags matching one of the patterns.
+func listTags(cmd *cobra.Command, r *repository.Repository, patterns []string) error {
+	tags, err := r.RefsWithPrefix("refs/tags/")
+	if err != nil {
+		return err
+	}
+	w := cmd.OutOrStdout()
+	for _, t := range tags {
+		name := strings.TrimPrefix(t.Name, "refs/tags/")
+		matched := len(patterns) == 0
+		for _, p := range patterns {
+			if ok, _ := path.Match(p, name); ok {
+				matched = true
+			}
+		}
+		if matched {
+			fmt.Fprintln(w, name)
+		}
+	}
+	return nil
+}
 
-	// Here you will define your flags and configuration settings.
+// createTag creates the tag args[0] for the object args[1], or HEAD.
+func createTag(r *repository.Repository, args []string) error {
+	if len(args) > 2 {
+		return fmt.Errorf("too many arguments")
+	}
+	rev := "HEAD"
+	if len(args) == 2 {
+		rev = args[1]
+	}
+	name := "refs/tags/" + args[0]
+	if _, err := r.ResolveRef(name); err == nil {
+		return fmt.Errorf("tag '%s' already exists", args[0])
+	}
+	sha, err := r.Find(rev, "", false)
+	if err != nil {
+		return err
+	}
+	if tagAnnotate || len(tagMessages) > 0 {
+		msg := cleanMessage(strings.Join(tagMessages, "\n\n"))
+		if msg == "" {
+			return fmt.Errorf("no tag message given, use -m")
+		}
+		if sha, err = r.WriteTag(args[0], sha, msg); err != nil {
+			return err
+		}
+	}
+	tx := r.NewRefTransaction()
+	if err := tx.Update(name, sha, repository.ZeroSHA); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
 
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// tagCmd.PersistentFlags().String("foo", "", "A help for foo")
+// deleteTags deletes the given tags in a single transaction.
+func deleteTags(r *repository.Repository, names []string) error {
+	if len(names) == 0 {
+		return fmt.Errorf("tag name required")
+	}
+	tx := r.NewRefTransaction()
+	defer tx.Abort()
+	shas := make([]string, len(names))
+	for i, name := range names {
+		sha, err := r.ResolveRef("refs/tags/" + name)
+		if err != nil {
+			return fmt.Errorf("tag '%s' not found", name)
+		}
+		if err := tx.Delete("refs/tags/"+name, sha); err != nil {
+			return err
+		}
+		shas[i] = sha
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if err := r.PruneEmptyDirs(); err != nil {
+		return err
+	}
+	for i, name := range names {
+		abbrev, err := r.Abbreviate(shas[i])
+		if err != nil {
+			return err
+		}
+		infof("Deleted tag '%s' (was %s)\n", name, abbrev)
+	}
+	return nil
+}
 
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// tagCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+func init() {
+	tagCmd.Flags().BoolVarP(&tagAnnotate, "annotate", "a", false, "create an annotated tag object")
+	tagCmd.Flags().StringArrayVarP(&tagMessages, "message", "m", nil, "use the given tag message")
+	tagCmd.Flags().BoolVarP(&tagDelete, "delete", "d", false, "delete tags")
+	tagCmd.Flags().BoolVarP(&tagList, "list", "l", false, "list tags matching the patterns")
+	rootCmd.AddCommand(tagCmd)
 }
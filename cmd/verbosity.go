@@ -0,0 +1,32 @@
+// Package cmd implements commands.
+package cmd
+
+import (
+	"fmt"
+	"os"
+)
+
+var (
+	quiet   bool
+	verbose bool
+)
+
+// infof prints an informational message to stderr unless --quiet is
+// given.
+func infof(format string, args ...interface{}) {
+	if !quiet {
+		fmt.Fprintf(os.Stderr, format, args...)
+	}
+}
+
+// verbosef prints a detailed message to stderr if --verbose is given.
+func verbosef(format string, args ...interface{}) {
+	if verbose && !quiet {
+		fmt.Fprintf(os.Stderr, format, args...)
+	}
+}
+
+func init() {
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "suppress informational output")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "print detailed output")
+}
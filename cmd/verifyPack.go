@@ -0,0 +1,100 @@
+// Package cmd implements commands.
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sboehler/got/pkg/pack"
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyPackStatOnly bool
+
+	// verifyPackCmd represents the verifyPack command
+	verifyPackCmd = &cobra.Command{
+		Use:   "verify-pack [-v] [-s] PACK...",
+		Short: "Validate packed archive files",
+		Long: `Check that each pack matches its index: the checksums of both files,
+the CRC32 checksums of the objects and the SHAs of the objects. PACK is the
+path to a .pack or .idx file. With -v, every object is listed with its type,
+size, size in the pack and offset, followed by the delta depth and the base
+of deltas, and the number of objects per delta chain length. With -s, only
+the statistics are printed.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			w := cmd.OutOrStdout()
+			for _, arg := range args {
+				name := strings.TrimSuffix(strings.TrimSuffix(arg, ".idx"), ".pack")
+				if err := verifyPack(w, name); err != nil {
+					if verbose && !verifyPackStatOnly {
+						fmt.Fprintf(w, "%s.pack: bad\n", name)
+					}
+					return err
+				}
+				if verbose && !verifyPackStatOnly {
+					fmt.Fprintf(w, "%s.pack: ok\n", name)
+				}
+			}
+			return nil
+		},
+		Args: cobra.MinimumNArgs(1),
+	}
+)
+
+// verifyPack verifies the pack with the given path without extension and
+// prints its objects and statistics as requested.
+func verifyPack(w io.Writer, name string) error {
+	idx, err := pack.LoadIndex(name + ".idx")
+	if err != nil {
+		return err
+	}
+	p, err := pack.Open(idx)
+	if err != nil {
+		return err
+	}
+	defer p.Close()
+	infos, err := p.Verify()
+	if err != nil {
+		return err
+	}
+	if !verbose && !verifyPackStatOnly {
+		return nil
+	}
+	var chains []int
+	for _, info := range infos {
+		for len(chains) <= info.Depth {
+			chains = append(chains, 0)
+		}
+		chains[info.Depth]++
+		if verifyPackStatOnly {
+			continue
+		}
+		fmt.Fprintf(w, "%s %-6s %d %d %d", info.SHA, info.Type, info.Size, info.PackedSize, info.Offset)
+		if info.Depth > 0 {
+			fmt.Fprintf(w, " %d %s", info.Depth, info.Base)
+		}
+		fmt.Fprintln(w)
+	}
+	for depth, n := range chains {
+		if n == 0 {
+			continue
+		}
+		objects := "objects"
+		if n == 1 {
+			objects = "object"
+		}
+		if depth == 0 {
+			fmt.Fprintf(w, "non delta: %d %s\n", n, objects)
+		} else {
+			fmt.Fprintf(w, "chain length = %d: %d %s\n", depth, n, objects)
+		}
+	}
+	return nil
+}
+
+func init() {
+	verifyPackCmd.Flags().BoolVarP(&verifyPackStatOnly, "stat-only", "s", false, "only print the number of objects per delta chain length")
+	rootCmd.AddCommand(verifyPackCmd)
+}
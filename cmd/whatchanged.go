@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/sboehler/got/pkg/object"
+	"github.com/sboehler/got/pkg/repository"
+	"github.com/spf13/cobra"
+)
+
+var (
+	whatchangedMaxCount int
+
+	// whatchangedCmd represents the whatchanged command
+	whatchangedCmd = &cobra.Command{
+		Use:   "whatchanged [-n N] [REVISION...] [-- PATH...]",
+		Short: "Show logs with the files each commit changed",
+		Long: `Show the commits reachable from the given revisions, or from HEAD, as log
+does, each followed by the files it changed compared to its first parent
+in git's raw diff format. Commits which change no files are omitted.
+Paths after -- limit the output to the commits and files which change
+them, following only the first parent of merges.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := openRepository()
+			if err != nil {
+				return err
+			}
+			walker, err := newLogWalker(cmd, r, args)
+			if err != nil {
+				return err
+			}
+			defer maybePager(cmd, r)()
+			w := cmd.OutOrStdout()
+			for n := 0; whatchangedMaxCount < 0 || n < whatchangedMaxCount; {
+				sha, c, err := walker.Next()
+				if err != nil {
+					return err
+				}
+				if sha == "" {
+					break
+				}
+				changes, err := commitChanges(r, c, walker.Paths)
+				if err != nil {
+					return err
+				}
+				if len(changes) == 0 {
+					continue
+				}
+				if n > 0 {
+					fmt.Fprintln(w)
+				}
+				if err := printCommit(w, r, "medium", false, sha, c, walker.IsBoundary(sha)); err != nil {
+					return err
+				}
+				fmt.Fprintln(w)
+				if err := printRawChanges(w, r, changes, true); err != nil {
+					return err
+				}
+				n++
+			}
+			return nil
+		},
+	}
+)
+
+// commitChanges returns the files at or below the given paths which the
+// commit changes compared to its first parent.
+func commitChanges(r *repository.Repository, c *object.Commit, paths []string) ([]repository.TreeChange, error) {
+	var parentTree string
+	if parents := c.Parents(); len(parents) > 0 {
+		p, err := r.ReadCommit(parents[0])
+		if err != nil {
+			return nil, err
+		}
+		parentTree = p.Tree()
+	}
+	return r.DiffTrees(parentTree, c.Tree(), paths...)
+}
+
+// printRawChanges prints changes in git's raw diff format. If abbrev is
+// set, SHAs are abbreviated.
+func printRawChanges(w io.Writer, r *repository.Repository, changes []repository.TreeChange, abbrev bool) error {
+	zero := repository.ZeroSHA
+	if abbrev {
+		zero = zero[:7]
+	}
+	for _, c := range changes {
+		modes := []string{"000000", "000000"}
+		shas := []string{zero, zero}
+		for i, e := range []object.TreeEntry{c.Old, c.New} {
+			if e.SHA == "" {
+				continue
+			}
+			modes[i], shas[i] = fmt.Sprintf("%06s", e.Mode), e.SHA
+			if abbrev {
+				var err error
+				if shas[i], err = r.Abbreviate(e.SHA); err != nil {
+					return err
+				}
+			}
+		}
+		fmt.Fprintf(w, ":%s %s %s %s %c\t%s\n", modes[0], modes[1], shas[0], shas[1], c.Status(), c.Path)
+	}
+	return nil
+}
+
+func init() {
+	whatchangedCmd.Flags().IntVarP(&whatchangedMaxCount, "max-count", "n", -1, "limit the number of commits to show")
+	rootCmd.AddCommand(whatchangedCmd)
+}
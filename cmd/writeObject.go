@@ -0,0 +1,51 @@
+// Package cmd implements commands.
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/sboehler/got/pkg/repository"
+	"github.com/spf13/cobra"
+)
+
+var (
+	writeObjectType string
+
+	// writeObjectCmd represents the writeObject command
+	writeObjectCmd = &cobra.Command{
+		Use:   "write-object",
+		Short: "Write raw object content from stdin to the object database",
+		Long: `Read raw, uncompressed object content without header from stdin,
+write it to the object database as an object of the given type and print
+its SHA. This reconstructs objects byte for byte, e.g. during repairs.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !repository.IsValidObjectType(writeObjectType) {
+				return fmt.Errorf("invalid object type: %s", writeObjectType)
+			}
+			data, err := io.ReadAll(cmd.InOrStdin())
+			if err != nil {
+				return err
+			}
+			r, err := openRepository()
+			if err != nil {
+				return err
+			}
+			hash, err := r.WriteObject(&repository.ObjectFile{
+				ObjectType: writeObjectType,
+				Data:       data,
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), hash)
+			return nil
+		},
+		Args: cobra.NoArgs,
+	}
+)
+
+func init() {
+	writeObjectCmd.Flags().StringVarP(&writeObjectType, "type", "t", "blob", "specify the type")
+	rootCmd.AddCommand(writeObjectCmd)
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteObjectTree(t *testing.T) {
+	r := newCmdTestRepo(t)
+	writeFile(t, r, "a", "a\n")
+	writeFile(t, r, "dir/b", "b\n")
+	mustRunGot(t, r.Worktree, "add", "-A")
+	tree := strings.TrimSpace(mustRunGot(t, r.Worktree, "write-tree"))
+	of, err := r.ReadObject(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := runGot(t, r.Worktree, string(of.Data), "write-object", "-t", "tree")
+	if err != nil {
+		t.Fatalf("got write-object: %v\n%s", err, out)
+	}
+	if got := strings.TrimSpace(out); got != tree {
+		t.Errorf("got %s for the raw tree, want %s", got, tree)
+	}
+	if _, err := runGot(t, r.Worktree, "", "write-object", "-t", "bogus"); err == nil || err.Error() != "invalid object type: bogus" {
+		t.Errorf("got error %v for an invalid type", err)
+	}
+}
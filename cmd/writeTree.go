@@ -0,0 +1,45 @@
+/*
+Copyright © 2022 NAME HERE <EMAIL ADDRESS>
+
+*/
+
+// Package cmd implements commands.
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// writeTreeCmd represents the write-tree command
+var writeTreeCmd = &cobra.Command{
+	Use:   "write-tree",
+	Short: "Create a tree object from the current index",
+	Long: `Write the tree objects for the contents of the index and print the SHA of
+the root tree. Unchanged directories reuse the trees cached in the index.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		r, err := openRepository()
+		if err != nil {
+			return err
+		}
+		idx, err := r.ReadIndex()
+		if err != nil {
+			return err
+		}
+		sha, err := r.WriteTree(idx)
+		if err != nil {
+			return err
+		}
+		if err := r.WriteIndex(idx); err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), sha)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(writeTreeCmd)
+}
@@ -0,0 +1,437 @@
+// Package index implements the git index, also known as the staging
+// area.
+package index
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var signature = []byte("DIRC")
+
+// Entry flags.
+const (
+	flagAssumeValid  = 0x8000
+	flagExtended     = 0x4000
+	flagStageMask    = 0x3000
+	flagStageShift   = 12
+	flagNameMask     = 0x0fff
+	flagSkipWorktree = 0x4000
+	flagIntentToAdd  = 0x2000
+)
+
+// Entry is an entry of the index.
+type Entry struct {
+	CTime, MTime time.Time
+	Dev, Ino     uint32
+	Mode         uint32
+	UID, GID     uint32
+	Size         uint32
+	SHA          string
+	Path         string
+
+	// Stage is 0 for normal entries, and 1 (base), 2 (ours) or 3
+	// (theirs) for the sides of a merge conflict.
+	Stage        int
+	AssumeValid  bool
+	SkipWorktree bool
+	IntentToAdd  bool
+}
+
+// Extension is an index extension, which is kept as is.
+type Extension struct {
+	Signature string
+	Data      []byte
+}
+
+// Index is a parsed index file.
+type Index struct {
+	Version uint32
+	Entries []Entry
+	// Tree is the cache tree from the TREE extension, or nil.
+	Tree *CacheTree
+	// Extensions are the other extensions.
+	Extensions []Extension
+}
+
+// New creates an empty version 2 index.
+func New() *Index {
+	return &Index{Version: 2}
+}
+
+// Entry returns the entry for the given path and stage.
+func (idx *Index) Entry(path string, stage int) (Entry, bool) {
+	i := idx.search(path, stage)
+	if i < len(idx.Entries) && idx.Entries[i].Path == path && idx.Entries[i].Stage == stage {
+		return idx.Entries[i], true
+	}
+	return Entry{}, false
+}
+
+// Add adds the entry to the index, replacing an entry with the same path
+// and stage. Adding a stage 0 entry resolves a conflict by removing the
+// other stages of the path.
+func (idx *Index) Add(e Entry) {
+	if e.Stage == 0 {
+		idx.Remove(e.Path)
+	}
+	i := idx.search(e.Path, e.Stage)
+	if i < len(idx.Entries) && idx.Entries[i].Path == e.Path && idx.Entries[i].Stage == e.Stage {
+		idx.Entries[i] = e
+	} else {
+		idx.Entries = append(idx.Entries, Entry{})
+		copy(idx.Entries[i+1:], idx.Entries[i:])
+		idx.Entries[i] = e
+	}
+	idx.invalidate(e.Path)
+}
+
+// Remove removes all entries for the given path. It returns whether an
+// entry was removed.
+func (idx *Index) Remove(path string) bool {
+	i := idx.search(path, 0)
+	j := i
+	for j < len(idx.Entries) && idx.Entries[j].Path == path {
+		j++
+	}
+	if i == j {
+		return false
+	}
+	idx.Entries = append(idx.Entries[:i], idx.Entries[j:]...)
+	idx.invalidate(path)
+	return true
+}
+
+// RemoveDir removes all entries below the given directory. It returns
+// the number of removed entries.
+func (idx *Index) RemoveDir(dir string) int {
+	prefix := dir + "/"
+	i := idx.search(prefix, 0)
+	j := i
+	for j < len(idx.Entries) && strings.HasPrefix(idx.Entries[j].Path, prefix) {
+		j++
+	}
+	if i == j {
+		return 0
+	}
+	idx.Entries = append(idx.Entries[:i], idx.Entries[j:]...)
+	idx.invalidate(prefix)
+	return j - i
+}
+
+// HasDir returns whether the index has entries below the given
+// directory.
+func (idx *Index) HasDir(dir string) bool {
+	prefix := dir + "/"
+	i := idx.search(prefix, 0)
+	return i < len(idx.Entries) && strings.HasPrefix(idx.Entries[i].Path, prefix)
+}
+
+// Conflicted returns whether the index has entries with a stage other
+// than 0.
+func (idx *Index) Conflicted() bool {
+	for _, e := range idx.Entries {
+		if e.Stage != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// search returns the position of the given path and stage in the sorted
+// entries.
+func (idx *Index) search(path string, stage int) int {
+	return sort.Search(len(idx.Entries), func(i int) bool {
+		e := idx.Entries[i]
+		return e.Path > path || e.Path == path && e.Stage >= stage
+	})
+}
+
+// invalidate invalidates the cached trees containing the given path and
+// drops the untracked cache.
+func (idx *Index) invalidate(path string) {
+	if idx.Tree != nil {
+		idx.Tree.invalidate(path)
+	}
+	var exts []Extension
+	for _, ext := range idx.Extensions {
+		if ext.Signature != "UNTR" {
+			exts = append(exts, ext)
+		}
+	}
+	idx.Extensions = exts
+}
+
+// Parse parses an index file of version 2, 3 or 4 and verifies its
+// checksum.
+func Parse(bs []byte) (*Index, error) {
+	if len(bs) < 32 || !bytes.Equal(bs[:4], signature) {
+		return nil, fmt.Errorf("invalid index signature")
+	}
+	if _, ok := Checksum(bs); !ok {
+		return nil, fmt.Errorf("index checksum mismatch")
+	}
+	return ParseUnchecked(bs)
+}
+
+// Checksum returns the checksum at the end of an index file and whether
+// it matches the file's content.
+func Checksum(bs []byte) (string, bool) {
+	if len(bs) < 20 {
+		return "", false
+	}
+	body, sum := bs[:len(bs)-20], bs[len(bs)-20:]
+	actual := sha1.Sum(body)
+	return hex.EncodeToString(sum), bytes.Equal(actual[:], sum)
+}
+
+// ParseUnchecked parses an index file like Parse, but does not verify its
+// checksum, so that damaged index files can be inspected.
+func ParseUnchecked(bs []byte) (*Index, error) {
+	if len(bs) < 32 || !bytes.Equal(bs[:4], signature) {
+		return nil, fmt.Errorf("invalid index signature")
+	}
+	body := bs[:len(bs)-20]
+	idx := &Index{Version: binary.BigEndian.Uint32(body[4:])}
+	if idx.Version < 2 || idx.Version > 4 {
+		return nil, fmt.Errorf("unsupported index version %d", idx.Version)
+	}
+	n := int(binary.BigEndian.Uint32(body[8:]))
+	var (
+		pos  = 12
+		prev string
+	)
+	for i := 0; i < n; i++ {
+		e, next, err := idx.parseEntry(body, pos, prev)
+		if err != nil {
+			return nil, errors.Wrapf(err, "index entry %d", i)
+		}
+		idx.Entries = append(idx.Entries, e)
+		pos, prev = next, e.Path
+	}
+	for pos < len(body) {
+		if len(body)-pos < 8 {
+			return nil, fmt.Errorf("truncated index extension")
+		}
+		sig := string(body[pos : pos+4])
+		size := int(binary.BigEndian.Uint32(body[pos+4:]))
+		pos += 8
+		if len(body)-pos < size {
+			return nil, fmt.Errorf("truncated index extension %s", sig)
+		}
+		if sig[0] < 'A' || sig[0] > 'Z' {
+			return nil, fmt.Errorf("unsupported mandatory index extension %s", sig)
+		}
+		data := body[pos : pos+size]
+		pos += size
+		if sig == "TREE" {
+			t, err := parseCacheTree(data)
+			if err != nil {
+				return nil, err
+			}
+			idx.Tree = t
+			continue
+		}
+		idx.Extensions = append(idx.Extensions, Extension{sig, append([]byte(nil), data...)})
+	}
+	return idx, nil
+}
+
+func (idx *Index) parseEntry(bs []byte, pos int, prev string) (Entry, int, error) {
+	start := pos
+	if len(bs)-pos < 62 {
+		return Entry{}, 0, fmt.Errorf("truncated entry")
+	}
+	u32 := func(off int) uint32 { return binary.BigEndian.Uint32(bs[pos+off:]) }
+	e := Entry{
+		CTime: time.Unix(int64(u32(0)), int64(u32(4))),
+		MTime: time.Unix(int64(u32(8)), int64(u32(12))),
+		Dev:   u32(16),
+		Ino:   u32(20),
+		Mode:  u32(24),
+		UID:   u32(28),
+		GID:   u32(32),
+		Size:  u32(36),
+		SHA:   hex.EncodeToString(bs[pos+40 : pos+60]),
+	}
+	flags := binary.BigEndian.Uint16(bs[pos+60:])
+	pos += 62
+	e.AssumeValid = flags&flagAssumeValid != 0
+	e.Stage = int(flags&flagStageMask) >> flagStageShift
+	if flags&flagExtended != 0 {
+		if idx.Version < 3 {
+			return Entry{}, 0, fmt.Errorf("extended flags in version %d index", idx.Version)
+		}
+		if len(bs)-pos < 2 {
+			return Entry{}, 0, fmt.Errorf("truncated entry")
+		}
+		ext := binary.BigEndian.Uint16(bs[pos:])
+		e.SkipWorktree = ext&flagSkipWorktree != 0
+		e.IntentToAdd = ext&flagIntentToAdd != 0
+		pos += 2
+	}
+	if idx.Version == 4 {
+		strip, n := decodeVarint(bs[pos:])
+		if n == 0 || strip > len(prev) {
+			return Entry{}, 0, fmt.Errorf("invalid path prefix")
+		}
+		pos += n
+		nul := bytes.IndexByte(bs[pos:], 0)
+		if nul < 0 {
+			return Entry{}, 0, fmt.Errorf("unterminated path")
+		}
+		e.Path = prev[:len(prev)-strip] + string(bs[pos:pos+nul])
+		return e, pos + nul + 1, nil
+	}
+	nul := bytes.IndexByte(bs[pos:], 0)
+	if nul < 0 {
+		return Entry{}, 0, fmt.Errorf("unterminated path")
+	}
+	e.Path = string(bs[pos : pos+nul])
+	// entries are padded with 1-8 NUL bytes to a multiple of 8 bytes
+	end := start + (pos+nul-start+8)&^7
+	if end > len(bs) {
+		return Entry{}, 0, fmt.Errorf("truncated entry")
+	}
+	return e, end, nil
+}
+
+// Write writes the index, followed by its checksum. Entries with extended
+// flags require at least version 3, to which the index is upgraded.
+func (idx *Index) Write(w io.Writer) error {
+	version := idx.Version
+	if version < 2 {
+		version = 2
+	}
+	for _, e := range idx.Entries {
+		if version == 2 && (e.SkipWorktree || e.IntentToAdd) {
+			version = 3
+		}
+	}
+	var buf bytes.Buffer
+	buf.Write(signature)
+	binary.Write(&buf, binary.BigEndian, version)
+	binary.Write(&buf, binary.BigEndian, uint32(len(idx.Entries)))
+	var prev string
+	for _, e := range idx.Entries {
+		if err := writeEntry(&buf, e, version, prev); err != nil {
+			return err
+		}
+		prev = e.Path
+	}
+	if idx.Tree != nil {
+		var data bytes.Buffer
+		if err := idx.Tree.encode(&data); err != nil {
+			return err
+		}
+		buf.WriteString("TREE")
+		binary.Write(&buf, binary.BigEndian, uint32(data.Len()))
+		data.WriteTo(&buf)
+	}
+	for _, ext := range idx.Extensions {
+		buf.WriteString(ext.Signature)
+		binary.Write(&buf, binary.BigEndian, uint32(len(ext.Data)))
+		buf.Write(ext.Data)
+	}
+	sum := sha1.Sum(buf.Bytes())
+	buf.Write(sum[:])
+	_, err := buf.WriteTo(w)
+	return errors.Wrap(err, "error writing index")
+}
+
+func writeEntry(buf *bytes.Buffer, e Entry, version uint32, prev string) error {
+	sha, err := hex.DecodeString(e.SHA)
+	if err != nil || len(sha) != 20 {
+		return fmt.Errorf("invalid SHA %s for %s", e.SHA, e.Path)
+	}
+	if e.Stage < 0 || e.Stage > 3 {
+		return fmt.Errorf("invalid stage %d for %s", e.Stage, e.Path)
+	}
+	start := buf.Len()
+	for _, v := range []uint32{
+		uint32(e.CTime.Unix()), uint32(e.CTime.Nanosecond()),
+		uint32(e.MTime.Unix()), uint32(e.MTime.Nanosecond()),
+		e.Dev, e.Ino, e.Mode, e.UID, e.GID, e.Size,
+	} {
+		binary.Write(buf, binary.BigEndian, v)
+	}
+	buf.Write(sha)
+	flags := uint16(e.Stage) << flagStageShift
+	if len(e.Path) < flagNameMask {
+		flags |= uint16(len(e.Path))
+	} else {
+		flags |= flagNameMask
+	}
+	if e.AssumeValid {
+		flags |= flagAssumeValid
+	}
+	var ext uint16
+	if e.SkipWorktree {
+		ext |= flagSkipWorktree
+	}
+	if e.IntentToAdd {
+		ext |= flagIntentToAdd
+	}
+	if ext != 0 {
+		flags |= flagExtended
+	}
+	binary.Write(buf, binary.BigEndian, flags)
+	if ext != 0 {
+		binary.Write(buf, binary.BigEndian, ext)
+	}
+	if version == 4 {
+		common := 0
+		for common < len(prev) && common < len(e.Path) && prev[common] == e.Path[common] {
+			common++
+		}
+		buf.Write(encodeVarint(len(prev) - common))
+		buf.WriteString(e.Path[common:])
+		buf.WriteByte(0)
+		return nil
+	}
+	buf.WriteString(e.Path)
+	n := buf.Len() - start
+	buf.Write(make([]byte, (n+8)&^7-n))
+	return nil
+}
+
+// decodeVarint decodes a variable length integer as used in version 4
+// indexes. It returns the value and the number of bytes read, which is 0
+// if the input is truncated.
+func decodeVarint(bs []byte) (int, int) {
+	if len(bs) == 0 {
+		return 0, 0
+	}
+	c := bs[0]
+	val, n := int(c&0x7f), 1
+	for c&0x80 != 0 {
+		if n == len(bs) {
+			return 0, 0
+		}
+		c = bs[n]
+		n++
+		val = (val+1)<<7 | int(c&0x7f)
+	}
+	return val, n
+}
+
+func encodeVarint(v int) []byte {
+	var buf [16]byte
+	pos := len(buf) - 1
+	buf[pos] = byte(v & 0x7f)
+	for v >>= 7; v > 0; v >>= 7 {
+		v--
+		pos--
+		buf[pos] = 0x80 | byte(v&0x7f)
+	}
+	return buf[pos:]
+}
@@ -0,0 +1,109 @@
+package index
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testIndex(version uint32) *Index {
+	sha := func(c string) string { return strings.Repeat(c, 40) }
+	ts := time.Unix(1600000000, 0)
+	return &Index{
+		Version: version,
+		Entries: []Entry{
+			{
+				CTime: time.Unix(1600000000, 1), MTime: time.Unix(1600000001, 999999999),
+				Dev: 1, Ino: 2, Mode: 0100644, UID: 1000, GID: 100, Size: 3,
+				SHA: sha("a"), Path: "README",
+			},
+			{CTime: ts, MTime: ts, Mode: 0100755, SHA: sha("b"), Path: "bin/run", AssumeValid: true},
+			{CTime: ts, MTime: ts, Mode: 0100644, SHA: sha("c"), Path: "dir/file", Stage: 1},
+			{CTime: ts, MTime: ts, Mode: 0100644, SHA: sha("d"), Path: "dir/file", Stage: 2},
+			{CTime: ts, MTime: ts, Mode: 0100644, SHA: sha("e"), Path: "dir/file", Stage: 3},
+			{CTime: ts, MTime: ts, Mode: 0120000, SHA: sha("f"), Path: "dir/link"},
+			{CTime: ts, MTime: ts, Mode: 0100644, SHA: sha("1"), Path: strings.Repeat("long/", 1000) + "name"},
+		},
+		Tree: &CacheTree{
+			Entries: 7,
+			SHA:     sha("2"),
+			Subtrees: []*CacheTree{
+				{Name: "bin", Entries: 1, SHA: sha("3")},
+				{Name: "dir", Entries: -1},
+			},
+		},
+		Extensions: []Extension{{Signature: "REUC", Data: []byte("resolve undo")}},
+	}
+}
+
+func TestWriteParseRoundTrip(t *testing.T) {
+	for _, version := range []uint32{2, 3, 4} {
+		want := testIndex(version)
+		if version >= 3 {
+			want.Entries[5].SkipWorktree = true
+			want.Entries[6].IntentToAdd = true
+		}
+		var buf bytes.Buffer
+		if err := want.Write(&buf); err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+		got, err := Parse(buf.Bytes())
+		if err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("version %d: got\n%+v\nwant\n%+v", version, got, want)
+		}
+	}
+}
+
+func TestWriteUpgradesForExtendedFlags(t *testing.T) {
+	idx := testIndex(2)
+	idx.Entries[0].SkipWorktree = true
+	var buf bytes.Buffer
+	if err := idx.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Parse(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != 3 || !got.Entries[0].SkipWorktree {
+		t.Errorf("got version %d with skip-worktree %v, want version 3 with skip-worktree", got.Version, got.Entries[0].SkipWorktree)
+	}
+}
+
+func TestParseCorrupt(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testIndex(2).Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	bs := buf.Bytes()
+	flipped := append([]byte(nil), bs...)
+	flipped[20] ^= 1
+	tests := map[string][]byte{
+		"empty":          nil,
+		"bad signature":  append([]byte("XXXX"), bs[4:]...),
+		"bad checksum":   flipped,
+		"truncated":      bs[:len(bs)-1],
+		"version 1":      withChecksum(append(append([]byte("DIRC"), 0, 0, 0, 1), bs[8:len(bs)-20]...)),
+		"too many":       withChecksum(append(append([]byte(nil), bs[:8]...), append([]byte{0, 0, 1, 0}, bs[12:len(bs)-20]...)...)),
+		"mandatory ext":  withChecksum(append(append([]byte(nil), bs[:len(bs)-20]...), "link\x00\x00\x00\x00"...)),
+		"truncated ext":  withChecksum(append(append([]byte(nil), bs[:len(bs)-20]...), "UNTR\x00\x00\x00\x09"...)),
+		"partial header": withChecksum(append(append([]byte(nil), bs[:len(bs)-20]...), "UNT"...)),
+	}
+	for name, bs := range tests {
+		if _, err := Parse(bs); err == nil {
+			t.Errorf("%s: Parse succeeded", name)
+		}
+	}
+}
+
+// withChecksum appends the checksum of an index body.
+func withChecksum(body []byte) []byte {
+	sum := sha1.Sum(body)
+	return append(body, sum[:]...)
+}
@@ -0,0 +1,59 @@
+package index
+
+import (
+	"os"
+	"time"
+)
+
+// Modes of index entries.
+const (
+	ModeFile       = 0100644
+	ModeExecutable = 0100755
+	ModeSymlink    = 0120000
+	ModeSubmodule  = 0160000
+)
+
+// ModeOf returns the index mode of a file with the given info.
+func ModeOf(info os.FileInfo) uint32 {
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		return ModeSymlink
+	case info.IsDir():
+		return ModeSubmodule
+	case info.Mode()&0111 != 0:
+		return ModeExecutable
+	default:
+		return ModeFile
+	}
+}
+
+// NewEntry creates an entry for the file at path with the given info and
+// content SHA.
+func NewEntry(path string, sha string, info os.FileInfo) Entry {
+	e := Entry{
+		Path:  path,
+		SHA:   sha,
+		Mode:  ModeOf(info),
+		Size:  uint32(info.Size()),
+		MTime: info.ModTime(),
+		CTime: info.ModTime(),
+	}
+	fillStat(&e, info)
+	return e
+}
+
+// Stale returns whether the file with the given info may have changed
+// since the entry was recorded, judging by its stat data.
+func (e Entry) Stale(info os.FileInfo) bool {
+	if ModeOf(info) != e.Mode || uint32(info.Size()) != e.Size || !sameTime(info.ModTime(), e.MTime) {
+		return true
+	}
+	var s Entry
+	fillStat(&s, info)
+	return s.Ino != 0 && (s.Ino != e.Ino || !sameTime(s.CTime, e.CTime))
+}
+
+// sameTime compares times at the precision stored in the index.
+func sameTime(a, b time.Time) bool {
+	return uint32(a.Unix()) == uint32(b.Unix()) && a.Nanosecond() == b.Nanosecond()
+}
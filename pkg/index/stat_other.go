@@ -0,0 +1,9 @@
+//go:build !linux
+
+package index
+
+import "os"
+
+// fillStat fills the platform-specific stat fields of the entry. They
+// are only recorded on Linux.
+func fillStat(e *Entry, info os.FileInfo) {}
@@ -0,0 +1,112 @@
+package index
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CacheTree caches the SHAs of the trees written from the index, as
+// stored in the TREE extension. Trees containing modified entries are
+// invalidated, so that unchanged directories need not be rewritten.
+type CacheTree struct {
+	// Name is the name of the directory, which is empty for the root.
+	Name string
+	// Entries is the number of index entries below the directory, or -1
+	// if the tree is invalid.
+	Entries  int
+	SHA      string
+	Subtrees []*CacheTree
+}
+
+// Valid returns whether the cached tree SHA is up to date.
+func (t *CacheTree) Valid() bool {
+	return t.Entries >= 0 && t.SHA != ""
+}
+
+// Subtree returns the cached tree of the given subdirectory, or nil.
+func (t *CacheTree) Subtree(name string) *CacheTree {
+	for _, s := range t.Subtrees {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// invalidate invalidates the trees of all directories containing path.
+func (t *CacheTree) invalidate(path string) {
+	t.Entries = -1
+	i := strings.IndexByte(path, '/')
+	if i < 0 {
+		return
+	}
+	if s := t.Subtree(path[:i]); s != nil {
+		s.invalidate(path[i+1:])
+	}
+}
+
+// parseCacheTree parses the data of a TREE extension.
+func parseCacheTree(bs []byte) (*CacheTree, error) {
+	t, rest, err := parseCacheTreeNode(bs)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("invalid cache tree: trailing data")
+	}
+	return t, nil
+}
+
+func parseCacheTreeNode(bs []byte) (*CacheTree, []byte, error) {
+	nul := bytes.IndexByte(bs, 0)
+	nl := bytes.IndexByte(bs, '\n')
+	if nul < 0 || nl < nul {
+		return nil, nil, fmt.Errorf("invalid cache tree: truncated entry")
+	}
+	fields := strings.Fields(string(bs[nul+1 : nl]))
+	if len(fields) != 2 {
+		return nil, nil, fmt.Errorf("invalid cache tree: malformed entry")
+	}
+	entries, err1 := strconv.Atoi(fields[0])
+	subtrees, err2 := strconv.Atoi(fields[1])
+	if err1 != nil || err2 != nil || subtrees < 0 {
+		return nil, nil, fmt.Errorf("invalid cache tree: malformed entry")
+	}
+	t := &CacheTree{Name: string(bs[:nul]), Entries: entries}
+	bs = bs[nl+1:]
+	if entries >= 0 {
+		if len(bs) < 20 {
+			return nil, nil, fmt.Errorf("invalid cache tree: truncated entry")
+		}
+		t.SHA, bs = hex.EncodeToString(bs[:20]), bs[20:]
+	}
+	for i := 0; i < subtrees; i++ {
+		s, rest, err := parseCacheTreeNode(bs)
+		if err != nil {
+			return nil, nil, err
+		}
+		t.Subtrees, bs = append(t.Subtrees, s), rest
+	}
+	return t, bs, nil
+}
+
+// encode writes the tree in the format of the TREE extension.
+func (t *CacheTree) encode(buf *bytes.Buffer) error {
+	fmt.Fprintf(buf, "%s\x00%d %d\n", t.Name, t.Entries, len(t.Subtrees))
+	if t.Entries >= 0 {
+		sha, err := hex.DecodeString(t.SHA)
+		if err != nil || len(sha) != 20 {
+			return fmt.Errorf("invalid cached tree SHA %s", t.SHA)
+		}
+		buf.Write(sha)
+	}
+	for _, s := range t.Subtrees {
+		if err := s.encode(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
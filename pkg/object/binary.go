@@ -0,0 +1,15 @@
+package object
+
+import "bytes"
+
+// binaryProbeLen is the number of bytes inspected by IsBinary.
+const binaryProbeLen = 8000
+
+// IsBinary returns whether data looks like binary content. Like git, it
+// considers content binary if its first 8000 bytes contain a NUL byte.
+func IsBinary(data []byte) bool {
+	if len(data) > binaryProbeLen {
+		data = data[:binaryProbeLen]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
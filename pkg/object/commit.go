@@ -0,0 +1,141 @@
+package object
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Commit represents a commit. The headers are kept as stored, so that a
+// parsed commit serializes to identical bytes.
+type Commit struct {
+	headers []header
+	message string
+}
+
+// NewCommit creates a new commit.
+func NewCommit(tree string, parents []string, author, committer Signature, message string) *Commit {
+	hs := []header{{"tree", tree}}
+	for _, p := range parents {
+		hs = append(hs, header{"parent", p})
+	}
+	hs = append(hs, header{"author", author.String()}, header{"committer", committer.String()})
+	return &Commit{headers: hs, message: message}
+}
+
+// Tree returns the SHA of the commit's tree.
+func (c *Commit) Tree() string {
+	return c.Header("tree")
+}
+
+// Parents returns the SHAs of the commit's parents.
+func (c *Commit) Parents() []string {
+	var res []string
+	for _, h := range c.headers {
+		if h.key == "parent" {
+			res = append(res, h.value)
+		}
+	}
+	return res
+}
+
+// Author returns the commit's author.
+func (c *Commit) Author() Signature {
+	s, _ := ParseSignature(c.Header("author"))
+	return s
+}
+
+// Committer returns the commit's committer.
+func (c *Commit) Committer() Signature {
+	s, _ := ParseSignature(c.Header("committer"))
+	return s
+}
+
+// GPGSig returns the commit's armored signature, if it is signed.
+func (c *Commit) GPGSig() string {
+	return c.Header("gpgsig")
+}
+
+// Sign sets the commit's armored signature. The signature is added as the
+// last header, replacing an existing signature.
+func (c *Commit) Sign(sig string) {
+	c.headers = append(c.unsignedHeaders(), header{"gpgsig", strings.TrimSuffix(sig, "\n")})
+}
+
+// Payload returns the serialized commit without its signature, which is
+// the data the signature was made over.
+func (c *Commit) Payload() []byte {
+	return joinObject(c.unsignedHeaders(), c.message)
+}
+
+func (c *Commit) unsignedHeaders() []header {
+	var hs []header
+	for _, h := range c.headers {
+		if h.key != "gpgsig" {
+			hs = append(hs, h)
+		}
+	}
+	return hs
+}
+
+// Message returns the commit message.
+func (c *Commit) Message() string {
+	return c.message
+}
+
+// Header returns the value of the first header with the given key.
+// Continuation lines of multi-line headers are joined with newlines.
+func (c *Commit) Header(key string) string {
+	return headerValue(c.headers, key)
+}
+
+// Deserialize implements Object.
+func (c *Commit) Deserialize(bs []byte) error {
+	hs, msg, err := splitObject(bs)
+	if err != nil {
+		return fmt.Errorf("invalid commit: %v", err)
+	}
+	c.headers, c.message = hs, msg
+	return nil
+}
+
+// Serialize implements Object.
+func (c *Commit) Serialize() []byte {
+	return joinObject(c.headers, c.message)
+}
+
+// splitObject splits a commit or tag into its headers and message.
+func splitObject(bs []byte) ([]header, string, error) {
+	hs, err := splitHeaders(bs)
+	if err != nil {
+		return nil, "", err
+	}
+	var msg string
+	if i := bytes.Index(bs, []byte("\n\n")); i >= 0 {
+		msg = string(bs[i+2:])
+	}
+	return hs, msg, nil
+}
+
+// joinObject serializes the headers and message of a commit or tag.
+func joinObject(hs []header, msg string) []byte {
+	var buf bytes.Buffer
+	for _, h := range hs {
+		buf.WriteString(h.key)
+		buf.WriteByte(' ')
+		buf.WriteString(strings.ReplaceAll(h.value, "\n", "\n "))
+		buf.WriteByte('\n')
+	}
+	buf.WriteByte('\n')
+	buf.WriteString(msg)
+	return buf.Bytes()
+}
+
+func headerValue(hs []header, key string) string {
+	for _, h := range hs {
+		if h.key == key {
+			return h.value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,24 @@
+package object
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+)
+
+// HashStream computes the SHA of an object of the given type and size
+// whose content is read from r, without holding the content in memory.
+// It fails if r does not provide exactly size bytes.
+func HashStream(typ string, size int64, r io.Reader) (string, error) {
+	hasher := sha1.New()
+	fmt.Fprintf(hasher, "%s %d\x00", typ, size)
+	n, err := io.Copy(hasher, io.LimitReader(r, size+1))
+	if err != nil {
+		return "", err
+	}
+	if n != size {
+		return "", fmt.Errorf("expected %d bytes of content, got %d", size, n)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
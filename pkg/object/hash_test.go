@@ -0,0 +1,71 @@
+package object
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// bufferedHash hashes an object held in memory.
+func bufferedHash(typ string, data []byte) string {
+	sum := sha1.Sum(append([]byte(fmt.Sprintf("%s %d\x00", typ, len(data))), data...))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestHashStream(t *testing.T) {
+	for _, data := range []string{"", "hello\n", strings.Repeat("large content\n", 100000)} {
+		got, err := HashStream("blob", int64(len(data)), strings.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := bufferedHash("blob", []byte(data)); got != want {
+			t.Errorf("got %s for %d bytes, want %s", got, len(data), want)
+		}
+	}
+	if got, _ := HashStream("blob", 6, strings.NewReader("hello\n")); got != "ce013625030ba8dba906f756967f9e9ca394464a" {
+		t.Errorf("got %s for hello", got)
+	}
+	for _, size := range []int64{5, 7} {
+		if _, err := HashStream("blob", size, strings.NewReader("hello\n")); err == nil {
+			t.Errorf("HashStream accepted 6 bytes of content for size %d", size)
+		}
+	}
+}
+
+func BenchmarkHashFile(b *testing.B) {
+	p := filepath.Join(b.TempDir(), "large")
+	data := bytes.Repeat([]byte("0123456789abcdef"), 4<<20)
+	if err := os.WriteFile(p, data, 0644); err != nil {
+		b.Fatal(err)
+	}
+	b.Run("buffered", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			bs, err := os.ReadFile(p)
+			if err != nil {
+				b.Fatal(err)
+			}
+			bufferedHash("blob", bs)
+		}
+	})
+	b.Run("stream", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(data)))
+		for i := 0; i < b.N; i++ {
+			f, err := os.Open(p)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := HashStream("blob", int64(len(data)), f); err != nil {
+				b.Fatal(err)
+			}
+			f.Close()
+		}
+	})
+}
@@ -1,6 +1,35 @@
 // Package object implements Git objects.
 package object
 
+import "fmt"
+
+// Object represents an object.
+type Object interface {
+	Serialize() []byte
+	Deserialize([]byte) error
+}
+
+// Parse constructs the object of the given type from its serialized data.
+func Parse(typ string, data []byte) (Object, error) {
+	var o Object
+	switch typ {
+	case "blob":
+		o = new(Blob)
+	case "tree":
+		o = new(Tree)
+	case "commit":
+		o = new(Commit)
+	case "tag":
+		o = new(Tag)
+	default:
+		return nil, fmt.Errorf("unsupported object type %s", typ)
+	}
+	if err := o.Deserialize(data); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
 // Blob represents a blob.
 type Blob struct {
 	data []byte
@@ -0,0 +1,39 @@
+package object
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sboehler/got/pkg/timefmt"
+)
+
+// Signature identifies the author, committer or tagger of an object,
+// together with a timestamp.
+type Signature struct {
+	Name  string
+	Email string
+	When  time.Time
+}
+
+// ParseSignature parses a signature of the form "Name <email> 1650000000
+// +0200".
+func ParseSignature(s string) (Signature, error) {
+	lt, gt := strings.IndexByte(s, '<'), strings.LastIndexByte(s, '>')
+	if lt < 0 || gt < lt {
+		return Signature{}, fmt.Errorf("invalid signature %q", s)
+	}
+	when, err := timefmt.ParseRaw(s[gt+1:])
+	if err != nil {
+		return Signature{}, fmt.Errorf("invalid signature %q: %v", s, err)
+	}
+	return Signature{
+		Name:  strings.TrimSpace(s[:lt]),
+		Email: s[lt+1 : gt],
+		When:  when,
+	}, nil
+}
+
+func (s Signature) String() string {
+	return fmt.Sprintf("%s <%s> %s", s.Name, s.Email, timefmt.Format(s.When))
+}
@@ -0,0 +1,66 @@
+package object
+
+import "fmt"
+
+// Tag represents an annotated tag. Like commits, tags keep their headers
+// as stored.
+type Tag struct {
+	headers []header
+	message string
+}
+
+// NewTag creates a new annotated tag with the given name, pointing to the
+// object with the given SHA and type.
+func NewTag(object, typ, name string, tagger Signature, message string) *Tag {
+	return &Tag{
+		headers: []header{
+			{"object", object},
+			{"type", typ},
+			{"tag", name},
+			{"tagger", tagger.String()},
+		},
+		message: message,
+	}
+}
+
+// Object returns the SHA of the tagged object.
+func (t *Tag) Object() string {
+	return headerValue(t.headers, "object")
+}
+
+// TargetType returns the type of the tagged object.
+func (t *Tag) TargetType() string {
+	return headerValue(t.headers, "type")
+}
+
+// Name returns the name of the tag.
+func (t *Tag) Name() string {
+	return headerValue(t.headers, "tag")
+}
+
+// Tagger returns the creator of the tag.
+func (t *Tag) Tagger() Signature {
+	s, _ := ParseSignature(headerValue(t.headers, "tagger"))
+	return s
+}
+
+// Message returns the tag message, including a trailing signature if
+// the tag is signed.
+func (t *Tag) Message() string {
+	return t.message
+}
+
+// Deserialize implements Object.
+func (t *Tag) Deserialize(bs []byte) error {
+	hs, msg, err := splitObject(bs)
+	if err != nil {
+		return fmt.Errorf("invalid tag: %v", err)
+	}
+	t.headers, t.message = hs, msg
+	return nil
+}
+
+// Serialize implements Object.
+func (t *Tag) Serialize() []byte {
+	return joinObject(t.headers, t.message)
+}
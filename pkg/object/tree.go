@@ -0,0 +1,136 @@
+package object
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Modes of tree entries.
+const (
+	ModeFile       = "100644"
+	ModeExecutable = "100755"
+	ModeSymlink    = "120000"
+	ModeTree       = "40000"
+	ModeSubmodule  = "160000"
+)
+
+// TreeEntry is an entry of a tree.
+type TreeEntry struct {
+	Mode string
+	Name string
+	SHA  string
+}
+
+// Type returns the type of the object the entry points to.
+func (e TreeEntry) Type() string {
+	switch e.Mode {
+	case ModeTree:
+		return "tree"
+	case ModeSubmodule:
+		return "commit"
+	default:
+		return "blob"
+	}
+}
+
+// sortKey returns the key by which git orders tree entries: subtrees
+// sort as if their name had a trailing slash.
+func (e TreeEntry) sortKey() string {
+	if e.Mode == ModeTree {
+		return e.Name + "/"
+	}
+	return e.Name
+}
+
+// CheckEntryName returns an error if name is not safe as the name of a
+// tree entry, as it would escape or alter the worktree when checked out:
+// it must be a single, non-empty path component other than ".", ".." and
+// ".git".
+func CheckEntryName(name string) error {
+	switch {
+	case name == "" || strings.ContainsAny(name, "/\x00"):
+		return fmt.Errorf("unsafe tree entry name %q: not a single path component", name)
+	case name == "." || name == "..":
+		return fmt.Errorf("unsafe tree entry name %q: refers to a directory outside the entry", name)
+	case strings.EqualFold(name, ".git"):
+		return fmt.Errorf("unsafe tree entry name %q: reserved for the repository", name)
+	}
+	return nil
+}
+
+// Tree represents a tree.
+type Tree struct {
+	entries []TreeEntry
+}
+
+// NewTree creates a new tree with the given entries, which are sorted in
+// git's order.
+func NewTree(entries []TreeEntry) *Tree {
+	entries = append([]TreeEntry(nil), entries...)
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].sortKey() < entries[j].sortKey()
+	})
+	return &Tree{entries}
+}
+
+// Entries returns the entries of the tree, in git's order.
+func (t *Tree) Entries() []TreeEntry {
+	return t.entries
+}
+
+// Entry returns the entry with the given name.
+func (t *Tree) Entry(name string) (TreeEntry, bool) {
+	for _, e := range t.entries {
+		if e.Name == name {
+			return e, true
+		}
+	}
+	return TreeEntry{}, false
+}
+
+// Deserialize implements Object.
+func (t *Tree) Deserialize(bs []byte) error {
+	var entries []TreeEntry
+	for len(bs) > 0 {
+		sp := bytes.IndexByte(bs, ' ')
+		if sp < 0 {
+			return fmt.Errorf("tree entry %d: missing mode", len(entries))
+		}
+		mode := string(bs[:sp])
+		bs = bs[sp+1:]
+		nul := bytes.IndexByte(bs, 0)
+		if nul < 0 {
+			return fmt.Errorf("tree entry %d: missing name", len(entries))
+		}
+		name := string(bs[:nul])
+		bs = bs[nul+1:]
+		if len(bs) < 20 {
+			return fmt.Errorf("tree entry %d: truncated SHA", len(entries))
+		}
+		entries = append(entries, TreeEntry{
+			Mode: mode,
+			Name: name,
+			SHA:  hex.EncodeToString(bs[:20]),
+		})
+		bs = bs[20:]
+	}
+	t.entries = entries
+	return nil
+}
+
+// Serialize implements Object.
+func (t *Tree) Serialize() []byte {
+	var buf bytes.Buffer
+	for _, e := range t.entries {
+		buf.WriteString(e.Mode)
+		buf.WriteByte(' ')
+		buf.WriteString(e.Name)
+		buf.WriteByte(0)
+		sha, _ := hex.DecodeString(e.SHA)
+		buf.Write(sha)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,184 @@
+package object
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Validate checks that data is a well-formed object of the given type.
+func Validate(typ string, data []byte) error {
+	switch typ {
+	case "blob":
+		return nil
+	case "tree":
+		return validateTree(data)
+	case "commit":
+		return validateCommit(data)
+	case "tag":
+		return validateTag(data)
+	default:
+		return fmt.Errorf("invalid object type %s", typ)
+	}
+}
+
+var validModes = map[string]bool{
+	ModeFile:       true,
+	ModeExecutable: true,
+	ModeSymlink:    true,
+	ModeTree:       true,
+	ModeSubmodule:  true,
+}
+
+func validateTree(data []byte) error {
+	var prev string
+	for i := 0; len(data) > 0; i++ {
+		sp := bytes.IndexByte(data, ' ')
+		if sp < 0 {
+			return fmt.Errorf("tree entry %d: missing mode", i)
+		}
+		mode := string(data[:sp])
+		if !validModes[mode] {
+			return fmt.Errorf("tree entry %d: bad mode %q", i, mode)
+		}
+		data = data[sp+1:]
+		nul := bytes.IndexByte(data, 0)
+		if nul < 0 {
+			return fmt.Errorf("tree entry %d: missing name", i)
+		}
+		name := string(data[:nul])
+		if err := CheckEntryName(name); err != nil {
+			return fmt.Errorf("tree entry %d: %v", i, err)
+		}
+		data = data[nul+1:]
+		if len(data) < 20 {
+			return fmt.Errorf("tree entry %d: truncated SHA", i)
+		}
+		data = data[20:]
+		key := name
+		if mode == ModeTree {
+			key += "/"
+		}
+		if i > 0 && key <= prev {
+			return fmt.Errorf("tree entry %d: %q is not sorted after %q", i, name, strings.TrimSuffix(prev, "/"))
+		}
+		prev = key
+	}
+	return nil
+}
+
+var (
+	shaRegexp   = regexp.MustCompile(`^[0-9a-f]{40}$`)
+	identRegexp = regexp.MustCompile(`^[^<>\n]*<[^<>\n]*> [0-9]+ [+-][0-9]{4}$`)
+)
+
+// header is a header line of a commit or tag.
+type header struct {
+	key, value string
+}
+
+// splitHeaders returns the header lines of a commit or tag. Continuation
+// lines are appended to the preceding header.
+func splitHeaders(data []byte) ([]header, error) {
+	end := bytes.Index(data, []byte("\n\n"))
+	if end < 0 {
+		if !bytes.HasSuffix(data, []byte("\n")) {
+			return nil, fmt.Errorf("missing end of headers")
+		}
+		end = len(data) - 1
+	}
+	var res []header
+	for _, line := range strings.Split(string(data[:end]), "\n") {
+		if strings.HasPrefix(line, " ") {
+			if len(res) == 0 {
+				return nil, fmt.Errorf("continuation line without header")
+			}
+			res[len(res)-1].value += "\n" + line[1:]
+			continue
+		}
+		sp := strings.IndexByte(line, ' ')
+		if sp < 0 {
+			return nil, fmt.Errorf("bad header line %q", line)
+		}
+		res = append(res, header{line[:sp], line[sp+1:]})
+	}
+	return res, nil
+}
+
+// expectHeader checks that the next header has the given key and that
+// its value matches the given check.
+func expectHeader(hs []header, key string, check func(string) error) ([]header, error) {
+	if len(hs) == 0 || hs[0].key != key {
+		return hs, fmt.Errorf("missing %s header", key)
+	}
+	if err := check(hs[0].value); err != nil {
+		return hs, fmt.Errorf("bad %s header: %v", key, err)
+	}
+	return hs[1:], nil
+}
+
+func checkSHA(s string) error {
+	if !shaRegexp.MatchString(s) {
+		return fmt.Errorf("invalid SHA %q", s)
+	}
+	return nil
+}
+
+func checkIdent(s string) error {
+	if !identRegexp.MatchString(s) {
+		return fmt.Errorf("invalid identity %q", s)
+	}
+	return nil
+}
+
+func validateCommit(data []byte) error {
+	hs, err := splitHeaders(data)
+	if err != nil {
+		return err
+	}
+	if hs, err = expectHeader(hs, "tree", checkSHA); err != nil {
+		return err
+	}
+	for len(hs) > 0 && hs[0].key == "parent" {
+		if hs, err = expectHeader(hs, "parent", checkSHA); err != nil {
+			return err
+		}
+	}
+	if hs, err = expectHeader(hs, "author", checkIdent); err != nil {
+		return err
+	}
+	_, err = expectHeader(hs, "committer", checkIdent)
+	return err
+}
+
+func validateTag(data []byte) error {
+	hs, err := splitHeaders(data)
+	if err != nil {
+		return err
+	}
+	if hs, err = expectHeader(hs, "object", checkSHA); err != nil {
+		return err
+	}
+	checkType := func(s string) error {
+		switch s {
+		case "blob", "tree", "commit", "tag":
+			return nil
+		}
+		return fmt.Errorf("invalid type %q", s)
+	}
+	if hs, err = expectHeader(hs, "type", checkType); err != nil {
+		return err
+	}
+	checkName := func(s string) error {
+		if s == "" {
+			return fmt.Errorf("empty tag name")
+		}
+		return nil
+	}
+	if hs, err = expectHeader(hs, "tag", checkName); err != nil {
+		return err
+	}
+	_, err = expectHeader(hs, "tagger", checkIdent)
+	return err
+}
@@ -0,0 +1,45 @@
+package object
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	var (
+		sha    = strings.Repeat("1", 40)
+		rawSHA = strings.Repeat("\x11", 20)
+		ident  = "A U Thor <author@example.com> 1600000000 +0200"
+	)
+	tests := []struct {
+		name, typ, data string
+		err             string
+	}{
+		{"blob", "blob", "anything\x00", ""},
+		{"tree", "tree", "100644 a\x00" + rawSHA + "40000 b\x00" + rawSHA, ""},
+		{"tree with bad mode", "tree", "100664 a\x00" + rawSHA, `bad mode "100664"`},
+		{"tree with truncated SHA", "tree", "100644 a\x00" + rawSHA[:10], "truncated SHA"},
+		{"unsorted tree", "tree", "100644 b\x00" + rawSHA + "100644 a\x00" + rawSHA, "is not sorted"},
+		{"commit", "commit", "tree " + sha + "\nparent " + sha + "\nauthor " + ident + "\ncommitter " + ident + "\n\nmessage\n", ""},
+		{"commit without tree", "commit", "author " + ident + "\ncommitter " + ident + "\n\nmessage\n", "missing tree header"},
+		{"commit with bad author", "commit", "tree " + sha + "\nauthor A U Thor\ncommitter " + ident + "\n\nmessage\n", "bad author header"},
+		{"tag", "tag", "object " + sha + "\ntype commit\ntag v1\ntagger " + ident + "\n\nmessage\n", ""},
+		{"tag without tagger", "tag", "object " + sha + "\ntype commit\ntag v1\n\nmessage\n", "missing tagger header"},
+		{"tag with bad type", "tag", "object " + sha + "\ntype note\ntag v1\ntagger " + ident + "\n\nmessage\n", "bad type header"},
+		{"unknown type", "note", "", "invalid object type"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Validate(test.typ, []byte(test.data))
+			if test.err == "" {
+				if err != nil {
+					t.Errorf("got error %v, want none", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("got error %v, want %q", err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package pack
+
+import "fmt"
+
+// ApplyDelta reconstructs an object from its base and a delta in git's
+// delta format: the sizes of the base and the result, followed by
+// instructions which either copy a range of the base or insert literal
+// data.
+func ApplyDelta(base, delta []byte) ([]byte, error) {
+	baseSize, delta, err := deltaSize(delta)
+	if err != nil {
+		return nil, err
+	}
+	if baseSize != int64(len(base)) {
+		return nil, fmt.Errorf("delta base has size %d, want %d", len(base), baseSize)
+	}
+	size, delta, err := deltaSize(delta)
+	if err != nil {
+		return nil, err
+	}
+	// Each instruction of at most 8 bytes produces at most 64 KiB.
+	if size > int64(len(delta))*0x10000 {
+		return nil, fmt.Errorf("delta result size %d is too large", size)
+	}
+	res := make([]byte, 0, size)
+	for len(delta) > 0 {
+		op := delta[0]
+		delta = delta[1:]
+		switch {
+		case op&0x80 != 0:
+			var offset, n int64
+			for i := uint(0); i < 7; i++ {
+				if op&(1<<i) == 0 {
+					continue
+				}
+				if len(delta) == 0 {
+					return nil, fmt.Errorf("truncated delta copy instruction")
+				}
+				if i < 4 {
+					offset |= int64(delta[0]) << (8 * i)
+				} else {
+					n |= int64(delta[0]) << (8 * (i - 4))
+				}
+				delta = delta[1:]
+			}
+			if n == 0 {
+				n = 0x10000
+			}
+			if offset+n > int64(len(base)) {
+				return nil, fmt.Errorf("delta copies beyond the end of its base")
+			}
+			res = append(res, base[offset:offset+n]...)
+		case op != 0:
+			if int(op) > len(delta) {
+				return nil, fmt.Errorf("truncated delta insert instruction")
+			}
+			res = append(res, delta[:op]...)
+			delta = delta[op:]
+		default:
+			return nil, fmt.Errorf("invalid delta instruction 0")
+		}
+		if int64(len(res)) > size {
+			return nil, fmt.Errorf("delta result exceeds size %d", size)
+		}
+	}
+	if int64(len(res)) != size {
+		return nil, fmt.Errorf("delta result has size %d, want %d", len(res), size)
+	}
+	return res, nil
+}
+
+// deltaSize decodes a size in a delta header and returns the remaining
+// delta.
+func deltaSize(delta []byte) (int64, []byte, error) {
+	var size int64
+	for i := uint(0); i < 64; i += 7 {
+		if len(delta) == 0 {
+			return 0, nil, fmt.Errorf("truncated delta header")
+		}
+		b := delta[0]
+		delta = delta[1:]
+		size |= int64(b&0x7f) << i
+		if b&0x80 == 0 {
+			return size, delta, nil
+		}
+	}
+	return 0, nil, fmt.Errorf("invalid delta header")
+}
+
+// blockSize is the length of the blocks of a delta base which are
+// indexed to find matches.
+const blockSize = 16
+
+// maxCopy is the largest range copied by a single delta instruction.
+const maxCopy = 0x10000
+
+// deltaIndex maps the blocks of a delta base to their first offset.
+type deltaIndex struct {
+	base   []byte
+	blocks map[[blockSize]byte]int
+}
+
+func newDeltaIndex(base []byte) *deltaIndex {
+	di := &deltaIndex{base: base, blocks: make(map[[blockSize]byte]int)}
+	for i := 0; i+blockSize <= len(base); i += blockSize {
+		var k [blockSize]byte
+		copy(k[:], base[i:])
+		if _, ok := di.blocks[k]; !ok {
+			di.blocks[k] = i
+		}
+	}
+	return di
+}
+
+// CreateDelta computes a delta which reconstructs target from base.
+func CreateDelta(base, target []byte) []byte {
+	return newDeltaIndex(base).delta(target, -1)
+}
+
+// delta computes a delta from the indexed base to target. It returns nil
+// if the delta grows beyond limit bytes, unless limit is negative.
+func (di *deltaIndex) delta(target []byte, limit int) []byte {
+	res := appendDeltaSize(nil, len(di.base))
+	res = appendDeltaSize(res, len(target))
+	lit := 0
+	flush := func(end int) {
+		for lit < end {
+			n := end - lit
+			if n > 0x7f {
+				n = 0x7f
+			}
+			res = append(res, byte(n))
+			res = append(res, target[lit:lit+n]...)
+			lit += n
+		}
+	}
+	for i := 0; i+blockSize <= len(target); {
+		var k [blockSize]byte
+		copy(k[:], target[i:])
+		j, ok := di.blocks[k]
+		if !ok {
+			i++
+			continue
+		}
+		n := blockSize
+		for j+n < len(di.base) && i+n < len(target) && di.base[j+n] == target[i+n] {
+			n++
+		}
+		for i > lit && j > 0 && di.base[j-1] == target[i-1] {
+			i, j, n = i-1, j-1, n+1
+		}
+		flush(i)
+		for n > 0 {
+			m := n
+			if m > maxCopy {
+				m = maxCopy
+			}
+			res = appendCopy(res, j, m)
+			i, j, n = i+m, j+m, n-m
+		}
+		lit = i
+		if limit >= 0 && len(res) > limit {
+			return nil
+		}
+	}
+	flush(len(target))
+	if limit >= 0 && len(res) > limit {
+		return nil
+	}
+	return res
+}
+
+// appendDeltaSize appends a size in the format of delta headers.
+func appendDeltaSize(bs []byte, size int) []byte {
+	for size >= 0x80 {
+		bs = append(bs, byte(size)|0x80)
+		size >>= 7
+	}
+	return append(bs, byte(size))
+}
+
+// appendCopy appends an instruction which copies n bytes at the given
+// offset of the base.
+func appendCopy(bs []byte, offset, n int) []byte {
+	op := byte(0x80)
+	var args []byte
+	for i := uint(0); i < 4; i++ {
+		if b := byte(offset >> (8 * i)); b != 0 {
+			op |= 1 << i
+			args = append(args, b)
+		}
+	}
+	for i := uint(0); i < 3; i++ {
+		if b := byte(n >> (8 * i)); b != 0 {
+			op |= 1 << (4 + i)
+			args = append(args, b)
+		}
+	}
+	return append(append(bs, op), args...)
+}
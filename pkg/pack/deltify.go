@@ -0,0 +1,86 @@
+package pack
+
+import (
+	"sort"
+	"unicode"
+)
+
+// Object is an object to be written to a pack.
+type Object struct {
+	SHA  string
+	Type string
+	// Path is the path at which the object was found, if any. Objects
+	// with similar paths are compared first when looking for deltas.
+	Path string
+	Data []byte
+
+	base  *Object
+	delta []byte
+	depth int
+}
+
+// minDeltaSize is the size below which objects are not deltified.
+const minDeltaSize = 50
+
+// FindDeltas looks for delta bases for the given objects. The objects are
+// ordered so that similar objects are close to each other, and each
+// object is compared with the window objects of the same type preceding
+// it. An object is stored as a delta against the base giving the smallest
+// delta, if the delta is less than half the size of the object. Delta
+// chains are at most depth deltas long.
+func FindDeltas(objs []*Object, window, depth int) {
+	sorted := append([]*Object(nil), objs...)
+	hashes := make(map[*Object]uint32, len(objs))
+	for _, o := range objs {
+		hashes[o] = nameHash(o.Path)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if hashes[a] != hashes[b] {
+			return hashes[a] < hashes[b]
+		}
+		return len(a.Data) > len(b.Data)
+	})
+	indexes := make(map[*Object]*deltaIndex)
+	for i, o := range sorted {
+		if i > window {
+			delete(indexes, sorted[i-window-1])
+		}
+		if len(o.Data) < minDeltaSize {
+			continue
+		}
+		limit := len(o.Data)/2 - 20
+		for j := i - 1; j >= 0 && j >= i-window; j-- {
+			base := sorted[j]
+			if base.Type != o.Type || base.depth >= depth || len(base.Data) < len(o.Data)/32 {
+				continue
+			}
+			di, ok := indexes[base]
+			if !ok {
+				di = newDeltaIndex(base.Data)
+				indexes[base] = di
+			}
+			if delta := di.delta(o.Data, limit); delta != nil {
+				o.base, o.delta, o.depth = base, delta, base.depth+1
+				limit = len(delta) - 1
+			}
+		}
+	}
+}
+
+// nameHash hashes a path so that paths with the same ending, such as
+// files with the same name in different directories, sort close to each
+// other.
+func nameHash(path string) uint32 {
+	var h uint32
+	for _, c := range []byte(path) {
+		if unicode.IsSpace(rune(c)) {
+			continue
+		}
+		h = (h >> 2) + uint32(c)<<24
+	}
+	return h
+}
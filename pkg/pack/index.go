@@ -0,0 +1,167 @@
+// Package pack implements Git packfiles.
+package pack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var idxMagic = []byte{0xff, 't', 'O', 'c'}
+
+// Index is a parsed pack index (.idx) file. It maps object SHAs to their
+// offsets in the corresponding packfile.
+type Index struct {
+	Path    string
+	Version int
+
+	fanout  [256]uint32
+	shas    []byte
+	offsets []int64
+	// crcs are the CRC32 checksums of the packed objects, which only
+	// version 2 indexes have.
+	crcs []byte
+	// packSum and sum are the checksums of the pack and of the index.
+	packSum, sum []byte
+}
+
+// LoadIndex loads the pack index at the given path.
+func LoadIndex(path string) (*Index, error) {
+	bs, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading pack index %s", path)
+	}
+	idx, err := ParseIndex(bs)
+	if err != nil {
+		return nil, errors.Wrapf(err, "pack index %s is corrupt", path)
+	}
+	idx.Path = path
+	return idx, nil
+}
+
+// ParseIndex parses a version 1 or version 2 pack index.
+func ParseIndex(bs []byte) (*Index, error) {
+	idx := &Index{Version: 1}
+	p := bs
+	if bytes.HasPrefix(bs, idxMagic) {
+		if len(bs) < 8 {
+			return nil, fmt.Errorf("truncated header")
+		}
+		idx.Version = int(binary.BigEndian.Uint32(bs[4:8]))
+		if idx.Version != 2 {
+			return nil, fmt.Errorf("unsupported version %d", idx.Version)
+		}
+		p = bs[8:]
+	}
+	if len(p) < 256*4 {
+		return nil, fmt.Errorf("truncated fanout table")
+	}
+	for i := range idx.fanout {
+		idx.fanout[i] = binary.BigEndian.Uint32(p[i*4:])
+		if i > 0 && idx.fanout[i] < idx.fanout[i-1] {
+			return nil, fmt.Errorf("fanout table is not monotonic")
+		}
+	}
+	p = p[256*4:]
+	n := int(idx.fanout[255])
+	entrySize := 24
+	if idx.Version == 2 {
+		entrySize = 28
+	}
+	if len(p) < n*entrySize+40 {
+		return nil, fmt.Errorf("truncated entries")
+	}
+	idx.offsets = make([]int64, n)
+	switch idx.Version {
+	case 1:
+		idx.shas = make([]byte, 0, n*20)
+		for i := 0; i < n; i++ {
+			e := p[i*24 : (i+1)*24]
+			idx.offsets[i] = int64(binary.BigEndian.Uint32(e))
+			idx.shas = append(idx.shas, e[4:]...)
+		}
+	case 2:
+		idx.shas = p[:n*20]
+		idx.crcs = p[n*20 : n*24]
+		offsets := p[n*24 : n*28]
+		large := p[n*28 : len(p)-40]
+		for i := 0; i < n; i++ {
+			off := binary.BigEndian.Uint32(offsets[i*4:])
+			if off&0x80000000 == 0 {
+				idx.offsets[i] = int64(off)
+				continue
+			}
+			j := int(off & 0x7fffffff)
+			if len(large) < (j+1)*8 {
+				return nil, fmt.Errorf("invalid large offset %d", j)
+			}
+			idx.offsets[i] = int64(binary.BigEndian.Uint64(large[j*8:]))
+		}
+	}
+	idx.packSum, idx.sum = bs[len(bs)-40:len(bs)-20], bs[len(bs)-20:]
+	return idx, nil
+}
+
+// Len returns the number of objects in the index.
+func (idx *Index) Len() int {
+	return len(idx.offsets)
+}
+
+// SHA returns the i-th SHA in the index, in sorted order.
+func (idx *Index) SHA(i int) string {
+	return hex.EncodeToString(idx.shas[i*20 : (i+1)*20])
+}
+
+// Lookup returns the offset of the object with the given SHA in the
+// packfile.
+func (idx *Index) Lookup(sha string) (int64, bool) {
+	bs, err := hex.DecodeString(sha)
+	if err != nil || len(bs) != 20 {
+		return 0, false
+	}
+	lo, hi := idx.bucket(bs[0])
+	i := lo + sort.Search(hi-lo, func(i int) bool {
+		return bytes.Compare(idx.shas[(lo+i)*20:(lo+i+1)*20], bs) >= 0
+	})
+	if i < hi && bytes.Equal(idx.shas[i*20:(i+1)*20], bs) {
+		return idx.offsets[i], true
+	}
+	return 0, false
+}
+
+// Prefix returns the SHAs in the index which start with the given
+// lowercase hex prefix of at least two digits, in sorted order.
+func (idx *Index) Prefix(prefix string) []string {
+	b, err := hex.DecodeString(prefix[:2])
+	if err != nil {
+		return nil
+	}
+	lo, hi := idx.bucket(b[0])
+	i := lo + sort.Search(hi-lo, func(i int) bool {
+		return idx.SHA(lo+i) >= prefix
+	})
+	var res []string
+	for ; i < hi; i++ {
+		sha := idx.SHA(i)
+		if !strings.HasPrefix(sha, prefix) {
+			break
+		}
+		res = append(res, sha)
+	}
+	return res
+}
+
+// bucket returns the range of entries whose SHA starts with the given byte.
+func (idx *Index) bucket(b byte) (int, int) {
+	var lo int
+	if b > 0 {
+		lo = int(idx.fanout[b-1])
+	}
+	return lo, int(idx.fanout[b])
+}
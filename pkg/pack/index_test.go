@@ -0,0 +1,112 @@
+package pack
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// testEntries returns n index entries with distinct SHAs.
+func testEntries(n int) []Entry {
+	entries := make([]Entry, n)
+	for i := range entries {
+		sum := sha1.Sum([]byte(fmt.Sprint(i)))
+		entries[i] = Entry{SHA: hex.EncodeToString(sum[:]), CRC32: uint32(i), Offset: int64(12 + 100*i)}
+	}
+	return entries
+}
+
+func encodeIndex(t testing.TB, entries []Entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := WriteIndex(&buf, entries, make([]byte, 20)); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestIndexRoundTrip(t *testing.T) {
+	entries := testEntries(1000)
+	// Offsets beyond 2 GiB are stored in the large offset table.
+	entries[3].Offset = 1 << 33
+	entries[7].Offset = 1<<31 + 5
+	idx, err := ParseIndex(encodeIndex(t, entries))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idx.Version != 2 || idx.Len() != len(entries) {
+		t.Fatalf("got version %d with %d entries, want version 2 with %d", idx.Version, idx.Len(), len(entries))
+	}
+	for i := 1; i < idx.Len(); i++ {
+		if idx.SHA(i-1) >= idx.SHA(i) {
+			t.Fatalf("SHAs are not sorted at %d", i)
+		}
+	}
+	for _, e := range entries {
+		offset, ok := idx.Lookup(e.SHA)
+		if !ok || offset != e.Offset {
+			t.Errorf("Lookup(%s) = %d, %v, want %d", e.SHA, offset, ok, e.Offset)
+		}
+	}
+	if _, ok := idx.Lookup(strings.Repeat("0", 40)); ok {
+		t.Errorf("Lookup of a missing SHA succeeded")
+	}
+	prefix := entries[0].SHA[:3]
+	var want []string
+	for i := 0; i < idx.Len(); i++ {
+		if strings.HasPrefix(idx.SHA(i), prefix) {
+			want = append(want, idx.SHA(i))
+		}
+	}
+	if got := idx.Prefix(prefix); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Prefix(%s) = %v, want %v", prefix, got, want)
+	}
+}
+
+func TestParseIndexCorrupt(t *testing.T) {
+	valid := encodeIndex(t, testEntries(10))
+	hugeCount := append([]byte(nil), valid...)
+	for i := 0; i < 256; i++ {
+		binary.BigEndian.PutUint32(hugeCount[8+4*i:], 0xffffffff)
+	}
+	descending := append([]byte(nil), valid...)
+	binary.BigEndian.PutUint32(descending[8+4*200:], 0)
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{"empty", nil, "truncated fanout table"},
+		{"bad version", append(append([]byte(nil), idxMagic...), 0, 0, 0, 3), "unsupported version 3"},
+		{"truncated", valid[:len(valid)-100], "truncated entries"},
+		{"huge object count", hugeCount, "truncated entries"},
+		{"descending fanout", descending, "fanout table is not monotonic"},
+	}
+	for _, test := range tests {
+		_, err := ParseIndex(test.data)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got error %v, want %q", test.name, err, test.err)
+		}
+	}
+}
+
+func BenchmarkIndexLookup(b *testing.B) {
+	for _, n := range []int{1000, 100000} {
+		entries := testEntries(n)
+		idx, err := ParseIndex(encodeIndex(b, entries))
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, ok := idx.Lookup(entries[i%n].SHA); !ok {
+					b.Fatal("missing entry")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package pack
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type testObject struct {
+	sha, typ string
+	data     []byte
+}
+
+func newTestObject(typ, data string) testObject {
+	h := sha1.New()
+	fmt.Fprintf(h, "%s %d\x00%s", typ, len(data), data)
+	return testObject{sha: hex.EncodeToString(h.Sum(nil)), typ: typ, data: []byte(data)}
+}
+
+// writeTestPack writes a pack of n objects with the given function and
+// its index to dir, and opens it.
+func writeTestPack(t *testing.T, dir string, n int, add func(*Writer) error) *Pack {
+	t.Helper()
+	var buf bytes.Buffer
+	pw, err := NewWriter(&buf, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := add(pw); err != nil {
+		t.Fatal(err)
+	}
+	sum, err := pw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return openTestPack(t, dir, buf.Bytes(), pw.Entries(), sum)
+}
+
+// openTestPack writes the given pack and an index of the given entries
+// with the given pack checksum to dir, and opens the pack.
+func openTestPack(t *testing.T, dir string, data []byte, entries []Entry, sum []byte) *Pack {
+	t.Helper()
+	var idx bytes.Buffer
+	if err := WriteIndex(&idx, entries, sum); err != nil {
+		t.Fatal(err)
+	}
+	base := filepath.Join(dir, fmt.Sprintf("pack-%x", sum))
+	if err := os.WriteFile(base+".pack", data, 0444); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(base+".idx", idx.Bytes(), 0444); err != nil {
+		t.Fatal(err)
+	}
+	index, err := LoadIndex(base + ".idx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := Open(index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+// checkPackObjects checks that the pack holds the given objects.
+func checkPackObjects(t *testing.T, p *Pack, objs []testObject) {
+	t.Helper()
+	if p.Index.Len() != len(objs) {
+		t.Errorf("pack has %d objects, want %d", p.Index.Len(), len(objs))
+	}
+	for _, o := range objs {
+		offset, ok := p.Index.Lookup(o.sha)
+		if !ok {
+			t.Errorf("object %s is not in the index", o.sha)
+			continue
+		}
+		typ, size, err := p.ReadHeader(offset)
+		if err != nil || typ != o.typ || size != int64(len(o.data)) {
+			t.Errorf("header of %s: got %s %d, %v, want %s %d", o.sha, typ, size, err, o.typ, len(o.data))
+		}
+		typ, data, err := p.Read(o.sha)
+		if err != nil {
+			t.Errorf("reading %s: %v", o.sha, err)
+			continue
+		}
+		if typ != o.typ || !bytes.Equal(data, o.data) {
+			t.Errorf("object %s: got %s %q, want %s %q", o.sha, typ, data, o.typ, o.data)
+		}
+	}
+}
+
+func TestPackRoundTripDeltas(t *testing.T) {
+	text := strings.Repeat("a line of text which deltas can copy\n", 10)
+	var (
+		base   = newTestObject("blob", text)
+		ofs1   = newTestObject("blob", text+"one\n")
+		ofs2   = newTestObject("blob", text+"one\ntwo\n")
+		ref    = newTestObject("blob", "zero\n"+text)
+		refEnd = newTestObject("blob", "zero\n"+text+"end\n")
+		commit = newTestObject("commit", "tree "+strings.Repeat("0", 40)+"\n\nmessage\n")
+	)
+	delta := func(from, to testObject) []byte { return CreateDelta(from.data, to.data) }
+	p := writeTestPack(t, t.TempDir(), 6, func(pw *Writer) error {
+		for _, add := range []func() error{
+			func() error { return pw.Add(commit.sha, commit.typ, commit.data) },
+			func() error { return pw.Add(base.sha, base.typ, base.data) },
+			// a chain of offset deltas
+			func() error { return pw.AddDelta(ofs1.sha, base.sha, delta(base, ofs1)) },
+			func() error { return pw.AddDelta(ofs2.sha, ofs1.sha, delta(ofs1, ofs2)) },
+			// a chain of ref deltas on an offset delta, whose bases follow
+			// them in the pack
+			func() error { return pw.AddDelta(refEnd.sha, ref.sha, delta(ref, refEnd)) },
+			func() error { return pw.AddDelta(ref.sha, ofs2.sha, delta(ofs2, ref)) },
+		} {
+			if err := add(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	objs := []testObject{base, ofs1, ofs2, ref, refEnd, commit}
+	checkPackObjects(t, p, objs)
+
+	infos, err := p.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	depths := map[string]int{commit.sha: 0, base.sha: 0, ofs1.sha: 1, ofs2.sha: 2, refEnd.sha: 4, ref.sha: 3}
+	for _, info := range infos {
+		if info.Depth != depths[info.SHA] {
+			t.Errorf("object %s: got depth %d, want %d", info.SHA, info.Depth, depths[info.SHA])
+		}
+	}
+
+	// the longest chain resolves without cached bases as well
+	fresh, err := Open(p.Index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fresh.Close()
+	if _, data, err := fresh.Read(refEnd.sha); err != nil || !bytes.Equal(data, refEnd.data) {
+		t.Errorf("reading %s from a fresh pack: got %q, %v", refEnd.sha, data, err)
+	}
+}
+
+func TestPackRoundTripFindDeltas(t *testing.T) {
+	var (
+		objs   []testObject
+		packed []*Object
+	)
+	text := strings.Repeat("some shared content\n", 20)
+	for i := 0; i < 20; i++ {
+		o := newTestObject("blob", fmt.Sprintf("%sversion %d\n", text, i))
+		objs = append(objs, o)
+		packed = append(packed, &Object{SHA: o.sha, Type: o.typ, Path: "file", Data: o.data})
+	}
+	FindDeltas(packed, 10, 3)
+	p := writeTestPack(t, t.TempDir(), len(packed), func(pw *Writer) error {
+		for _, o := range packed {
+			if err := pw.AddObject(o); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	checkPackObjects(t, p, objs)
+	infos, err := p.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	deltas := 0
+	for _, info := range infos {
+		if info.Depth > 3 {
+			t.Errorf("object %s has depth %d, want at most 3", info.SHA, info.Depth)
+		}
+		if info.Depth > 0 {
+			deltas++
+		}
+	}
+	if deltas == 0 {
+		t.Error("no object was deltified")
+	}
+}
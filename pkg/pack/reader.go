@@ -0,0 +1,304 @@
+package pack
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultMaxDeltaDepth is the default limit on the length of delta chains.
+const DefaultMaxDeltaDepth = 50
+
+var typeNames = map[int]string{
+	ObjCommit: "commit",
+	ObjTree:   "tree",
+	ObjBlob:   "blob",
+	ObjTag:    "tag",
+}
+
+// maxCached is the number of resolved objects kept to speed up the
+// resolution of deltas sharing a base.
+const maxCached = 256
+
+// Pack is an open packfile together with its index. A Pack is not safe
+// for concurrent use.
+type Pack struct {
+	Index *Index
+	// MaxDeltaDepth is the maximum length of a delta chain. Longer chains
+	// are considered corrupt.
+	MaxDeltaDepth int
+
+	path  string
+	f     *os.File
+	size  int64
+	cache map[int64]object
+}
+
+type object struct {
+	typ  string
+	data []byte
+}
+
+// entry is the header of an object in a pack.
+type entry struct {
+	offset int64
+	code   int
+	size   int64
+	// base is the offset of the base object of a delta.
+	base int64
+	// data is the offset of the compressed data.
+	data int64
+}
+
+// Open opens the packfile belonging to the given index.
+func Open(idx *Index) (*Pack, error) {
+	path := strings.TrimSuffix(idx.Path, ".idx") + ".pack"
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error opening pack %s", path)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, errors.Wrapf(err, "error opening pack %s", path)
+	}
+	p := &Pack{
+		Index:         idx,
+		MaxDeltaDepth: DefaultMaxDeltaDepth,
+		path:          path,
+		f:             f,
+		size:          info.Size(),
+		cache:         make(map[int64]object),
+	}
+	if err := p.checkHeader(); err != nil {
+		f.Close()
+		return nil, errors.Wrapf(err, "pack %s is corrupt", path)
+	}
+	return p, nil
+}
+
+func (p *Pack) checkHeader() error {
+	var hdr [12]byte
+	if _, err := p.f.ReadAt(hdr[:], 0); err != nil || p.size < 32 {
+		return fmt.Errorf("truncated pack")
+	}
+	if !bytes.Equal(hdr[:4], []byte("PACK")) {
+		return fmt.Errorf("invalid signature")
+	}
+	if v := binary.BigEndian.Uint32(hdr[4:]); v != 2 && v != 3 {
+		return fmt.Errorf("unsupported version %d", v)
+	}
+	if n := binary.BigEndian.Uint32(hdr[8:]); int(n) != p.Index.Len() {
+		return fmt.Errorf("pack has %d objects, index has %d", n, p.Index.Len())
+	}
+	return nil
+}
+
+// Close closes the packfile.
+func (p *Pack) Close() error {
+	return p.f.Close()
+}
+
+// Read reads the object with the given SHA and returns its type and data.
+// It returns an error if the object is not in the pack.
+func (p *Pack) Read(sha string) (string, []byte, error) {
+	offset, ok := p.Index.Lookup(sha)
+	if !ok {
+		return "", nil, fmt.Errorf("object %s is not in pack %s", sha, p.path)
+	}
+	return p.ReadAt(offset)
+}
+
+// ReadAt reads the object at the given offset and returns its type and
+// data. Deltas are resolved against their bases. The returned data must
+// not be modified.
+func (p *Pack) ReadAt(offset int64) (string, []byte, error) {
+	if o, ok := p.cache[offset]; ok {
+		return o.typ, o.data, nil
+	}
+	chain, base, err := p.chain(offset)
+	if err != nil {
+		return "", nil, err
+	}
+	var o object
+	if cached, ok := p.cache[base.offset]; ok {
+		o = cached
+	} else {
+		o.typ = typeNames[base.code]
+		if o.data, err = p.inflate(base); err != nil {
+			return "", nil, err
+		}
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		delta, err := p.inflate(chain[i])
+		if err != nil {
+			return "", nil, err
+		}
+		if o.data, err = ApplyDelta(o.data, delta); err != nil {
+			return "", nil, errors.Wrapf(err, "pack %s is corrupt at offset %d", p.path, chain[i].offset)
+		}
+	}
+	p.remember(offset, o)
+	return o.typ, o.data, nil
+}
+
+// ReadHeader returns the type and size of the object at the given
+// offset, inflating only the header of a delta.
+func (p *Pack) ReadHeader(offset int64) (string, int64, error) {
+	if o, ok := p.cache[offset]; ok {
+		return o.typ, int64(len(o.data)), nil
+	}
+	chain, base, err := p.chain(offset)
+	if err != nil {
+		return "", 0, err
+	}
+	typ := typeNames[base.code]
+	if o, ok := p.cache[base.offset]; ok {
+		typ = o.typ
+	}
+	if len(chain) == 0 {
+		return typ, base.size, nil
+	}
+	zr, err := zlib.NewReader(bufio.NewReader(io.NewSectionReader(p.f, chain[0].data, p.size-chain[0].data)))
+	if err != nil {
+		return "", 0, errors.Wrapf(err, "pack %s is corrupt at offset %d", p.path, offset)
+	}
+	defer zr.Close()
+	var hdr [20]byte
+	n, err := io.ReadFull(zr, hdr[:])
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", 0, errors.Wrapf(err, "pack %s is corrupt at offset %d", p.path, offset)
+	}
+	_, rest, err := deltaSize(hdr[:n])
+	if err == nil {
+		var size int64
+		if size, _, err = deltaSize(rest); err == nil {
+			return typ, size, nil
+		}
+	}
+	return "", 0, errors.Wrapf(err, "pack %s is corrupt at offset %d", p.path, offset)
+}
+
+// chain follows the delta chain starting at the given offset. It returns
+// the deltas in the order they were found, and the base object at the
+// end of the chain. The chain ends early at a cached base, whose entry
+// only has its offset set.
+func (p *Pack) chain(offset int64) ([]entry, entry, error) {
+	var (
+		chain []entry
+		seen  = make(map[int64]bool)
+	)
+	for {
+		if seen[offset] {
+			return nil, entry{}, fmt.Errorf("pack %s is corrupt: delta cycle at offset %d", p.path, offset)
+		}
+		seen[offset] = true
+		e, err := p.readEntry(offset)
+		if err != nil {
+			return nil, entry{}, errors.Wrapf(err, "pack %s is corrupt at offset %d", p.path, offset)
+		}
+		if e.code != ObjOfsDelta && e.code != ObjRefDelta {
+			return chain, e, nil
+		}
+		if _, ok := p.cache[e.base]; ok {
+			return append(chain, e), entry{offset: e.base}, nil
+		}
+		chain = append(chain, e)
+		if len(chain) > p.MaxDeltaDepth {
+			return nil, entry{}, fmt.Errorf("pack %s: delta chain at offset %d exceeds the maximum depth of %d", p.path, chain[0].offset, p.MaxDeltaDepth)
+		}
+		offset = e.base
+	}
+}
+
+// readEntry parses the header of the object at the given offset.
+func (p *Pack) readEntry(offset int64) (entry, error) {
+	if offset < 12 || offset >= p.size-20 {
+		return entry{}, fmt.Errorf("invalid object offset")
+	}
+	var buf [32]byte
+	n, err := p.f.ReadAt(buf[:], offset)
+	if err != nil && err != io.EOF {
+		return entry{}, err
+	}
+	bs := buf[:n]
+	e := entry{offset: offset, code: int(bs[0]>>4) & 7, size: int64(bs[0] & 0x0f)}
+	i, shift := 1, uint(4)
+	for c := bs[0]; c&0x80 != 0; i++ {
+		if i == len(bs) || shift > 56 {
+			return entry{}, fmt.Errorf("invalid object header")
+		}
+		c = bs[i]
+		e.size |= int64(c&0x7f) << shift
+		shift += 7
+	}
+	switch e.code {
+	case ObjCommit, ObjTree, ObjBlob, ObjTag:
+	case ObjOfsDelta:
+		if i == len(bs) {
+			return entry{}, fmt.Errorf("invalid delta base offset")
+		}
+		c := bs[i]
+		rel := int64(c & 0x7f)
+		for i++; c&0x80 != 0; i++ {
+			if i == len(bs) || rel >= 1<<56 {
+				return entry{}, fmt.Errorf("invalid delta base offset")
+			}
+			c = bs[i]
+			rel = ((rel + 1) << 7) | int64(c&0x7f)
+		}
+		if rel <= 0 || rel > offset {
+			return entry{}, fmt.Errorf("invalid delta base offset")
+		}
+		e.base = offset - rel
+	case ObjRefDelta:
+		if i+20 > len(bs) {
+			return entry{}, fmt.Errorf("truncated delta base")
+		}
+		sha := hex.EncodeToString(bs[i : i+20])
+		i += 20
+		base, ok := p.Index.Lookup(sha)
+		if !ok {
+			return entry{}, fmt.Errorf("delta base %s is not in the pack", sha)
+		}
+		e.base = base
+	default:
+		return entry{}, fmt.Errorf("invalid object type %d", e.code)
+	}
+	e.data = offset + int64(i)
+	return e, nil
+}
+
+// inflate decompresses the data of the given entry.
+func (p *Pack) inflate(e entry) ([]byte, error) {
+	zr, err := zlib.NewReader(bufio.NewReader(io.NewSectionReader(p.f, e.data, p.size-e.data)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "pack %s is corrupt at offset %d", p.path, e.data)
+	}
+	defer zr.Close()
+	data, err := io.ReadAll(io.LimitReader(zr, e.size+1))
+	if err != nil {
+		return nil, errors.Wrapf(err, "pack %s is corrupt at offset %d", p.path, e.data)
+	}
+	if int64(len(data)) != e.size {
+		return nil, fmt.Errorf("pack %s is corrupt at offset %d: object has size %d, want %d", p.path, e.data, len(data), e.size)
+	}
+	return data, nil
+}
+
+// remember caches a resolved object, evicting all cached objects when the
+// cache is full.
+func (p *Pack) remember(offset int64, o object) {
+	if len(p.cache) >= maxCached {
+		p.cache = make(map[int64]object)
+	}
+	p.cache[offset] = o
+}
@@ -0,0 +1,146 @@
+package pack
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// ObjectInfo describes an object stored in a pack.
+type ObjectInfo struct {
+	SHA string
+	// Type is the type of the object, also for deltas.
+	Type string
+	// Size is the size of the stored data, which is the size of the delta
+	// for deltified objects.
+	Size int64
+	// PackedSize is the number of bytes the object takes up in the pack.
+	PackedSize int64
+	Offset     int64
+	// Depth is the length of the delta chain, or 0 for objects which are
+	// not deltified.
+	Depth int
+	// Base is the SHA of the delta base.
+	Base string
+}
+
+// Verify checks the pack against its index: the checksums of both files,
+// the CRC32 checksums of the packed objects, if the index has them, and
+// the SHAs of all objects. It returns the objects in pack order.
+func (p *Pack) Verify() ([]ObjectInfo, error) {
+	if err := p.verifyChecksums(); err != nil {
+		return nil, err
+	}
+	idx := p.Index
+	order := make([]int, idx.Len())
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return idx.offsets[order[i]] < idx.offsets[order[j]]
+	})
+	var (
+		infos  = make([]ObjectInfo, len(order))
+		byOffs = make(map[int64]int, len(order))
+		bases  = make([]int64, len(order))
+	)
+	for k, i := range order {
+		info := ObjectInfo{SHA: idx.SHA(i), Offset: idx.offsets[i]}
+		end := p.size - 20
+		if k+1 < len(order) {
+			end = idx.offsets[order[k+1]]
+		}
+		info.PackedSize = end - info.Offset
+		if err := p.verifyObject(i, &info, &bases[k]); err != nil {
+			return nil, errors.Wrapf(err, "pack %s is corrupt: object %s", p.path, info.SHA)
+		}
+		byOffs[info.Offset] = k
+		infos[k] = info
+	}
+	for k := range infos {
+		for b := bases[k]; b != 0; {
+			j, ok := byOffs[b]
+			if !ok {
+				return nil, fmt.Errorf("pack %s is corrupt: object %s: delta base at offset %d is not in the index", p.path, infos[k].SHA, b)
+			}
+			if infos[k].Base == "" {
+				infos[k].Base = infos[j].SHA
+			}
+			infos[k].Depth++
+			b = bases[j]
+		}
+	}
+	return infos, nil
+}
+
+// verifyChecksums checks the trailing checksums of the pack and the index.
+func (p *Pack) verifyChecksums() error {
+	bs, err := os.ReadFile(p.Index.Path)
+	if err != nil {
+		return errors.Wrapf(err, "error reading pack index %s", p.Index.Path)
+	}
+	if sum := sha1.Sum(bs[:len(bs)-20]); !bytes.Equal(sum[:], p.Index.sum) {
+		return fmt.Errorf("pack index %s is corrupt: checksum mismatch", p.Index.Path)
+	}
+	h := sha1.New()
+	if _, err := io.Copy(h, io.NewSectionReader(p.f, 0, p.size-20)); err != nil {
+		return errors.Wrapf(err, "error reading pack %s", p.path)
+	}
+	trailer := make([]byte, 20)
+	if _, err := p.f.ReadAt(trailer, p.size-20); err != nil {
+		return errors.Wrapf(err, "error reading pack %s", p.path)
+	}
+	if !bytes.Equal(h.Sum(nil), trailer) {
+		return fmt.Errorf("pack %s is corrupt: checksum mismatch", p.path)
+	}
+	if !bytes.Equal(trailer, p.Index.packSum) {
+		return fmt.Errorf("pack index %s does not belong to pack %s", p.Index.Path, p.path)
+	}
+	return nil
+}
+
+// verifyObject checks the i-th object of the index, whose offset and
+// packed size are set in info, and fills in the rest of info. For deltas,
+// base is set to the offset of the delta base.
+func (p *Pack) verifyObject(i int, info *ObjectInfo, base *int64) error {
+	if info.PackedSize <= 0 {
+		return fmt.Errorf("invalid offset %d", info.Offset)
+	}
+	if p.Index.crcs != nil {
+		raw := make([]byte, info.PackedSize)
+		if _, err := p.f.ReadAt(raw, info.Offset); err != nil {
+			return err
+		}
+		if crc32.ChecksumIEEE(raw) != binary.BigEndian.Uint32(p.Index.crcs[i*4:]) {
+			return fmt.Errorf("CRC32 mismatch")
+		}
+	}
+	e, err := p.readEntry(info.Offset)
+	if err != nil {
+		return err
+	}
+	info.Size = e.size
+	if e.code == ObjOfsDelta || e.code == ObjRefDelta {
+		*base = e.base
+	}
+	typ, data, err := p.ReadAt(info.Offset)
+	if err != nil {
+		return err
+	}
+	info.Type = typ
+	h := sha1.New()
+	fmt.Fprintf(h, "%s %d\x00", typ, len(data))
+	h.Write(data)
+	if sha := hex.EncodeToString(h.Sum(nil)); sha != info.SHA {
+		return fmt.Errorf("SHA mismatch: data hashes to %s", sha)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package pack
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// encodeVerifyPack encodes a pack of a commit, a blob and an offset delta
+// against the blob. It returns the pack, its index entries and checksum.
+func encodeVerifyPack(t *testing.T) ([]byte, []Entry, []byte, []testObject) {
+	t.Helper()
+	text := strings.Repeat("content shared by both blobs\n", 10)
+	var (
+		commit = newTestObject("commit", "tree "+strings.Repeat("0", 40)+"\n\nmessage\n")
+		base   = newTestObject("blob", text)
+		delta  = newTestObject("blob", text+"more\n")
+		buf    bytes.Buffer
+	)
+	pw, err := NewWriter(&buf, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range []testObject{commit, base} {
+		if err := pw.Add(o.sha, o.typ, o.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pw.AddDelta(delta.sha, base.sha, CreateDelta(base.data, delta.data)); err != nil {
+		t.Fatal(err)
+	}
+	sum, err := pw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes(), pw.Entries(), sum, []testObject{commit, base, delta}
+}
+
+// resum replaces the trailing checksum of a modified pack.
+func resum(data []byte) []byte {
+	data = append([]byte(nil), data...)
+	sum := sha1.Sum(data[:len(data)-20])
+	copy(data[len(data)-20:], sum[:])
+	return data
+}
+
+func TestVerify(t *testing.T) {
+	data, entries, sum, objs := encodeVerifyPack(t)
+	p := openTestPack(t, t.TempDir(), data, entries, sum)
+	infos, err := p.Verify()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != len(objs) {
+		t.Fatalf("got %d objects, want %d", len(infos), len(objs))
+	}
+	var packed int64
+	for i, info := range infos {
+		o := objs[i]
+		if info.SHA != o.sha || info.Type != o.typ || info.Offset != entries[i].Offset {
+			t.Errorf("object %d: got %s %s at %d, want %s %s at %d", i, info.SHA, info.Type, info.Offset, o.sha, o.typ, entries[i].Offset)
+		}
+		packed += info.PackedSize
+	}
+	if want := int64(len(data) - 12 - 20); packed != want {
+		t.Errorf("objects take up %d bytes, want %d", packed, want)
+	}
+	if infos[1].Depth != 0 || infos[1].Size != int64(len(objs[1].data)) {
+		t.Errorf("base: got depth %d and size %d", infos[1].Depth, infos[1].Size)
+	}
+	if d := infos[2]; d.Depth != 1 || d.Base != objs[1].sha || d.Size >= int64(len(objs[2].data)) {
+		t.Errorf("delta: got depth %d, base %s and size %d", d.Depth, d.Base, d.Size)
+	}
+
+	if _, err := exec.LookPath("git"); err == nil {
+		if out, err := exec.Command("git", "verify-pack", p.Index.Path).CombinedOutput(); err != nil {
+			t.Errorf("git verify-pack: %v\n%s", err, out)
+		}
+	}
+}
+
+func TestVerifyCorrupt(t *testing.T) {
+	data, entries, sum, _ := encodeVerifyPack(t)
+	// flip a byte in the compressed data of the blob
+	flipped := append([]byte(nil), data...)
+	flipped[entries[1].Offset+10] ^= 0xff
+
+	renamed := append([]Entry(nil), entries...)
+	renamed[0].SHA = strings.Repeat("1", 40)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		entries []Entry
+		sum     []byte
+		err     string
+	}{
+		{"pack checksum", flipped, entries, sum, "checksum mismatch"},
+		{"CRC32", resum(flipped), entries, resum(flipped)[len(flipped)-20:], "CRC32 mismatch"},
+		{"SHA", data, renamed, sum, "SHA mismatch"},
+		{"index of another pack", data, entries, bytes.Repeat([]byte{1}, 20), "does not belong to pack"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := openTestPack(t, t.TempDir(), test.data, test.entries, test.sum)
+			_, err := p.Verify()
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("got error %v, want %q", err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package pack
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Object types as encoded in packfiles.
+const (
+	ObjCommit   = 1
+	ObjTree     = 2
+	ObjBlob     = 3
+	ObjTag      = 4
+	ObjOfsDelta = 6
+	ObjRefDelta = 7
+)
+
+var typeCodes = map[string]int{
+	"commit": ObjCommit,
+	"tree":   ObjTree,
+	"blob":   ObjBlob,
+	"tag":    ObjTag,
+}
+
+// Entry is an index entry for an object in a pack.
+type Entry struct {
+	SHA    string
+	CRC32  uint32
+	Offset int64
+}
+
+// Writer writes a version 2 packfile.
+type Writer struct {
+	w       io.Writer
+	hasher  hash.Hash
+	offset  int64
+	n, want int
+	entries []Entry
+	offsets map[string]int64
+}
+
+// NewWriter creates a writer for a pack with the given number of objects
+// and writes the pack header.
+func NewWriter(w io.Writer, n int) (*Writer, error) {
+	pw := &Writer{hasher: sha1.New(), want: n, offsets: make(map[string]int64)}
+	pw.w = io.MultiWriter(w, pw.hasher)
+	var hdr [12]byte
+	copy(hdr[:], "PACK")
+	binary.BigEndian.PutUint32(hdr[4:], 2)
+	binary.BigEndian.PutUint32(hdr[8:], uint32(n))
+	if err := pw.write(hdr[:]); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+func (pw *Writer) write(bs []byte) error {
+	n, err := pw.w.Write(bs)
+	pw.offset += int64(n)
+	return errors.Wrap(err, "error writing pack")
+}
+
+// Add adds an undeltified object to the pack.
+func (pw *Writer) Add(sha string, typ string, data []byte) error {
+	code, ok := typeCodes[typ]
+	if !ok {
+		return fmt.Errorf("object %s has invalid type %s", sha, typ)
+	}
+	return pw.add(sha, code, nil, data)
+}
+
+// AddObject adds an object to the pack. If FindDeltas found a delta base
+// for the object, it is stored as a delta, and the base is added first
+// unless the pack already contains it. Objects already in the pack are
+// skipped.
+func (pw *Writer) AddObject(o *Object) error {
+	if _, ok := pw.offsets[o.SHA]; ok {
+		return nil
+	}
+	if o.base == nil {
+		return pw.Add(o.SHA, o.Type, o.Data)
+	}
+	if err := pw.AddObject(o.base); err != nil {
+		return err
+	}
+	return pw.AddDelta(o.SHA, o.base.SHA, o.delta)
+}
+
+// AddDelta adds an object as a delta against the object baseSHA. If the
+// base is in the pack, it is referenced by its offset, otherwise by its
+// SHA.
+func (pw *Writer) AddDelta(sha, baseSHA string, delta []byte) error {
+	if offset, ok := pw.offsets[baseSHA]; ok {
+		return pw.add(sha, ObjOfsDelta, encodeOffset(pw.offset-offset), delta)
+	}
+	base, err := hex.DecodeString(baseSHA)
+	if err != nil || len(base) != 20 {
+		return fmt.Errorf("invalid SHA %s", baseSHA)
+	}
+	return pw.add(sha, ObjRefDelta, base, delta)
+}
+
+// add writes an object with the given type code. For delta objects, base
+// is the encoded base reference following the object header.
+func (pw *Writer) add(sha string, code int, base []byte, data []byte) error {
+	if pw.n == pw.want {
+		return fmt.Errorf("too many objects for pack of %d objects", pw.want)
+	}
+	var buf bytes.Buffer
+	buf.Write(encodeHeader(code, int64(len(data))))
+	buf.Write(base)
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	pw.offsets[sha] = pw.offset
+	pw.entries = append(pw.entries, Entry{
+		SHA:    sha,
+		CRC32:  crc32.ChecksumIEEE(buf.Bytes()),
+		Offset: pw.offset,
+	})
+	pw.n++
+	return pw.write(buf.Bytes())
+}
+
+// Close writes the pack trailer and returns the pack checksum, which is
+// also the name of the pack.
+func (pw *Writer) Close() ([]byte, error) {
+	if pw.n != pw.want {
+		return nil, fmt.Errorf("pack has %d objects, want %d", pw.n, pw.want)
+	}
+	sum := pw.hasher.Sum(nil)
+	return sum, pw.write(sum)
+}
+
+// Entries returns the index entries of the objects written so far.
+func (pw *Writer) Entries() []Entry {
+	return pw.entries
+}
+
+// encodeHeader encodes the type and size of a pack object.
+func encodeHeader(code int, size int64) []byte {
+	b := byte(code<<4) | byte(size&0x0f)
+	size >>= 4
+	var res []byte
+	for size > 0 {
+		res = append(res, b|0x80)
+		b = byte(size & 0x7f)
+		size >>= 7
+	}
+	return append(res, b)
+}
+
+// encodeOffset encodes the distance of a delta to its base.
+func encodeOffset(rel int64) []byte {
+	res := []byte{byte(rel & 0x7f)}
+	for rel >>= 7; rel > 0; rel >>= 7 {
+		rel--
+		res = append([]byte{byte(rel&0x7f) | 0x80}, res...)
+	}
+	return res
+}
+
+// WriteIndex writes a version 2 pack index for the given entries.
+func WriteIndex(w io.Writer, entries []Entry, packSum []byte) error {
+	entries = append([]Entry(nil), entries...)
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].SHA < entries[j].SHA
+	})
+	var (
+		hasher = sha1.New()
+		buf    bytes.Buffer
+		fanout [256]uint32
+		large  []int64
+	)
+	buf.Write(idxMagic)
+	binary.Write(&buf, binary.BigEndian, uint32(2))
+	for _, e := range entries {
+		b, err := hex.DecodeString(e.SHA[:2])
+		if err != nil {
+			return errors.Wrapf(err, "invalid SHA %s", e.SHA)
+		}
+		for i := int(b[0]); i < 256; i++ {
+			fanout[i]++
+		}
+	}
+	binary.Write(&buf, binary.BigEndian, fanout)
+	for i, e := range entries {
+		bs, err := hex.DecodeString(e.SHA)
+		if err != nil || len(bs) != 20 {
+			return fmt.Errorf("invalid SHA %s", e.SHA)
+		}
+		if i > 0 && e.SHA == entries[i-1].SHA {
+			return fmt.Errorf("duplicate object %s", e.SHA)
+		}
+		buf.Write(bs)
+	}
+	for _, e := range entries {
+		binary.Write(&buf, binary.BigEndian, e.CRC32)
+	}
+	for _, e := range entries {
+		if e.Offset < 0x80000000 {
+			binary.Write(&buf, binary.BigEndian, uint32(e.Offset))
+			continue
+		}
+		binary.Write(&buf, binary.BigEndian, uint32(0x80000000|len(large)))
+		large = append(large, e.Offset)
+	}
+	for _, off := range large {
+		binary.Write(&buf, binary.BigEndian, uint64(off))
+	}
+	buf.Write(packSum)
+	hasher.Write(buf.Bytes())
+	buf.Write(hasher.Sum(nil))
+	_, err := buf.WriteTo(w)
+	return errors.Wrap(err, "error writing pack index")
+}
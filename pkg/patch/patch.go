@@ -0,0 +1,269 @@
+// Package patch implements parsing and applying unified diffs.
+package patch
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// File is the patch for a single file.
+type File struct {
+	// OldPath and NewPath are the paths before and after the patch.
+	// They are empty for created and deleted files, respectively.
+	OldPath, NewPath string
+	Hunks            []*Hunk
+}
+
+// Hunk is a contiguous change within a file.
+type Hunk struct {
+	OldStart, OldLines int
+	NewStart, NewLines int
+	Lines              []Line
+}
+
+// Line is a line in a hunk. Op is one of ' ', '-' or '+'. Text includes
+// the line terminator, unless the line is at the end of a file without a
+// trailing newline.
+type Line struct {
+	Op   byte
+	Text string
+}
+
+// Parse parses a unified diff. Paths are stripped of their first strip
+// components, like patch -p.
+func Parse(r io.Reader, strip int) ([]*File, error) {
+	var (
+		br    = bufio.NewReader(r)
+		files []*File
+		cur   *File
+		err   error
+		line  string
+	)
+	for {
+		if line, err = readLine(br); err != nil {
+			break
+		}
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			cur = &File{}
+			files = append(files, cur)
+		case strings.HasPrefix(line, "--- "):
+			if cur == nil || len(cur.Hunks) > 0 {
+				cur = &File{}
+				files = append(files, cur)
+			}
+			if cur.OldPath, err = parsePath(line[4:], strip); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(line, "+++ "):
+			if cur == nil {
+				return nil, fmt.Errorf("unexpected line %q", strings.TrimRight(line, "\n"))
+			}
+			if cur.NewPath, err = parsePath(line[4:], strip); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(line, "@@ "):
+			if cur == nil {
+				return nil, fmt.Errorf("hunk without file header")
+			}
+			h, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, err
+			}
+			if err := readHunk(h, br); err != nil {
+				return nil, err
+			}
+			cur.Hunks = append(cur.Hunks, h)
+		}
+	}
+	if err != io.EOF {
+		return nil, errors.Wrap(err, "error reading patch")
+	}
+	return files, nil
+}
+
+func parsePath(s string, strip int) (string, error) {
+	s = strings.TrimRight(s, "\r\n")
+	if i := strings.IndexByte(s, '\t'); i >= 0 {
+		s = s[:i]
+	}
+	if s == "/dev/null" {
+		return "", nil
+	}
+	for i := 0; i < strip; i++ {
+		j := strings.IndexByte(s, '/')
+		if j < 0 {
+			return "", fmt.Errorf("cannot strip %d components from path %s", strip, s)
+		}
+		s = s[j+1:]
+	}
+	return s, nil
+}
+
+func parseHunkHeader(line string) (*Hunk, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || fields[3] != "@@" || fields[1][0] != '-' || fields[2][0] != '+' {
+		return nil, fmt.Errorf("invalid hunk header %q", strings.TrimRight(line, "\n"))
+	}
+	var (
+		h   Hunk
+		err error
+	)
+	if h.OldStart, h.OldLines, err = parseRange(fields[1][1:]); err != nil {
+		return nil, errors.Wrapf(err, "invalid hunk header %q", strings.TrimRight(line, "\n"))
+	}
+	if h.NewStart, h.NewLines, err = parseRange(fields[2][1:]); err != nil {
+		return nil, errors.Wrapf(err, "invalid hunk header %q", strings.TrimRight(line, "\n"))
+	}
+	return &h, nil
+}
+
+func parseRange(s string) (int, int, error) {
+	start, lines := s, "1"
+	if i := strings.IndexByte(s, ','); i >= 0 {
+		start, lines = s[:i], s[i+1:]
+	}
+	a, err := strconv.Atoi(start)
+	if err != nil {
+		return 0, 0, err
+	}
+	b, err := strconv.Atoi(lines)
+	if err != nil {
+		return 0, 0, err
+	}
+	return a, b, nil
+}
+
+func readLine(br *bufio.Reader) (string, error) {
+	l, err := br.ReadString('\n')
+	if err == io.EOF && len(l) > 0 {
+		err = nil
+	}
+	return l, err
+}
+
+func readHunk(h *Hunk, br *bufio.Reader) error {
+	oldLines, newLines := h.OldLines, h.NewLines
+	for oldLines > 0 || newLines > 0 || nextIsMarker(br) {
+		line, err := readLine(br)
+		if err != nil {
+			return fmt.Errorf("truncated hunk")
+		}
+		if strings.HasPrefix(line, `\`) {
+			// "\ No newline at end of file" refers to the previous line
+			if len(h.Lines) > 0 {
+				l := &h.Lines[len(h.Lines)-1]
+				l.Text = strings.TrimSuffix(l.Text, "\n")
+			}
+			continue
+		}
+		if line == "\n" {
+			// some tools drop the space of empty context lines
+			line = " \n"
+		}
+		switch line[0] {
+		case ' ':
+			oldLines--
+			newLines--
+		case '-':
+			oldLines--
+		case '+':
+			newLines--
+		default:
+			return fmt.Errorf("invalid hunk line %q", strings.TrimRight(line, "\n"))
+		}
+		h.Lines = append(h.Lines, Line{Op: line[0], Text: line[1:]})
+	}
+	if oldLines < 0 || newLines < 0 {
+		return fmt.Errorf("hunk line counts do not match header")
+	}
+	return nil
+}
+
+func nextIsMarker(br *bufio.Reader) bool {
+	bs, err := br.Peek(1)
+	return err == nil && bs[0] == '\\'
+}
+
+// Apply applies the patch to the given content. Hunks must match the
+// content exactly, but may be offset from the lines given in their header.
+func (f *File) Apply(src []byte) ([]byte, error) {
+	var (
+		lines = splitLines(src)
+		out   []string
+		pos   int
+	)
+	for i, h := range f.Hunks {
+		var pre, post []string
+		for _, l := range h.Lines {
+			if l.Op != '+' {
+				pre = append(pre, l.Text)
+			}
+			if l.Op != '-' {
+				post = append(post, l.Text)
+			}
+		}
+		start := h.OldStart - 1
+		if h.OldLines == 0 {
+			start = h.OldStart
+		}
+		at, ok := find(lines, pre, pos, start)
+		if !ok {
+			return nil, fmt.Errorf("hunk #%d does not apply", i+1)
+		}
+		out = append(out, lines[pos:at]...)
+		out = append(out, post...)
+		pos = at + len(pre)
+	}
+	out = append(out, lines[pos:]...)
+	var buf bytes.Buffer
+	for _, l := range out {
+		buf.WriteString(l)
+	}
+	return buf.Bytes(), nil
+}
+
+// find finds the position closest to start, but not before min, at which
+// the given lines match.
+func find(lines, pre []string, min, start int) (int, bool) {
+	matches := func(at int) bool {
+		if at < min || at+len(pre) > len(lines) {
+			return false
+		}
+		for i, l := range pre {
+			if lines[at+i] != l {
+				return false
+			}
+		}
+		return true
+	}
+	for d := 0; start-d >= min || start+d <= len(lines); d++ {
+		if matches(start - d) {
+			return start - d, true
+		}
+		if matches(start + d) {
+			return start + d, true
+		}
+	}
+	return 0, false
+}
+
+func splitLines(bs []byte) []string {
+	var res []string
+	for len(bs) > 0 {
+		i := bytes.IndexByte(bs, '\n')
+		if i < 0 {
+			res = append(res, string(bs))
+			break
+		}
+		res = append(res, string(bs[:i+1]))
+		bs = bs[i+1:]
+	}
+	return res
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+)
+
+// bloomFilter is a bloom filter over object SHAs. As SHAs are uniformly
+// distributed, the bit positions are taken directly from their bytes.
+type bloomFilter struct {
+	bits []uint64
+}
+
+const bloomHashes = 4
+
+func newBloomFilter(n int) *bloomFilter {
+	// about ten bits per object, for a false positive rate below 2%
+	words := (n*10 + 63) / 64
+	if words < 16 {
+		words = 16
+	}
+	return &bloomFilter{bits: make([]uint64, words)}
+}
+
+func (b *bloomFilter) positions(sha string) ([bloomHashes]uint64, bool) {
+	var res [bloomHashes]uint64
+	bs, err := hex.DecodeString(sha)
+	if err != nil || len(bs) != 20 {
+		return res, false
+	}
+	m := uint64(len(b.bits) * 64)
+	for i := range res {
+		res[i] = uint64(binary.BigEndian.Uint32(bs[i*4:])) % m
+	}
+	return res, true
+}
+
+func (b *bloomFilter) add(sha string) {
+	pos, ok := b.positions(sha)
+	if !ok {
+		return
+	}
+	for _, p := range pos {
+		b.bits[p/64] |= 1 << (p % 64)
+	}
+}
+
+// mayContain returns false if the SHA is definitely not in the filter.
+func (b *bloomFilter) mayContain(sha string) bool {
+	pos, ok := b.positions(sha)
+	if !ok {
+		return false
+	}
+	for _, p := range pos {
+		if b.bits[p/64]&(1<<(p%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// UseBloomFilter makes HasObject consult an in-memory bloom filter over
+// all loose and packed objects, which avoids probing the object store
+// for objects which do not exist. The filter is built on first use and
+// kept up to date with objects written through the repository. It pays
+// off for operations which check the existence of many objects.
+func (r *Repository) UseBloomFilter() {
+	r.useBloom = true
+}
+
+// bloomFilter returns the bloom filter over all objects, building it if
+// necessary. It returns nil if the filter could not be built.
+func (r *Repository) bloomFilter() *bloomFilter {
+	if r.bloom != nil {
+		return r.bloom
+	}
+	packs, err := r.PackIndexes()
+	if err != nil {
+		return nil
+	}
+	var loose []string
+	for _, dir := range r.objectDirs() {
+		fanout, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, d := range fanout {
+			if !d.IsDir() || len(d.Name()) != 2 || !isHex(d.Name()) {
+				continue
+			}
+			fs, err := os.ReadDir(filepath.Join(dir, d.Name()))
+			if err != nil {
+				return nil
+			}
+			for _, f := range fs {
+				loose = append(loose, d.Name()+f.Name())
+			}
+		}
+	}
+	n := len(loose)
+	for _, idx := range packs {
+		n += idx.Len()
+	}
+	b := newBloomFilter(n)
+	for _, sha := range loose {
+		b.add(sha)
+	}
+	for _, idx := range packs {
+		for i := 0; i < idx.Len(); i++ {
+			b.add(idx.SHA(i))
+		}
+	}
+	r.bloom = b
+	return b
+}
@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sboehler/got/pkg/index"
+	"github.com/sboehler/got/pkg/object"
+)
+
+// CheckoutTree updates the index and the worktree from the tree from,
+// which is the tree of HEAD or empty for an unborn branch, to the tree
+// to. Only paths which differ between the two trees are touched, so local
+// changes to other paths are kept. Checkout is refused if it would
+// overwrite local changes or untracked files.
+func (r *Repository) CheckoutTree(idx *index.Index, from, to string) error {
+	if err := r.RequireWorktree(); err != nil {
+		return err
+	}
+	if idx.Conflicted() {
+		return fmt.Errorf("you need to resolve your current index first")
+	}
+	oldFiles := make(map[string]object.TreeEntry)
+	if from != "" {
+		var err error
+		if oldFiles, err = r.TreeFiles(from); err != nil {
+			return err
+		}
+	}
+	newFiles, err := r.TreeFiles(to)
+	if err != nil {
+		return err
+	}
+	for p := range newFiles {
+		for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+			if _, ok := newFiles[dir]; ok {
+				return fmt.Errorf("tree %s has both a file and a directory at %s", to, dir)
+			}
+		}
+	}
+	var removed, changed []string
+	for p, e := range oldFiles {
+		if _, ok := newFiles[p]; !ok {
+			removed = append(removed, p)
+		} else if newFiles[p] != e {
+			changed = append(changed, p)
+		}
+	}
+	for p := range newFiles {
+		if _, ok := oldFiles[p]; !ok {
+			changed = append(changed, p)
+		}
+	}
+	sort.Strings(removed)
+	sort.Strings(changed)
+	if err := r.checkOverwrite(idx, removed, changed); err != nil {
+		return err
+	}
+	if r.dryRun("check out tree %s", to) {
+		return nil
+	}
+	for _, p := range removed {
+		idx.Remove(p)
+		abs := filepath.Join(r.Worktree, filepath.FromSlash(p))
+		if err := os.Remove(abs); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "error removing %s", p)
+		}
+		r.removeEmptyParents(p)
+	}
+	for _, p := range changed {
+		e, err := r.checkoutFile(p, newFiles[p])
+		if err != nil {
+			return err
+		}
+		idx.Add(e)
+	}
+	return nil
+}
+
+// checkOverwrite returns an error if updating the given paths would lose
+// local changes or untracked files.
+func (r *Repository) checkOverwrite(idx *index.Index, removed, changed []string) error {
+	_, dirty, err := r.IsClean(idx, 0)
+	if err != nil {
+		return err
+	}
+	touched := make(map[string]bool)
+	for _, p := range append(removed, changed...) {
+		touched[p] = true
+	}
+	var modified, untracked []string
+	for _, p := range dirty {
+		if touched[p] {
+			modified = append(modified, p)
+		}
+	}
+	ig := r.NewIgnorer()
+	for _, p := range changed {
+		if _, ok := idx.Entry(p, 0); ok {
+			continue
+		}
+		if dir := r.leadingSymlink(p); dir != "" {
+			if _, ok := idx.Entry(dir, 0); !ok {
+				untracked = append(untracked, dir)
+			}
+			continue
+		}
+		info, err := os.Lstat(filepath.Join(r.Worktree, filepath.FromSlash(p)))
+		if err != nil {
+			continue
+		}
+		if !info.IsDir() {
+			if !ig.Ignored(p, false) {
+				untracked = append(untracked, p)
+			}
+			continue
+		}
+		// a directory is replaced if it only has tracked and ignored files
+		files, err := r.WorktreeFiles(p, idx)
+		if err != nil {
+			return err
+		}
+		for _, f := range files {
+			if _, ok := idx.Entry(f, 0); !ok {
+				untracked = append(untracked, f)
+			}
+		}
+	}
+	if len(modified) > 0 {
+		return fmt.Errorf("your local changes to the following files would be overwritten by checkout:\n\t%s\nPlease commit your changes before you switch branches", strings.Join(modified, "\n\t"))
+	}
+	if len(untracked) > 0 {
+		return fmt.Errorf("the following untracked working tree files would be overwritten by checkout:\n\t%s\nPlease move or remove them before you switch branches", strings.Join(untracked, "\n\t"))
+	}
+	return nil
+}
+
+// checkoutFile writes the given tree entry to the worktree at path p and
+// returns its index entry.
+func (r *Repository) checkoutFile(p string, te object.TreeEntry) (index.Entry, error) {
+	if dir := r.leadingSymlink(p); dir != "" {
+		return index.Entry{}, fmt.Errorf("cannot write %s beyond the symbolic link %s", p, dir)
+	}
+	abs := filepath.Join(r.Worktree, filepath.FromSlash(p))
+	// directories in the way only contain ignored files at this point
+	if err := os.RemoveAll(abs); err != nil {
+		return index.Entry{}, errors.Wrapf(err, "error removing %s", p)
+	}
+	if err := os.MkdirAll(filepath.Dir(abs), dirperms); err != nil {
+		return index.Entry{}, errors.Wrapf(err, "error writing %s", p)
+	}
+	if te.Mode == object.ModeSubmodule {
+		if err := os.Mkdir(abs, dirperms); err != nil {
+			return index.Entry{}, errors.Wrapf(err, "error writing %s", p)
+		}
+		return index.Entry{Path: p, SHA: te.SHA, Mode: index.ModeSubmodule}, nil
+	}
+	of, err := r.ReadObject(te.SHA)
+	if err != nil {
+		return index.Entry{}, err
+	}
+	if of.ObjectType != "blob" {
+		return index.Entry{}, fmt.Errorf("%s is a %s, not a blob", p, of.ObjectType)
+	}
+	if te.Mode == object.ModeSymlink {
+		if err := os.Symlink(filepath.FromSlash(string(of.Data)), abs); err != nil {
+			return index.Entry{}, errors.Wrapf(err, "error writing %s", p)
+		}
+	} else {
+		data, err := r.SmudgeContent(p, of.Data)
+		if err != nil {
+			return index.Entry{}, err
+		}
+		perm := os.FileMode(0666)
+		if te.Mode == object.ModeExecutable {
+			perm = 0777
+		}
+		if err := os.WriteFile(abs, data, perm); err != nil {
+			return index.Entry{}, errors.Wrapf(err, "error writing %s", p)
+		}
+	}
+	info, err := os.Lstat(abs)
+	if err != nil {
+		return index.Entry{}, errors.Wrapf(err, "error writing %s", p)
+	}
+	return index.NewEntry(p, te.SHA, info), nil
+}
+
+// leadingSymlink returns the first directory of the worktree path p
+// which is a symbolic link, or the empty string if there is none.
+// Writing through such a link could leave the worktree.
+func (r *Repository) leadingSymlink(p string) string {
+	var dir string
+	for _, c := range strings.Split(path.Dir(p), "/") {
+		if c == "." {
+			break
+		}
+		dir = path.Join(dir, c)
+		info, err := os.Lstat(filepath.Join(r.Worktree, filepath.FromSlash(dir)))
+		if err != nil {
+			break
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return dir
+		}
+	}
+	return ""
+}
+
+// removeEmptyParents removes the directories containing the worktree
+// path p which became empty.
+func (r *Repository) removeEmptyParents(p string) {
+	for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+		if os.Remove(filepath.Join(r.Worktree, filepath.FromSlash(dir))) != nil {
+			return
+		}
+	}
+}
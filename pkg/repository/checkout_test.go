@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sboehler/got/pkg/object"
+)
+
+func TestCheckoutTreeUnsafePaths(t *testing.T) {
+	r := newTestRepo(t)
+	outside := t.TempDir()
+	blob := writeBlob(t, r, "payload\n")
+	link := writeBlob(t, r, outside)
+	nested := writeTestTree(t, r, object.TreeEntry{Mode: object.ModeFile, Name: "escaped", SHA: blob})
+	tests := []struct {
+		name    string
+		entries []object.TreeEntry
+	}{
+		{"parent directory", []object.TreeEntry{{Mode: object.ModeTree, Name: "..", SHA: nested}}},
+		{"current directory", []object.TreeEntry{{Mode: object.ModeTree, Name: ".", SHA: nested}}},
+		{"git directory", []object.TreeEntry{{Mode: object.ModeTree, Name: ".git", SHA: nested}}},
+		{"git directory in other case", []object.TreeEntry{{Mode: object.ModeTree, Name: ".GIT", SHA: nested}}},
+		{"git directory below", []object.TreeEntry{{Mode: object.ModeTree, Name: "dir", SHA: writeTestTree(t, r, object.TreeEntry{Mode: object.ModeTree, Name: ".git", SHA: nested})}}},
+		{"slash", []object.TreeEntry{{Mode: object.ModeFile, Name: "../escaped", SHA: blob}}},
+		{"through a symlink", []object.TreeEntry{
+			{Mode: object.ModeSymlink, Name: "link", SHA: link},
+			{Mode: object.ModeTree, Name: "link", SHA: nested},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tree := writeTestTree(t, r, test.entries...)
+			idx, err := r.ReadIndex()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := r.CheckoutTree(idx, "", tree); err == nil {
+				t.Errorf("checkout of a malicious tree succeeded")
+			}
+			for _, p := range []string{
+				filepath.Join(filepath.Dir(r.Worktree), "escaped"),
+				filepath.Join(r.GitDir, "escaped"),
+				filepath.Join(outside, "escaped"),
+			} {
+				if _, err := os.Lstat(p); !os.IsNotExist(err) {
+					t.Errorf("%s was written", p)
+					os.Remove(p)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckoutTreeThroughWorktreeSymlink(t *testing.T) {
+	r := newTestRepo(t)
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(r.Worktree, "dir")); err != nil {
+		t.Fatal(err)
+	}
+	nested := writeTestTree(t, r, object.TreeEntry{Mode: object.ModeFile, Name: "escaped", SHA: writeBlob(t, r, "payload\n")})
+	tree := writeTestTree(t, r, object.TreeEntry{Mode: object.ModeTree, Name: "dir", SHA: nested})
+	idx, err := r.ReadIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.CheckoutTree(idx, "", tree); err == nil {
+		t.Errorf("checkout through an untracked symlink succeeded")
+	}
+	if _, err := os.Lstat(filepath.Join(outside, "escaped")); !os.IsNotExist(err) {
+		t.Errorf("a file was written outside the worktree")
+	}
+
+	// a tracked symlink is replaced by a directory
+	if err := os.Remove(filepath.Join(r.Worktree, "dir")); err != nil {
+		t.Fatal(err)
+	}
+	linked := writeTestTree(t, r, object.TreeEntry{Mode: object.ModeSymlink, Name: "dir", SHA: writeBlob(t, r, outside)})
+	if err := r.CheckoutTree(idx, "", linked); err != nil {
+		t.Fatal(err)
+	}
+	updateRef(t, r, "refs/heads/master", writeCommit(t, r, linked, "link\n"))
+	if err := r.CheckoutTree(idx, linked, tree); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Lstat(filepath.Join(r.Worktree, "dir", "escaped")); err != nil || !info.Mode().IsRegular() {
+		t.Errorf("got %v, %v for the checked out file", info, err)
+	}
+	if _, err := os.Lstat(filepath.Join(outside, "escaped")); !os.IsNotExist(err) {
+		t.Errorf("a file was written outside the worktree")
+	}
+}
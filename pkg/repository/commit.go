@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/sboehler/got/pkg/object"
+)
+
+// CommitTree writes a commit of the given tree with the given parents
+// and message, using the configured author and committer identities. If
+// signer is not nil, the commit is signed. It returns the SHA of the new
+// commit.
+func (r *Repository) CommitTree(tree string, parents []string, message string, signer Signer) (string, error) {
+	author, err := r.Signature("author")
+	if err != nil {
+		return "", err
+	}
+	return r.CommitTreeAs(author, tree, parents, message, signer)
+}
+
+// CommitTreeAs is like CommitTree, but records the given author instead
+// of the configured one.
+func (r *Repository) CommitTreeAs(author object.Signature, tree string, parents []string, message string, signer Signer) (string, error) {
+	if err := r.checkType(tree, "tree"); err != nil {
+		return "", err
+	}
+	for _, p := range parents {
+		if err := r.checkType(p, "commit"); err != nil {
+			return "", err
+		}
+	}
+	committer, err := r.Signature("committer")
+	if err != nil {
+		return "", err
+	}
+	c := object.NewCommit(tree, parents, author, committer, message)
+	if signer != nil {
+		sig, err := signer.Sign(c.Payload())
+		if err != nil {
+			return "", err
+		}
+		c.Sign(string(sig))
+	}
+	return r.WriteObject(&ObjectFile{ObjectType: "commit", Data: c.Serialize()})
+}
+
+// WriteTag writes an annotated tag with the given name and message for
+// the object with the given SHA, using the configured committer identity
+// as the tagger. It returns the SHA of the tag object.
+func (r *Repository) WriteTag(name, sha, message string) (string, error) {
+	typ, err := r.ObjectType(sha)
+	if err != nil {
+		return "", err
+	}
+	tagger, err := r.Signature("committer")
+	if err != nil {
+		return "", err
+	}
+	return r.WriteTagObject(object.NewTag(sha, typ, name, tagger, message))
+}
+
+// WriteTagObject writes the given annotated tag after checking it with
+// CheckTag. It returns the SHA of the tag object.
+func (r *Repository) WriteTagObject(t *object.Tag) (string, error) {
+	if err := r.CheckTag(t); err != nil {
+		return "", err
+	}
+	return r.WriteObject(&ObjectFile{ObjectType: "tag", Data: t.Serialize()})
+}
+
+// CheckTag returns an error if the type recorded in the tag is not the
+// type of the object it points to.
+func (r *Repository) CheckTag(t *object.Tag) error {
+	typ, err := r.ObjectType(t.Object())
+	if err != nil {
+		return err
+	}
+	if typ != t.TargetType() {
+		return fmt.Errorf("tag %s has type %s, but %s is a %s", t.Name(), t.TargetType(), t.Object(), typ)
+	}
+	return nil
+}
+
+// checkType returns an error if the object with the given SHA does not
+// exist or is not of the given type.
+func (r *Repository) checkType(sha string, typ string) error {
+	of, err := r.ReadObject(sha)
+	if err != nil {
+		return err
+	}
+	if of.ObjectType != typ {
+		return fmt.Errorf("%s is not a valid %s object", sha, typ)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sboehler/got/pkg/object"
+	"github.com/sboehler/got/pkg/timefmt"
+)
+
+func TestCommitTreeDates(t *testing.T) {
+	r := newTestRepo(t)
+	t.Setenv("GIT_AUTHOR_NAME", "Env Author")
+	t.Setenv("GIT_AUTHOR_DATE", "Thu, 07 Apr 2005 22:13:13 +0200")
+	t.Setenv("GIT_COMMITTER_EMAIL", "committer@example.com")
+	t.Setenv("GIT_COMMITTER_DATE", "2005-04-08T10:00:00-07:00")
+	tree := writeTestTree(t, r)
+	c, err := r.ReadCommit(writeCommit(t, r, tree, "env\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.Header("author"), "Env Author <author@example.com> 1112904793 +0200"; got != want {
+		t.Errorf("got author %q, want %q", got, want)
+	}
+	if got, want := c.Header("committer"), "A U Thor <committer@example.com> 1112979600 -0700"; got != want {
+		t.Errorf("got committer %q, want %q", got, want)
+	}
+
+	when, err := timefmt.Parse("2001-02-03 04:05:06 +0100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	author := object.Signature{Name: "Imported", Email: "imported@example.com", When: when}
+	sha, err := r.CommitTreeAs(author, tree, nil, "imported\n", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, err = r.ReadCommit(sha); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.Header("author"), "Imported <imported@example.com> 981169506 +0100"; got != want {
+		t.Errorf("got author %q, want %q", got, want)
+	}
+	if got, want := c.Header("committer"), "A U Thor <committer@example.com> 1112979600 -0700"; got != want {
+		t.Errorf("got committer %q, want %q", got, want)
+	}
+}
+
+func TestRunCommitMsgHook(t *testing.T) {
+	r := newTestRepo(t)
+	if msg, err := r.RunCommitMsgHook("no hook\n"); err != nil || msg != "no hook\n" {
+		t.Errorf("without a hook: got %q, %v", msg, err)
+	}
+
+	hook := r.GitPath("hooks", "commit-msg")
+	if err := os.MkdirAll(filepath.Dir(hook), 0777); err != nil {
+		t.Fatal(err)
+	}
+	script := "#!/bin/sh\ngrep -q reject \"$1\" && exit 1\necho 'Signed-off-by: Hook' >> \"$1\"\n"
+	if err := os.WriteFile(hook, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	msg, err := r.RunCommitMsgHook("subject\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "subject\nSigned-off-by: Hook\n"; msg != want {
+		t.Errorf("got message %q, want %q", msg, want)
+	}
+	if bs, err := os.ReadFile(r.GitPath("COMMIT_EDITMSG")); err != nil || string(bs) != msg {
+		t.Errorf("got COMMIT_EDITMSG %q, %v, want %q", bs, err, msg)
+	}
+	if _, err := r.RunCommitMsgHook("reject me\n"); err == nil {
+		t.Error("RunCommitMsgHook succeeded although the hook failed")
+	}
+}
+
+func TestWriteTagType(t *testing.T) {
+	r := newTestRepo(t)
+	blob := writeBlob(t, r, "content\n")
+	tagger, err := r.Signature("committer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = r.WriteTagObject(object.NewTag(blob, "commit", "v1", tagger, "message\n"))
+	if want := "tag v1 has type commit, but " + blob + " is a blob"; err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %q", err, want)
+	}
+	sha, err := r.WriteTag("v1", blob, "message\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := r.LoadObject(sha, "tag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ := o.(*object.Tag).TargetType(); typ != "blob" {
+		t.Errorf("got type %s, want blob", typ)
+	}
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/sboehler/got/pkg/object"
+)
+
+// autoCRLF returns the value of core.autocrlf, which is one of "true",
+// "input" or "false".
+func (r *Repository) autoCRLF() string {
+	switch v := strings.ToLower(r.ConfigValue("core", "autocrlf")); v {
+	case "true", "input":
+		return v
+	default:
+		return "false"
+	}
+}
+
+// ConvertToGit converts worktree content to the form stored in the
+// repository. If core.autocrlf is true or input, CRLF line endings are
+// converted to LF. Binary content is never converted.
+func (r *Repository) ConvertToGit(data []byte) []byte {
+	if r.autoCRLF() == "false" || object.IsBinary(data) {
+		return data
+	}
+	return bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+}
+
+// ConvertToWorktree converts repository content to the form written to
+// the worktree. If core.autocrlf is true, LF line endings are converted
+// to CRLF. Binary content is never converted.
+func (r *Repository) ConvertToWorktree(data []byte) []byte {
+	if r.autoCRLF() != "true" || object.IsBinary(data) {
+		return data
+	}
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	return bytes.ReplaceAll(data, []byte("\n"), []byte("\r\n"))
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/sboehler/got/pkg/object"
+)
+
+// Fetch copies all objects reachable from the given SHAs from the remote
+// repository which are missing locally. Objects which exist locally are
+// assumed to have their complete history present. To keep this true if
+// the fetch is interrupted, objects are written after all objects they
+// refer to. It returns the number of copied objects.
+func (r *Repository) Fetch(remote *Repository, shas []string) (int, error) {
+	type pending struct {
+		sha  string
+		of   *ObjectFile
+		refs []string
+	}
+	var (
+		stack []pending
+		seen  = make(map[string]bool)
+		n     int
+	)
+	push := func(sha string) error {
+		if seen[sha] || r.HasObject(sha) {
+			return nil
+		}
+		seen[sha] = true
+		of, err := remote.ReadObject(sha)
+		if err != nil {
+			return err
+		}
+		refs, err := references(of)
+		if err != nil {
+			return fmt.Errorf("object %s is corrupt: %v", sha, err)
+		}
+		stack = append(stack, pending{sha: sha, of: of, refs: refs})
+		return nil
+	}
+	for _, sha := range shas {
+		if err := push(sha); err != nil {
+			return n, err
+		}
+		for len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			if len(top.refs) > 0 {
+				ref := top.refs[0]
+				top.refs = top.refs[1:]
+				if err := push(ref); err != nil {
+					return n, err
+				}
+				continue
+			}
+			if _, err := r.WriteObject(top.of); err != nil {
+				return n, err
+			}
+			n++
+			stack = stack[:len(stack)-1]
+		}
+	}
+	return n, nil
+}
+
+// references returns the SHAs of the objects the given object refers to.
+// Submodule commits in trees are skipped, as they live in another
+// repository.
+func references(of *ObjectFile) ([]string, error) {
+	o, err := object.Parse(of.ObjectType, of.Data)
+	if err != nil {
+		return nil, err
+	}
+	var res []string
+	switch o := o.(type) {
+	case *object.Commit:
+		res = append([]string{o.Tree()}, o.Parents()...)
+	case *object.Tag:
+		res = append(res, o.Object())
+	case *object.Tree:
+		for _, e := range o.Entries() {
+			if e.Mode != object.ModeSubmodule {
+				res = append(res, e.SHA)
+			}
+		}
+	}
+	return res, nil
+}
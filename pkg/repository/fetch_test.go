@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFetch(t *testing.T) {
+	remote := newTestRepo(t)
+	shas := linearHistory(t, remote, 3)
+	r := newTestRepo(t)
+	n, err := r.Fetch(remote, shas[1:2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Two commits, two trees and two blobs.
+	if n != 6 {
+		t.Errorf("fetched %d objects, want 6", n)
+	}
+	if n, err = r.Fetch(remote, shas[2:]); err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("fetched %d objects, want 3", n)
+	}
+	reachable, missing, broken := r.Reachable(shas[2:])
+	if len(broken) > 0 {
+		t.Fatal(broken)
+	}
+	if len(reachable) != 9 || len(missing) != 0 {
+		t.Errorf("got %d reachable and %d missing objects, want 9 and 0", len(reachable), len(missing))
+	}
+}
+
+func TestFetchInterrupted(t *testing.T) {
+	remote := newTestRepo(t)
+	shas := linearHistory(t, remote, 3)
+	// Remove the oldest blob, so that the fetch fails part way.
+	c, err := remote.ReadCommit(shas[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := remote.TreeFiles(c.Tree())
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob := files["f"].SHA
+	if err := os.Remove(filepath.Join(remote.ObjectDir(), blob[:2], blob[2:])); err != nil {
+		t.Fatal(err)
+	}
+	r := newTestRepo(t)
+	if _, err := r.Fetch(remote, shas[2:]); err == nil {
+		t.Fatal("fetch with a missing object succeeded")
+	}
+	// Every object written must have its complete closure present.
+	all, err := r.Objects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) == 0 {
+		t.Fatal("no objects were fetched")
+	}
+	_, missing, broken := r.Reachable(all)
+	if len(broken) > 0 {
+		t.Fatal(broken)
+	}
+	if len(missing) != 0 {
+		t.Errorf("objects %v are missing after an interrupted fetch", missing)
+	}
+	for _, sha := range shas {
+		if r.HasObject(sha) {
+			t.Errorf("commit %s was written before its history", sha)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Filter transforms content between the worktree and the repository.
+// Clean is applied when content is added to the repository, Smudge when
+// it is written to the worktree.
+type Filter interface {
+	Clean(io.Reader) io.Reader
+	Smudge(io.Reader) io.Reader
+}
+
+type passthrough struct{}
+
+func (passthrough) Clean(r io.Reader) io.Reader  { return r }
+func (passthrough) Smudge(r io.Reader) io.Reader { return r }
+
+type filterRule struct {
+	pattern string
+	filter  Filter
+}
+
+// AddFilter registers a filter for paths matching the given pattern. A
+// pattern without a slash matches the base name of a path, otherwise it
+// matches the path relative to the worktree root. Filters registered
+// later take precedence.
+func (r *Repository) AddFilter(pattern string, f Filter) {
+	r.filters = append(r.filters, filterRule{pattern, f})
+}
+
+// Filter returns the filter for the given worktree path. Filters
+// registered with AddFilter take precedence over filter drivers assigned
+// in .gitattributes. If no filter matches, a passthrough filter is
+// returned.
+func (r *Repository) Filter(p string) Filter {
+	for i := len(r.filters) - 1; i >= 0; i-- {
+		if matchPattern(r.filters[i].pattern, p) {
+			return r.filters[i].filter
+		}
+	}
+	if driver := r.filterDriver(p); driver != "" {
+		section := `filter "` + driver + `"`
+		return &commandFilter{
+			dir:    r.Worktree,
+			clean:  r.ConfigValue(section, "clean"),
+			smudge: r.ConfigValue(section, "smudge"),
+		}
+	}
+	return passthrough{}
+}
+
+// ConvertsContent returns whether CleanContent or SmudgeContent may change
+// the content at the given path.
+func (r *Repository) ConvertsContent(p string) bool {
+	_, ok := r.Filter(p).(passthrough)
+	return !ok || r.autoCRLF() != "false"
+}
+
+// CleanContent converts worktree content at the given path to the form
+// stored in the repository, applying its filter and line ending
+// conversion.
+func (r *Repository) CleanContent(p string, data []byte) ([]byte, error) {
+	bs, err := io.ReadAll(r.Filter(p).Clean(bytes.NewReader(data)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error filtering %s", p)
+	}
+	return r.ConvertToGit(bs), nil
+}
+
+// SmudgeContent converts repository content to the form written to the
+// worktree at the given path, applying line ending conversion and its
+// filter.
+func (r *Repository) SmudgeContent(p string, data []byte) ([]byte, error) {
+	bs, err := io.ReadAll(r.Filter(p).Smudge(bytes.NewReader(r.ConvertToWorktree(data))))
+	return bs, errors.Wrapf(err, "error filtering %s", p)
+}
+
+// filterDriver returns the name of the filter driver assigned to the
+// given path in the worktree's .gitattributes file.
+func (r *Repository) filterDriver(p string) string {
+	if r.Bare {
+		return ""
+	}
+	f, err := os.Open(filepath.Join(r.Worktree, ".gitattributes"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	var driver string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") || !matchPattern(fields[0], p) {
+			continue
+		}
+		for _, attr := range fields[1:] {
+			switch {
+			case strings.HasPrefix(attr, "filter="):
+				driver = strings.TrimPrefix(attr, "filter=")
+			case attr == "-filter" || attr == "!filter":
+				driver = ""
+			}
+		}
+	}
+	return driver
+}
+
+func matchPattern(pattern, p string) bool {
+	if !strings.Contains(pattern, "/") {
+		p = path.Base(p)
+	}
+	ok, _ := path.Match(strings.TrimPrefix(pattern, "/"), p)
+	return ok
+}
+
+// commandFilter is a filter driver which runs shell commands configured
+// in filter.<driver>.clean and filter.<driver>.smudge.
+type commandFilter struct {
+	dir           string
+	clean, smudge string
+}
+
+func (f *commandFilter) Clean(r io.Reader) io.Reader  { return f.run(f.clean, r) }
+func (f *commandFilter) Smudge(r io.Reader) io.Reader { return f.run(f.smudge, r) }
+
+func (f *commandFilter) run(command string, r io.Reader) io.Reader {
+	if command == "" {
+		return r
+	}
+	pr, pw := io.Pipe()
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Dir = f.dir
+	cmd.Stdin = r
+	cmd.Stdout = pw
+	cmd.Stderr = os.Stderr
+	go func() {
+		pw.CloseWithError(errors.Wrapf(cmd.Run(), "filter %q failed", command))
+	}()
+	return pr
+}
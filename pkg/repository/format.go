@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"strings"
+
+	"github.com/sboehler/got/pkg/object"
+)
+
+// DateFormat is git's default date format, as used by log.
+const DateFormat = "Mon Jan 2 15:04:05 2006 -0700"
+
+// CommitFormat is a parsed commit format template, as accepted by
+// log --format. It is a sequence of literal strings and placeholders.
+type CommitFormat []formatItem
+
+type formatItem struct {
+	literal     string
+	placeholder string
+}
+
+// commitPlaceholders are the placeholders supported in commit formats.
+var commitPlaceholders = []string{"H", "h", "an", "ae", "ad", "cn", "ce", "s", "b", "n", "%"}
+
+// ParseCommitFormat parses a commit format template. The supported
+// placeholders are %H and %h for the full and abbreviated SHA, %an, %ae
+// and %ad for the author name, email and date, %cn and %ce for the
+// committer name and email, %s for the subject, %b for the body, %n for a
+// newline and %% for a percent sign. Unknown placeholders are kept
+// verbatim, as git does.
+func ParseCommitFormat(s string) CommitFormat {
+	var (
+		f   CommitFormat
+		lit strings.Builder
+	)
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '%')
+		if i < 0 {
+			lit.WriteString(s)
+			break
+		}
+		lit.WriteString(s[:i])
+		s = s[i+1:]
+		var placeholder string
+		for _, p := range commitPlaceholders {
+			if strings.HasPrefix(s, p) {
+				placeholder = p
+				break
+			}
+		}
+		switch placeholder {
+		case "":
+			lit.WriteByte('%')
+			continue
+		case "n":
+			lit.WriteByte('\n')
+		case "%":
+			lit.WriteByte('%')
+		default:
+			if lit.Len() > 0 {
+				f = append(f, formatItem{literal: lit.String()})
+				lit.Reset()
+			}
+			f = append(f, formatItem{placeholder: placeholder})
+		}
+		s = s[len(placeholder):]
+	}
+	if lit.Len() > 0 {
+		f = append(f, formatItem{literal: lit.String()})
+	}
+	return f
+}
+
+// FormatCommit renders a commit with the given format.
+func (r *Repository) FormatCommit(f CommitFormat, sha string, c *object.Commit) (string, error) {
+	var b strings.Builder
+	subject, body := splitMessage(c.Message())
+	for _, item := range f {
+		switch item.placeholder {
+		case "":
+			b.WriteString(item.literal)
+		case "H":
+			b.WriteString(sha)
+		case "h":
+			abbrev, err := r.Abbreviate(sha)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(abbrev)
+		case "an":
+			b.WriteString(c.Author().Name)
+		case "ae":
+			b.WriteString(c.Author().Email)
+		case "ad":
+			b.WriteString(c.Author().When.Format(DateFormat))
+		case "cn":
+			b.WriteString(c.Committer().Name)
+		case "ce":
+			b.WriteString(c.Committer().Email)
+		case "s":
+			b.WriteString(subject)
+		case "b":
+			b.WriteString(body)
+		}
+	}
+	return b.String(), nil
+}
+
+// splitMessage splits a commit message into its subject, the first
+// paragraph joined into a single line, and its body.
+func splitMessage(msg string) (string, string) {
+	msg = strings.TrimLeft(msg, "\n")
+	subject, body := msg, ""
+	if i := strings.Index(msg, "\n\n"); i >= 0 {
+		subject, body = msg[:i], strings.TrimLeft(msg[i+2:], "\n")
+	}
+	return strings.Replace(strings.TrimRight(subject, "\n"), "\n", " ", -1), body
+}
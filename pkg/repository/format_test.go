@@ -0,0 +1,54 @@
+package repository
+
+import "testing"
+
+func TestFormatCommit(t *testing.T) {
+	r := newTestRepo(t)
+	tree := writeTestTree(t, r)
+	sha := writeCommit(t, r, tree, "The subject\ncontinued\n\nThe body.\n")
+	c, err := r.ReadCommit(sha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		format, want string
+	}{
+		{"%H", sha},
+		{"%h", sha[:7]},
+		{"%an <%ae>", "A U Thor <author@example.com>"},
+		{"%cn <%ce>", "A U Thor <author@example.com>"},
+		{"%ad", "Sun Sep 13 14:26:40 2020 +0200"},
+		{"%s", "The subject continued"},
+		{"%b", "The body.\n"},
+		{"%h %s%n%b", sha[:7] + " The subject continued\nThe body.\n"},
+		{"100%% %x %", "100% %x %"},
+		{"plain", "plain"},
+		{"", ""},
+	}
+	for _, test := range tests {
+		got, err := r.FormatCommit(ParseCommitFormat(test.format), sha, c)
+		if err != nil {
+			t.Errorf("%q: %v", test.format, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%q: got %q, want %q", test.format, got, test.want)
+		}
+	}
+}
+
+func TestFormatCommitNoBody(t *testing.T) {
+	r := newTestRepo(t)
+	sha := writeCommit(t, r, writeTestTree(t, r), "subject only\n")
+	c, err := r.ReadCommit(sha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := r.FormatCommit(ParseCommitFormat("[%s][%b]"), sha, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[subject only][]"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/sboehler/got/pkg/object"
+)
+
+// CheckObject checks the integrity of the object with the given SHA: it
+// must be readable, hash to its SHA and be well-formed.
+func (r *Repository) CheckObject(sha string) error {
+	of, err := r.ReadObject(sha)
+	if err != nil {
+		return err
+	}
+	if hash := Hash(of); hash != sha {
+		return fmt.Errorf("object %s is corrupt: hash mismatch, got %s", sha, hash)
+	}
+	if err := object.Validate(of.ObjectType, of.Data); err != nil {
+		return fmt.Errorf("object %s is corrupt: %v", sha, err)
+	}
+	return nil
+}
+
+// References returns the SHAs of the objects referenced by the object
+// with the given SHA.
+func (r *Repository) References(sha string) ([]string, error) {
+	of, err := r.ReadObject(sha)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := references(of)
+	if err != nil {
+		return nil, fmt.Errorf("object %s is corrupt: %v", sha, err)
+	}
+	return refs, nil
+}
+
+// Reachable returns the set of objects reachable from the given SHAs.
+// Objects which are referenced but missing are returned separately, as
+// are the errors of objects which cannot be read; the traversal
+// continues past them.
+func (r *Repository) Reachable(shas []string) (map[string]bool, []string, map[string]error) {
+	var (
+		reachable = make(map[string]bool)
+		missing   []string
+		broken    = make(map[string]error)
+		stack     = append([]string(nil), shas...)
+	)
+	for len(stack) > 0 {
+		sha := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if reachable[sha] {
+			continue
+		}
+		reachable[sha] = true
+		if !r.HasObject(sha) {
+			missing = append(missing, sha)
+			continue
+		}
+		refs, err := r.References(sha)
+		if err != nil {
+			broken[sha] = err
+			continue
+		}
+		stack = append(stack, refs...)
+	}
+	return reachable, missing, broken
+}
+
+// Roots returns the SHAs of the objects referenced by HEAD, all refs and
+// all reflog entries, from which reachability is computed.
+func (r *Repository) Roots() ([]string, error) {
+	var res []string
+	if sha, err := r.ResolveRef("HEAD"); err == nil {
+		res = append(res, sha)
+	}
+	refs, err := r.Refs()
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range refs {
+		res = append(res, ref.SHA)
+	}
+	logs, err := r.Reflogs()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range logs {
+		entries, err := r.ReadReflog(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if e.New != ZeroSHA {
+				res = append(res, e.New)
+			}
+		}
+	}
+	return res, nil
+}
+
+// ZeroSHA is the all-zero SHA, which denotes a missing object in reflogs
+// and ref updates.
+const ZeroSHA = "0000000000000000000000000000000000000000"
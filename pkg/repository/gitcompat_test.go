@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/sboehler/got/pkg/object"
+)
+
+// These tests compare got against the git binary and are skipped if git
+// is not installed.
+
+// gitEnv is the environment for running git in tests, isolated from the
+// user's configuration and with a fixed identity and date.
+func gitEnv(t *testing.T) []string {
+	return append(os.Environ(),
+		"HOME="+t.TempDir(),
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_AUTHOR_NAME=A U Thor",
+		"GIT_AUTHOR_EMAIL=author@example.com",
+		"GIT_AUTHOR_DATE=1600000000 +0200",
+		"GIT_COMMITTER_NAME=C O Mitter",
+		"GIT_COMMITTER_EMAIL=committer@example.com",
+		"GIT_COMMITTER_DATE=1600000000 +0200",
+	)
+}
+
+// runGit runs git in dir with the given stdin and returns its trimmed
+// output.
+func runGit(t *testing.T, dir, stdin string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = gitEnv(t)
+	cmd.Stdin = strings.NewReader(stdin)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, stderr.String())
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func requireGit(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+}
+
+// gitObject returns the type and raw content of an object as read by
+// git.
+func gitObject(t *testing.T, dir, sha string) (string, []byte) {
+	t.Helper()
+	typ := runGit(t, dir, "", "cat-file", "-t", sha)
+	cmd := exec.Command("git", "cat-file", typ, sha)
+	cmd.Dir = dir
+	cmd.Env = gitEnv(t)
+	data, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("git cat-file %s %s: %v", typ, sha, err)
+	}
+	return typ, data
+}
+
+// checkReadsLikeGit checks that got reads the object with the same type
+// and content as git.
+func checkReadsLikeGit(t *testing.T, r *Repository, sha string) {
+	t.Helper()
+	typ, data := gitObject(t, r.Worktree, sha)
+	of, err := r.ReadObject(sha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if of.ObjectType != typ || !bytes.Equal(of.Data, data) {
+		t.Errorf("object %s: got %s %q, git reads %s %q", sha, of.ObjectType, of.Data, typ, data)
+	}
+	if _, err := object.Parse(of.ObjectType, of.Data); err != nil {
+		t.Errorf("object %s: %v", sha, err)
+	}
+}
+
+func TestReadGitObjects(t *testing.T) {
+	requireGit(t)
+	dir := t.TempDir()
+	runGit(t, dir, "", "init", "-q")
+	blob := runGit(t, dir, "blob content\n", "hash-object", "-w", "--stdin")
+	script := runGit(t, dir, "#!/bin/sh\n", "hash-object", "-w", "--stdin")
+	link := runGit(t, dir, "target", "hash-object", "-w", "--stdin")
+	sub := runGit(t, dir, fmt.Sprintf("100644 blob %s\tnested\n", blob), "mktree")
+	tree := runGit(t, dir, fmt.Sprintf("100644 blob %s\tfile\n100755 blob %s\tscript\n120000 blob %s\tlink\n040000 tree %s\tdir\n", blob, script, link, sub), "mktree")
+	c1 := runGit(t, dir, "first\n", "commit-tree", tree)
+	c2 := runGit(t, dir, "second\n", "commit-tree", tree)
+	merge := runGit(t, dir, "merge\n\nwith a body\n", "commit-tree", tree, "-p", c1, "-p", c2)
+	runGit(t, dir, "", "tag", "-a", "-m", "version 1", "v1", merge)
+	tag := runGit(t, dir, "", "rev-parse", "v1")
+
+	r, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for _, sha := range []string{blob, script, link, sub, tree, c1, c2, merge, tag} {
+		checkReadsLikeGit(t, r, sha)
+	}
+
+	tr, err := r.ReadTree(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []string
+	for _, e := range tr.Entries() {
+		entries = append(entries, fmt.Sprintf("%06s %s %s\t%s", e.Mode, e.Type(), e.SHA, e.Name))
+	}
+	if want := runGit(t, dir, "", "ls-tree", tree); strings.Join(entries, "\n") != want {
+		t.Errorf("got tree entries\n%s\ngit lists\n%s", strings.Join(entries, "\n"), want)
+	}
+
+	c, err := r.ReadCommit(merge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parents := strings.Join(c.Parents(), " "); parents != c1+" "+c2 {
+		t.Errorf("got parents %s, want %s %s", parents, c1, c2)
+	}
+	if c.Tree() != tree || c.Message() != "merge\n\nwith a body\n" {
+		t.Errorf("got tree %s and message %q", c.Tree(), c.Message())
+	}
+	if a := c.Author(); a.Name != "A U Thor" || a.Email != "author@example.com" || a.When.Unix() != 1600000000 {
+		t.Errorf("got author %v", a)
+	}
+	if got, want := c.Committer().String(), "C O Mitter <committer@example.com> 1600000000 +0200"; got != want {
+		t.Errorf("got committer %s, want %s", got, want)
+	}
+
+	o, err := r.LoadObject(tag, "tag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tg := o.(*object.Tag)
+	if tg.Object() != merge || tg.TargetType() != "commit" || tg.Name() != "v1" || tg.Message() != "version 1\n" {
+		t.Errorf("got tag of %s %s named %s with message %q", tg.TargetType(), tg.Object(), tg.Name(), tg.Message())
+	}
+	if got := tg.Tagger().String(); got != "C O Mitter <committer@example.com> 1600000000 +0200" {
+		t.Errorf("got tagger %s", got)
+	}
+	if got, err := r.Resolve("v1^{tree}"); err != nil || got != tree {
+		t.Errorf("Resolve(v1^{tree}) = %s, %v, want %s", got, err, tree)
+	}
+}
+
+func TestGitReadsObjects(t *testing.T) {
+	requireGit(t)
+	r := newTestRepo(t)
+	blob := writeBlob(t, r, "blob content\n")
+	script := writeBlob(t, r, "#!/bin/sh\n")
+	link := writeBlob(t, r, "target")
+	sub := writeTestTree(t, r, object.TreeEntry{Mode: object.ModeFile, Name: "nested", SHA: blob})
+	tree := writeTestTree(t, r,
+		object.TreeEntry{Mode: object.ModeFile, Name: "file", SHA: blob},
+		object.TreeEntry{Mode: object.ModeExecutable, Name: "script", SHA: script},
+		object.TreeEntry{Mode: object.ModeSymlink, Name: "link", SHA: link},
+		object.TreeEntry{Mode: object.ModeTree, Name: "dir", SHA: sub},
+		// sorts after "dir" only because trees sort with a trailing slash
+		object.TreeEntry{Mode: object.ModeFile, Name: "dir.txt", SHA: blob},
+	)
+	c1 := writeCommit(t, r, tree, "first\n")
+	c2 := writeCommit(t, r, sub, "second\n")
+	merge := writeCommit(t, r, tree, "merge\n", c1, c2)
+	tag, err := r.WriteTag("v1", merge, "version 1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	updateRef(t, r, "refs/heads/master", merge)
+	updateRef(t, r, "refs/tags/v1", tag)
+
+	dir := r.Worktree
+	for sha, want := range map[string]string{blob: "blob", sub: "tree", tree: "tree", merge: "commit", tag: "tag"} {
+		typ, data := gitObject(t, dir, sha)
+		if typ != want {
+			t.Errorf("git reads %s as %s, want %s", sha, typ, want)
+		}
+		if got := runGit(t, dir, string(data), "hash-object", "-t", typ, "--stdin"); got != sha {
+			t.Errorf("git hashes the %s as %s, got wrote %s", typ, got, sha)
+		}
+		checkReadsLikeGit(t, r, sha)
+	}
+	if parents := runGit(t, dir, "", "rev-parse", merge+"^1", merge+"^2"); parents != c1+"\n"+c2 {
+		t.Errorf("git reads parents %q, want %s and %s", parents, c1, c2)
+	}
+	if target := runGit(t, dir, "", "rev-parse", "v1^{commit}"); target != merge {
+		t.Errorf("git peels v1 to %s, want %s", target, merge)
+	}
+	runGit(t, dir, "", "fsck", "--strict", "--no-dangling")
+}
+
+func TestIndexLikeGit(t *testing.T) {
+	requireGit(t)
+	r := newTestRepo(t)
+	writeWorktreeFile(t, r, "a", "a\n")
+	writeWorktreeFile(t, r, "dir/b", "b\n")
+	writeWorktreeFile(t, r, "dir/sub/c", "c\n")
+	commitWorktree(t, r, "a", "dir/b", "dir/sub/c")
+
+	dir := r.Worktree
+	lsFiles := func() string {
+		idx, err := r.ReadIndex()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var lines []string
+		for _, e := range idx.Entries {
+			lines = append(lines, fmt.Sprintf("%06o %s %d\t%s", e.Mode, e.SHA, e.Stage, e.Path))
+		}
+		return strings.Join(lines, "\n")
+	}
+	if got, want := lsFiles(), runGit(t, dir, "", "ls-files", "--stage"); got != want {
+		t.Errorf("got index entries\n%s\ngit lists\n%s", got, want)
+	}
+	if out := runGit(t, dir, "", "status", "--porcelain"); out != "" {
+		t.Errorf("git status after commit:\n%s", out)
+	}
+
+	// read an index written by git, in each version git supports
+	writeWorktreeFile(t, r, "dir/d", "d\n")
+	runGit(t, dir, "", "add", "dir/d")
+	for _, version := range []string{"2", "3", "4"} {
+		runGit(t, dir, "", "update-index", "--index-version", version)
+		if got, want := lsFiles(), runGit(t, dir, "", "ls-files", "--stage"); got != want {
+			t.Errorf("version %s: got index entries\n%s\ngit lists\n%s", version, got, want)
+		}
+	}
+}
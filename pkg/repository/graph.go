@@ -0,0 +1,133 @@
+package repository
+
+import "strings"
+
+// Graph draws the commit graph of a walk as ASCII art, as git log --graph
+// does. Each column, or lane, of the graph waits for a commit, and the
+// commits must be added children first.
+type Graph struct {
+	lanes []string
+}
+
+// GraphLines are the graph prefixes for the output of a commit: Row is
+// the prefix of its first line, which marks the commit with a '*'.
+// Connectors draw the edges of merges and of lanes joining, and prefix
+// the following lines; further lines are prefixed with Padding. All
+// prefixes have the same width.
+type GraphLines struct {
+	Row        string
+	Connectors []string
+	Padding    string
+}
+
+// Add adds the next commit with the given parents to the graph and
+// returns the prefixes for its output.
+func (g *Graph) Add(sha string, parents []string) GraphLines {
+	col := -1
+	for i, lane := range g.lanes {
+		if lane == sha {
+			col = i
+			break
+		}
+	}
+	if col < 0 {
+		g.lanes = append(g.lanes, sha)
+		col = len(g.lanes) - 1
+	}
+	var res GraphLines
+	res.Row = g.rails(func(i int) byte {
+		if i == col {
+			return '*'
+		}
+		return '|'
+	})
+	if len(parents) > 2 {
+		// octopus merges are marked as git does
+		i := 2*col + 1
+		res.Row = res.Row[:i] + strings.Repeat("-.", len(parents)-2) + res.Row[i:]
+	}
+
+	// The commit's lane continues with its first parent, and further
+	// parents open new lanes to its right.
+	lanes := append(append(append([]string(nil), g.lanes[:col]...), parents...), g.lanes[col+1:]...)
+	switch {
+	case len(parents) == 0 && col < len(lanes):
+		// lanes to the right of a root commit move left
+		line := []byte(strings.Repeat(" ", 2*len(g.lanes)-1))
+		for i := range lanes {
+			if i < col {
+				line[2*i] = '|'
+			} else {
+				line[2*i+1] = '/'
+			}
+		}
+		res.Connectors = append(res.Connectors, string(line))
+	case len(parents) > 1:
+		line := []byte(strings.Repeat(" ", 2*len(lanes)-1))
+		for i := range lanes {
+			if i <= col {
+				line[2*i] = '|'
+			} else {
+				line[2*i-1] = '\\'
+			}
+		}
+		res.Connectors = append(res.Connectors, string(line))
+	}
+	g.lanes = lanes
+
+	// Lanes waiting for a commit which an earlier lane waits for as well
+	// join that lane, moving left by one column per line.
+	for j := 1; j < len(g.lanes); j++ {
+		i := 0
+		for i < j && g.lanes[i] != g.lanes[j] {
+			i++
+		}
+		if i == j {
+			continue
+		}
+		line := []byte(strings.Repeat(" ", 2*len(g.lanes)-1))
+		for k := range g.lanes {
+			if k < j {
+				line[2*k] = '|'
+			} else {
+				line[2*k-1] = '/'
+			}
+		}
+		res.Connectors = append(res.Connectors, string(line))
+		g.lanes = append(g.lanes[:j], g.lanes[j+1:]...)
+		for k := j - 1; k > i; k-- {
+			line := []byte(g.rails(func(int) byte { return '|' }))
+			line[2*k-1] = '/'
+			res.Connectors = append(res.Connectors, string(line))
+		}
+		j--
+	}
+	res.Padding = g.rails(func(int) byte { return '|' })
+
+	width := len(res.Row)
+	for _, s := range append(res.Connectors, res.Padding) {
+		if len(s) > width {
+			width = len(s)
+		}
+	}
+	pad := func(s string) string {
+		return s + strings.Repeat(" ", width-len(s))
+	}
+	res.Row, res.Padding = pad(res.Row), pad(res.Padding)
+	for i, c := range res.Connectors {
+		res.Connectors[i] = pad(c)
+	}
+	return res
+}
+
+// rails draws the current lanes with the given character for each lane.
+func (g *Graph) rails(char func(i int) byte) string {
+	if len(g.lanes) == 0 {
+		return ""
+	}
+	line := []byte(strings.Repeat(" ", 2*len(g.lanes)-1))
+	for i := range g.lanes {
+		line[2*i] = char(i)
+	}
+	return string(line)
+}
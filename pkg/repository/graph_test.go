@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGraph(t *testing.T) {
+	type commit struct {
+		sha     string
+		parents string
+	}
+	tests := []struct {
+		name    string
+		commits []commit
+		want    string
+	}{
+		{
+			name:    "linear",
+			commits: []commit{{"c", "b"}, {"b", "a"}, {"a", ""}},
+			want:    "* c\n* b\n* a\n",
+		},
+		{
+			name: "merge",
+			commits: []commit{
+				{"m", "c3 s1"}, {"s1", "c1"}, {"c3", "c2"}, {"c2", "c1"}, {"c1", ""},
+			},
+			want: "*   m\n|\\\n| * s1\n* | c3\n* | c2\n|/\n* c1\n",
+		},
+		{
+			name: "two roots",
+			commits: []commit{
+				{"m", "a b"}, {"a", ""}, {"b", ""},
+			},
+			want: "*   m\n|\\\n* | a\n /\n* b\n",
+		},
+		{
+			name: "join across lanes",
+			commits: []commit{
+				{"m2", "c4 t1"}, {"t1", "c3"}, {"c4", "m1"}, {"m1", "c3 s2"}, {"s2", "c1"}, {"c3", "c1"}, {"c1", ""},
+			},
+			want: "*   m2\n|\\\n| * t1\n* | c4\n* |   m1\n|\\ \\\n| |/\n|/|\n| * s2\n* | c3\n|/\n* c1\n",
+		},
+		{
+			name: "octopus",
+			commits: []commit{
+				{"o", "a b c"}, {"c", "a"}, {"b", "a"}, {"a", ""},
+			},
+			want: "*-.   o\n|\\ \\\n| | * c\n| |/\n|/|\n| * b\n|/\n* a\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				g   Graph
+				out strings.Builder
+			)
+			for _, c := range test.commits {
+				lines := g.Add(c.sha, strings.Fields(c.parents))
+				for _, l := range lines.Connectors {
+					if len(l) != len(lines.Row) {
+						t.Errorf("connector %q is not as wide as row %q", l, lines.Row)
+					}
+				}
+				out.WriteString(lines.Row + " " + c.sha + "\n")
+				for _, l := range lines.Connectors {
+					out.WriteString(strings.TrimRight(l, " ") + "\n")
+				}
+			}
+			if got := out.String(); got != test.want {
+				t.Errorf("got graph\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// HeadState describes what HEAD points to.
+type HeadState int
+
+const (
+	// HeadBranch means HEAD points to an existing branch.
+	HeadBranch HeadState = iota
+	// HeadUnborn means HEAD points to a branch which does not exist yet,
+	// as is the case before the first commit.
+	HeadUnborn
+	// HeadDetached means HEAD points directly to an existing commit.
+	HeadDetached
+	// HeadDangling means HEAD points directly to a missing object or to
+	// an object which is not a commit.
+	HeadDangling
+)
+
+func (s HeadState) String() string {
+	switch s {
+	case HeadBranch:
+		return "branch"
+	case HeadUnborn:
+		return "unborn branch"
+	case HeadDetached:
+		return "detached"
+	case HeadDangling:
+		return "dangling detached"
+	default:
+		return fmt.Sprintf("HeadState(%d)", int(s))
+	}
+}
+
+// CheckHead returns the state of HEAD and the branch or SHA it points
+// to. An error is returned if HEAD is missing or malformed.
+func (r *Repository) CheckHead() (HeadState, string, error) {
+	content, err := r.ReadRef("HEAD")
+	if err != nil {
+		return 0, "", err
+	}
+	if strings.HasPrefix(content, symrefPrefix) {
+		target := strings.TrimPrefix(content, symrefPrefix)
+		if !strings.HasPrefix(target, "refs/heads/") {
+			return 0, "", fmt.Errorf("HEAD is corrupt: points to %s outside of refs/heads", target)
+		}
+		if err := ValidateRefName(target); err != nil {
+			return 0, "", errors.Wrap(err, "HEAD is corrupt")
+		}
+		if _, err := r.ResolveRef(target); err != nil {
+			if errors.Cause(err) == ErrRefNotFound {
+				return HeadUnborn, target, nil
+			}
+			return 0, "", err
+		}
+		return HeadBranch, target, nil
+	}
+	if len(content) != 40 || !isHex(content) {
+		return 0, "", fmt.Errorf("HEAD is corrupt: %q is neither a SHA nor a symbolic ref", content)
+	}
+	if !r.HasObject(content) {
+		return HeadDangling, content, nil
+	}
+	typ, _, err := r.ReadObjectHeader(content)
+	if err != nil {
+		return 0, "", err
+	}
+	if typ != "commit" {
+		return HeadDangling, content, nil
+	}
+	return HeadDetached, content, nil
+}
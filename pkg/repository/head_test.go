@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/sboehler/got/pkg/object"
+)
+
+func TestCheckHeadDetached(t *testing.T) {
+	r := newTestRepo(t)
+	blob := writeBlob(t, r, "a\n")
+	tree := writeTestTree(t, r, object.TreeEntry{Mode: object.ModeFile, Name: "a", SHA: blob})
+	commit := writeCommit(t, r, tree, "first\n")
+	missing := "0123456789012345678901234567890123456789"
+
+	check := func(sha string, want HeadState) {
+		t.Helper()
+		if err := r.UpdateRef("HEAD", sha); err != nil {
+			t.Fatal(err)
+		}
+		state, got, err := r.CheckHead()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state != want || got != sha {
+			t.Errorf("HEAD at %s: got %v %s, want %v", sha, state, got, want)
+		}
+	}
+	check(commit, HeadDetached)
+	check(tree, HeadDangling)
+	check(missing, HeadDangling)
+
+	// packed objects are checked as well
+	packLooseObjects(t, r, blob, tree, commit)
+	check(commit, HeadDetached)
+	check(tree, HeadDangling)
+}
+
+func TestCheckHeadSymbolic(t *testing.T) {
+	r := newTestRepo(t)
+	state, branch, err := r.CheckHead()
+	if err != nil || state != HeadUnborn || branch != "refs/heads/"+DefaultBranch {
+		t.Fatalf("new repository: got %v %s, %v, want an unborn default branch", state, branch, err)
+	}
+	updateRef(t, r, branch, writeCommit(t, r, writeTestTree(t, r), "first\n"))
+	if state, got, err := r.CheckHead(); err != nil || state != HeadBranch || got != branch {
+		t.Errorf("after the first commit: got %v %s, %v, want branch %s", state, got, err, branch)
+	}
+}
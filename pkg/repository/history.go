@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sboehler/got/pkg/object"
+)
+
+// TreeChange is a file which differs between two trees. Old is the zero
+// entry for added files, New the zero entry for deleted files.
+type TreeChange struct {
+	Path     string
+	Old, New object.TreeEntry
+}
+
+// Status returns 'A', 'D' or 'M' for added, deleted and modified files,
+// and 'T' for files whose type changed, e.g. from a file to a symlink.
+func (c TreeChange) Status() byte {
+	switch {
+	case c.Old.SHA == "":
+		return 'A'
+	case c.New.SHA == "":
+		return 'D'
+	case modeKind(c.Old.Mode) != modeKind(c.New.Mode):
+		return 'T'
+	default:
+		return 'M'
+	}
+}
+
+// modeKind returns the mode of an entry with the executable bit cleared,
+// which identifies the kind of file it is.
+func modeKind(mode string) string {
+	if mode == object.ModeExecutable {
+		return object.ModeFile
+	}
+	return mode
+}
+
+// DiffTrees returns the files at or below the given paths which differ
+// between the trees from and to, sorted by path. Either tree may be empty.
+// If no paths are given, all files are compared.
+func (r *Repository) DiffTrees(from, to string, paths ...string) ([]TreeChange, error) {
+	files := func(tree string) (map[string]object.TreeEntry, error) {
+		if tree == "" {
+			return nil, nil
+		}
+		return r.TreeFiles(tree)
+	}
+	oldFiles, err := files(from)
+	if err != nil {
+		return nil, err
+	}
+	newFiles, err := files(to)
+	if err != nil {
+		return nil, err
+	}
+	inPaths := func(p string) bool {
+		if len(paths) == 0 {
+			return true
+		}
+		for _, dir := range paths {
+			if InPath(p, dir) {
+				return true
+			}
+		}
+		return false
+	}
+	var res []TreeChange
+	for p, e := range oldFiles {
+		if n := newFiles[p]; n != e && inPaths(p) {
+			res = append(res, TreeChange{Path: p, Old: e, New: n})
+		}
+	}
+	for p, e := range newFiles {
+		if _, ok := oldFiles[p]; !ok && inPaths(p) {
+			res = append(res, TreeChange{Path: p, New: e})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Path < res[j].Path })
+	return res, nil
+}
+
+// DiffTreeEntries returns the entries which differ between the trees from
+// and to, sorted by name, without descending into subtrees. Either tree
+// may be empty. An entry which changes between a tree and another type
+// is returned as a deletion followed by an addition.
+func (r *Repository) DiffTreeEntries(from, to string) ([]TreeChange, error) {
+	entries := func(tree string) (map[string]object.TreeEntry, error) {
+		res := make(map[string]object.TreeEntry)
+		if tree == "" {
+			return res, nil
+		}
+		t, err := r.ReadTree(tree)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range t.Entries() {
+			res[e.Name] = e
+		}
+		return res, nil
+	}
+	oldEntries, err := entries(from)
+	if err != nil {
+		return nil, err
+	}
+	newEntries, err := entries(to)
+	if err != nil {
+		return nil, err
+	}
+	var res []TreeChange
+	for name, e := range oldEntries {
+		n, ok := newEntries[name]
+		switch {
+		case !ok:
+			res = append(res, TreeChange{Path: name, Old: e})
+		case (e.Mode == object.ModeTree) != (n.Mode == object.ModeTree):
+			res = append(res, TreeChange{Path: name, Old: e}, TreeChange{Path: name, New: n})
+		case n != e:
+			res = append(res, TreeChange{Path: name, Old: e, New: n})
+		}
+	}
+	for name, e := range newEntries {
+		if _, ok := oldEntries[name]; !ok {
+			res = append(res, TreeChange{Path: name, New: e})
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Path < res[j].Path })
+	return res, nil
+}
+
+// lookupPath returns the entry at the slash-separated path p in the given
+// tree, and whether it exists. The empty path names the tree itself.
+func (r *Repository) lookupPath(tree, p string) (object.TreeEntry, bool, error) {
+	e := object.TreeEntry{Mode: object.ModeTree, SHA: tree}
+	for _, name := range strings.Split(p, "/") {
+		if name == "" {
+			continue
+		}
+		if e.Mode != object.ModeTree {
+			return object.TreeEntry{}, false, nil
+		}
+		t, err := r.readPathTree(e.SHA)
+		if err != nil {
+			return object.TreeEntry{}, false, err
+		}
+		var ok bool
+		if e, ok = t.Entry(name); !ok {
+			return object.TreeEntry{}, false, nil
+		}
+	}
+	return e, true, nil
+}
+
+// UseTreeCache makes path lookups, as in HEAD:dir/file, keep up to n
+// trees in memory, so that looking up many paths in the same tree reads
+// the trees they share only once. The cache is dropped when it is full.
+func (r *Repository) UseTreeCache(n int) {
+	r.trees = make(map[string]*object.Tree)
+	r.maxTrees = n
+}
+
+// readPathTree reads a tree for a path lookup, using the tree cache if
+// it is enabled.
+func (r *Repository) readPathTree(sha string) (*object.Tree, error) {
+	if t, ok := r.trees[sha]; ok {
+		return t, nil
+	}
+	t, err := r.ReadTree(sha)
+	if err != nil || r.maxTrees == 0 {
+		return t, err
+	}
+	if len(r.trees) >= r.maxTrees {
+		r.trees = make(map[string]*object.Tree)
+	}
+	r.trees[sha] = t
+	return t, nil
+}
+
+// changesPaths returns whether the commit changes any of the given paths,
+// or files below them, compared to its first parent. Root commits change
+// all paths which exist in their tree.
+func (r *Repository) changesPaths(c *object.Commit, paths []string) (bool, error) {
+	var parentTree string
+	if parents := c.Parents(); len(parents) > 0 {
+		p, err := r.ReadCommit(parents[0])
+		if err != nil {
+			return false, err
+		}
+		parentTree = p.Tree()
+	}
+	for _, p := range paths {
+		e, ok, err := r.lookupPath(c.Tree(), p)
+		if err != nil {
+			return false, err
+		}
+		if parentTree == "" {
+			if ok {
+				return true, nil
+			}
+			continue
+		}
+		pe, pok, err := r.lookupPath(parentTree, p)
+		if err != nil {
+			return false, err
+		}
+		if ok != pok || e.SHA != pe.SHA || e.Mode != pe.Mode {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// HookPath returns the path of the hook with the given name, honoring
+// core.hooksPath.
+func (r *Repository) HookPath(name string) string {
+	if dir := r.ConfigValue("core", "hooksPath"); dir != "" {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(r.topDir(), dir)
+		}
+		return filepath.Join(dir, name)
+	}
+	return r.GitPath("hooks", name)
+}
+
+// topDir returns the worktree, or the git directory of a bare repository.
+func (r *Repository) topDir() string {
+	if r.Bare {
+		return r.GitDir
+	}
+	return r.Worktree
+}
+
+// RunHook runs the hook with the given name and arguments, if it exists
+// and is executable. The hook inherits stdin, stdout and stderr, and an
+// error is returned if it exits with a non-zero status.
+func (r *Repository) RunHook(name string, args ...string) error {
+	p := r.HookPath(name)
+	fi, err := os.Stat(p)
+	if err != nil || fi.IsDir() || fi.Mode()&0111 == 0 {
+		return nil
+	}
+	cmd := exec.Command(p, args...)
+	cmd.Dir = r.topDir()
+	cmd.Env = append(os.Environ(), "GIT_DIR="+r.GitDir)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return errors.Wrapf(cmd.Run(), "hook %s failed", name)
+}
+
+// RunCommitMsgHook writes the commit message to COMMIT_EDITMSG and runs
+// the commit-msg hook with the path of that file. It returns the message
+// as left in the file by the hook, which may edit it.
+func (r *Repository) RunCommitMsgHook(msg string) (string, error) {
+	p := r.GitPath("COMMIT_EDITMSG")
+	if err := r.writeFile(p, strings.NewReader(msg)); err != nil {
+		return "", errors.Wrap(err, "error writing commit message")
+	}
+	if err := r.RunHook("commit-msg", p); err != nil {
+		return "", err
+	}
+	bs, err := os.ReadFile(p)
+	return string(bs), errors.Wrap(err, "error reading commit message")
+}
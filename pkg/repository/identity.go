@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sboehler/got/pkg/object"
+	"github.com/sboehler/got/pkg/timefmt"
+)
+
+// Identity returns the name and email of the given role, which is either
+// "author" or "committer". The GIT_AUTHOR_* and GIT_COMMITTER_* environment
+// variables take precedence over user.name and user.email.
+func (r *Repository) Identity(role string) (string, string, error) {
+	if role != "author" && role != "committer" {
+		return "", "", fmt.Errorf("invalid identity role %s", role)
+	}
+	prefix := "GIT_" + strings.ToUpper(role) + "_"
+	name := os.Getenv(prefix + "NAME")
+	if name == "" {
+		name = r.ConfigValue("user", "name")
+	}
+	email := os.Getenv(prefix + "EMAIL")
+	if email == "" {
+		email = r.ConfigValue("user", "email")
+	}
+	if email == "" {
+		email = os.Getenv("EMAIL")
+	}
+	if name == "" || email == "" {
+		return "", "", fmt.Errorf(`%s identity unknown
+
+*** Please tell me who you are.
+
+Run
+
+  git config user.email "you@example.com"
+  git config user.name "Your Name"
+
+to set the identity of this repository.`, strings.ToUpper(role[:1])+role[1:])
+	}
+	return name, email, nil
+}
+
+// Signature returns the signature of the given role at the current time,
+// or at the time in GIT_AUTHOR_DATE or GIT_COMMITTER_DATE, if set.
+func (r *Repository) Signature(role string) (object.Signature, error) {
+	name, email, err := r.Identity(role)
+	if err != nil {
+		return object.Signature{}, err
+	}
+	when := time.Now()
+	if date := os.Getenv("GIT_" + strings.ToUpper(role) + "_DATE"); date != "" {
+		if when, err = timefmt.Parse(date); err != nil {
+			return object.Signature{}, errors.Wrapf(err, "invalid %s date", role)
+		}
+	}
+	return object.Signature{Name: name, Email: email, When: when}, nil
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIdentity(t *testing.T) {
+	r := newTestRepo(t)
+	for _, v := range []string{"GIT_AUTHOR_NAME", "GIT_AUTHOR_EMAIL", "GIT_COMMITTER_NAME", "GIT_COMMITTER_EMAIL", "EMAIL"} {
+		t.Setenv(v, "")
+	}
+	check := func(role, wantName, wantEmail string) {
+		t.Helper()
+		name, email, err := r.Identity(role)
+		if err != nil || name != wantName || email != wantEmail {
+			t.Errorf("Identity(%s) = %q, %q, %v, want %q, %q", role, name, email, err, wantName, wantEmail)
+		}
+	}
+	check("author", "A U Thor", "author@example.com")
+	check("committer", "A U Thor", "author@example.com")
+
+	// the environment of a role takes precedence over the configuration
+	t.Setenv("GIT_AUTHOR_NAME", "Env Author")
+	t.Setenv("GIT_COMMITTER_EMAIL", "committer@example.com")
+	check("author", "Env Author", "author@example.com")
+	check("committer", "A U Thor", "committer@example.com")
+
+	// EMAIL is only used if user.email is unset
+	t.Setenv("EMAIL", "fallback@example.com")
+	check("author", "Env Author", "author@example.com")
+	r.Config.Section("user").DeleteKey("email")
+	check("author", "Env Author", "fallback@example.com")
+	check("committer", "A U Thor", "committer@example.com")
+
+	t.Setenv("EMAIL", "")
+	r.Config.Section("user").DeleteKey("name")
+	for _, role := range []string{"author", "committer"} {
+		if _, _, err := r.Identity(role); err == nil || !strings.Contains(err.Error(), "*** Please tell me who you are.") {
+			t.Errorf("got error %v for an unknown %s identity", err, role)
+		}
+	}
+	if _, _, err := r.Identity("tagger"); err == nil {
+		t.Errorf("Identity accepted an invalid role")
+	}
+}
@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreRule is a pattern from a .gitignore file.
+type ignoreRule struct {
+	// base is the path key of the directory of the ignore file.
+	base    string
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+	// anchored patterns match the path relative to base, others match
+	// the base name.
+	anchored bool
+}
+
+func (rule ignoreRule) match(p string, isDir bool) bool {
+	if rule.dirOnly && !isDir || !InPath(p, rule.base) || p == rule.base {
+		return false
+	}
+	if rule.anchored {
+		if rule.base != "" {
+			p = p[len(rule.base)+1:]
+		}
+		return rule.re.MatchString(p)
+	}
+	return rule.re.MatchString(path.Base(p))
+}
+
+// Ignorer decides which worktree paths are ignored according to the
+// .gitignore files in the worktree, .git/info/exclude and the file
+// configured in core.excludesFile. Patterns match case-insensitively if
+// core.ignorecase is set.
+type Ignorer struct {
+	r     *Repository
+	fold  bool
+	rules map[string][]ignoreRule
+	cache map[string]bool
+}
+
+// NewIgnorer creates an Ignorer for the repository's worktree.
+func (r *Repository) NewIgnorer() *Ignorer {
+	ig := &Ignorer{
+		r:     r,
+		fold:  r.IgnoreCase(),
+		rules: make(map[string][]ignoreRule),
+		cache: make(map[string]bool),
+	}
+	var global []ignoreRule
+	if p := r.ConfigValue("core", "excludesfile"); p != "" {
+		if strings.HasPrefix(p, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				p = filepath.Join(home, p[2:])
+			}
+		}
+		global = append(global, readIgnoreFile(p, "", ig.fold)...)
+	}
+	global = append(global, readIgnoreFile(r.GitPath("info", "exclude"), "", ig.fold)...)
+	ig.rules[""] = append(global, readIgnoreFile(filepath.Join(r.Worktree, ".gitignore"), "", ig.fold)...)
+	return ig
+}
+
+// Ignored returns whether the worktree path p, relative to the worktree
+// root, is ignored. Paths in ignored directories are always ignored.
+func (ig *Ignorer) Ignored(p string, isDir bool) bool {
+	if p == "" {
+		return false
+	}
+	key := p
+	if isDir {
+		key += "/"
+	}
+	if res, ok := ig.cache[key]; ok {
+		return res
+	}
+	dir := path.Dir(p)
+	if dir == "." {
+		dir = ""
+	}
+	res := dir != "" && ig.Ignored(dir, true)
+	if !res {
+		for d := dir; ; d = path.Dir(d) {
+			if d == "." {
+				d = ""
+			}
+			if matched, negate := ig.matchIn(d, p, isDir); matched {
+				res = !negate
+				break
+			}
+			if d == "" {
+				break
+			}
+		}
+	}
+	ig.cache[key] = res
+	return res
+}
+
+// matchIn matches p against the rules of the .gitignore file in dir, the
+// last matching rule taking precedence.
+func (ig *Ignorer) matchIn(dir, p string, isDir bool) (bool, bool) {
+	rules, ok := ig.rules[dir]
+	if !ok {
+		rules = readIgnoreFile(filepath.Join(ig.r.Worktree, filepath.FromSlash(dir), ".gitignore"), ig.r.PathKey(dir), ig.fold)
+		ig.rules[dir] = rules
+	}
+	key := ig.r.PathKey(p)
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].match(key, isDir) {
+			return true, rules[i].negate
+		}
+	}
+	return false, false
+}
+
+// readIgnoreFile reads the rules of the ignore file at path p, whose
+// patterns are relative to the worktree directory with the path key base.
+// If fold is set, the patterns match case-insensitively.
+func readIgnoreFile(p string, base string, fold bool) []ignoreRule {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var res []ignoreRule
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if rule, ok := parseIgnoreRule(s.Text(), base, fold); ok {
+			res = append(res, rule)
+		}
+	}
+	return res
+}
+
+func parseIgnoreRule(line string, base string, fold bool) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+	rule := ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		rule.negate, line = true, line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly, line = true, strings.TrimRight(line, "/")
+	}
+	rule.anchored = strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	expr := "^" + globToRegexp(line) + "$"
+	if fold {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if line == "" || err != nil {
+		return ignoreRule{}, false
+	}
+	rule.re = re
+	return rule, true
+}
+
+// globToRegexp translates a gitignore glob into a regular expression.
+func globToRegexp(glob string) string {
+	var sb strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			sb.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sboehler/got/pkg/index"
+)
+
+// IndexPath returns the path of the index file, which can be overridden
+// with the GIT_INDEX_FILE environment variable.
+func (r *Repository) IndexPath() string {
+	if p := os.Getenv("GIT_INDEX_FILE"); p != "" {
+		return p
+	}
+	return r.GitPath("index")
+}
+
+// ReadIndex reads the index. If there is no index file, an empty index is
+// returned.
+func (r *Repository) ReadIndex() (*index.Index, error) {
+	if err := r.RequireWorktree(); err != nil {
+		return nil, err
+	}
+	bs, err := os.ReadFile(r.IndexPath())
+	if os.IsNotExist(err) {
+		return index.New(), nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading index")
+	}
+	idx, err := index.Parse(bs)
+	return idx, errors.Wrap(err, "index is corrupt")
+}
+
+// WriteIndex writes the index. The index file is locked while it is
+// written and replaced atomically. Entries of files modified no earlier
+// than the index is written are racily clean: the file may change again
+// within the timestamp granularity without a change of its stat data. As
+// in git, their size is smudged so that the file is compared by content
+// the next time.
+func (r *Repository) WriteIndex(idx *index.Index) error {
+	if err := r.RequireWorktree(); err != nil {
+		return err
+	}
+	p := r.IndexPath()
+	if r.dryRun("write %s", p) {
+		return nil
+	}
+	lock := p + ".lock"
+	f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("cannot lock index: %s exists", lock)
+		}
+		return errors.Wrap(err, "error locking index")
+	}
+	if info, err := f.Stat(); err == nil {
+		smudgeRacyEntries(idx, info.ModTime())
+	}
+	w := bufio.NewWriter(f)
+	err = idx.Write(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(lock, p)
+	}
+	if err != nil {
+		os.Remove(lock)
+		return errors.Wrap(err, "error writing index")
+	}
+	return nil
+}
+
+// smudgeRacyEntries sets the size of the entries modified at or after the
+// given time to zero, which makes their stat data stale.
+func smudgeRacyEntries(idx *index.Index, t time.Time) {
+	for i := range idx.Entries {
+		e := &idx.Entries[i]
+		if e.Stage == 0 && e.Mode != index.ModeSubmodule && !e.MTime.Before(t) {
+			e.Size = 0
+		}
+	}
+}
+
+// indexTime returns the modification time of the index file in
+// nanoseconds, or 0 if there is no index.
+func (r *Repository) indexTime() int64 {
+	info, err := os.Stat(r.IndexPath())
+	if err != nil {
+		return 0
+	}
+	return info.ModTime().UnixNano()
+}
@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/sboehler/got/pkg/object"
+	"github.com/sboehler/got/pkg/pack"
+)
+
+// writeTruncatedBlob writes a loose blob whose header is valid but whose
+// data is shorter than the header claims, so that only reading the header
+// succeeds.
+func writeTruncatedBlob(t *testing.T, r *Repository, sha string) {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write([]byte("blob 100\x00short"))
+	zw.Close()
+	p := filepath.Join(r.ObjectDir(), sha[:2], sha[2:])
+	if err := os.MkdirAll(filepath.Dir(p), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, buf.Bytes(), 0444); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// packLooseObjects moves the given loose objects into a new pack.
+func packLooseObjects(t testing.TB, r *Repository, shas ...string) {
+	t.Helper()
+	var buf bytes.Buffer
+	pw, err := pack.NewWriter(&buf, len(shas))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sha := range shas {
+		of, err := r.ReadObject(sha)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := pw.Add(sha, of.ObjectType, of.Data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sum, err := pw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var idx bytes.Buffer
+	if err := pack.WriteIndex(&idx, pw.Entries(), sum); err != nil {
+		t.Fatal(err)
+	}
+	base := filepath.Join(r.ObjectDir(), "pack", fmt.Sprintf("pack-%x", sum))
+	if err := os.MkdirAll(filepath.Dir(base), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(base+".pack", buf.Bytes(), 0444); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(base+".idx", idx.Bytes(), 0444); err != nil {
+		t.Fatal(err)
+	}
+	for _, sha := range shas {
+		if err := os.Remove(filepath.Join(r.ObjectDir(), sha[:2], sha[2:])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.ReloadPacks()
+}
+
+func TestForEachObject(t *testing.T) {
+	r := newTestRepo(t)
+	blob := writeBlob(t, r, "a\n")
+	tree := writeTestTree(t, r, object.TreeEntry{Mode: object.ModeFile, Name: "a", SHA: blob})
+	commit := writeCommit(t, r, tree, "first\n")
+	want := map[string]string{blob: "blob", tree: "tree", commit: "commit"}
+	var got []string
+	err := r.ForEachObject(func(sha, typ string) error {
+		if typ != want[sha] {
+			t.Errorf("object %s: got type %q, want %q", sha, typ, want[sha])
+		}
+		got = append(got, sha)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) || !sort.StringsAreSorted(got) {
+		t.Errorf("got objects %v, want the %d objects in sorted order", got, len(want))
+	}
+
+	stop := errors.New("stop")
+	var calls int
+	err = r.ForEachObject(func(sha, typ string) error {
+		calls++
+		return stop
+	})
+	if err != stop || calls != 1 {
+		t.Errorf("got %v after %d calls, want the callback's error after 1 call", err, calls)
+	}
+}
+
+func TestForEachObjectOfType(t *testing.T) {
+	r := newTestRepo(t)
+	blob := writeBlob(t, r, "a\n")
+	tree := writeTestTree(t, r, object.TreeEntry{Mode: object.ModeFile, Name: "a", SHA: blob})
+	c1 := writeCommit(t, r, tree, "first\n")
+	c2 := writeCommit(t, r, tree, "second\n", c1)
+	// Reading the data of this blob fails, so classifying it must only
+	// read its header.
+	truncated := strings.Repeat("f", 40)
+	writeTruncatedBlob(t, r, truncated)
+	if _, err := r.ReadObject(truncated); err == nil {
+		t.Fatal("reading the truncated blob succeeded")
+	}
+	var got []string
+	err := r.ForEachObjectOfType("commit", func(sha string) error {
+		got = append(got, sha)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{c1, c2}
+	sort.Strings(want)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got commits %v, want %v", got, want)
+	}
+}
+
+func TestReloadPacks(t *testing.T) {
+	r := newTestRepo(t)
+	blob := writeBlob(t, r, "packed\n")
+	if packs, err := r.PackIndexes(); err != nil || len(packs) != 0 {
+		t.Fatalf("got %d packs, %v", len(packs), err)
+	}
+	packLooseObjects(t, r, blob)
+	if packs, err := r.PackIndexes(); err != nil || len(packs) != 1 {
+		t.Fatalf("got %d packs after reloading, %v", len(packs), err)
+	}
+	of, err := r.ReadObject(blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(of.Data) != "packed\n" {
+		t.Errorf("got %q", of.Data)
+	}
+}
+
+// packedTestRepo returns a repository with the given number of packs of
+// n blobs each, and the SHAs of the blobs.
+func packedTestRepo(t testing.TB, packs, n int) (*Repository, []string) {
+	t.Helper()
+	r := newTestRepo(t)
+	var all []string
+	for i := 0; i < packs; i++ {
+		var shas []string
+		for j := 0; j < n; j++ {
+			shas = append(shas, writeBlob(t, r, fmt.Sprintf("pack %d blob %d\n", i, j)))
+		}
+		packLooseObjects(t, r, shas...)
+		all = append(all, shas...)
+	}
+	return r, all
+}
+
+func TestBloomFilter(t *testing.T) {
+	r, shas := packedTestRepo(t, 3, 50)
+	loose := writeBlob(t, r, "loose\n")
+	r.UseBloomFilter()
+	for _, sha := range append(shas, loose) {
+		if !r.HasObject(sha) {
+			t.Errorf("HasObject(%s) = false for an existing object", sha)
+		}
+	}
+	written := writeBlob(t, r, "written after the filter was built\n")
+	if !r.HasObject(written) {
+		t.Errorf("HasObject(%s) = false for an object written after the filter was built", written)
+	}
+	packLooseObjects(t, r, loose, written)
+	if !r.HasObject(loose) || !r.HasObject(written) {
+		t.Errorf("objects are missing after packing them")
+	}
+	var falsePositives int
+	for i := 0; i < 1000; i++ {
+		sha := Hash(&ObjectFile{ObjectType: "blob", Data: []byte(fmt.Sprintf("missing %d\n", i))})
+		if r.HasObject(sha) {
+			t.Errorf("HasObject(%s) = true for a missing object", sha)
+		}
+		if r.bloom.mayContain(sha) {
+			falsePositives++
+		}
+	}
+	if falsePositives > 50 {
+		t.Errorf("got %d false positives in 1000 lookups", falsePositives)
+	}
+}
+
+func BenchmarkHasObjectMissing(b *testing.B) {
+	missing := strings.Repeat("f", 40)
+	for _, bloom := range []bool{false, true} {
+		b.Run(fmt.Sprintf("bloom=%v", bloom), func(b *testing.B) {
+			r, _ := packedTestRepo(b, 16, 20)
+			if bloom {
+				r.UseBloomFilter()
+			}
+			r.HasObject(missing)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if r.HasObject(missing) {
+					b.Fatal("found a missing object")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// RelPath converts a path given relative to the current directory into
+// a slash-separated path relative to the root of the worktree, as used
+// for index entries. It returns an error if the path is outside the
+// worktree.
+func (r *Repository) RelPath(path string) (string, error) {
+	if err := r.RequireWorktree(); err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid path %s", path)
+	}
+	rel, err := filepath.Rel(r.Worktree, abs)
+	if err != nil {
+		return "", errors.Wrapf(err, "invalid path %s", path)
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside repository at %s", path, r.Worktree)
+	}
+	if rel == "." {
+		return "", nil
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// IgnoreCase returns whether paths are compared case-insensitively,
+// configured by core.ignorecase.
+func (r *Repository) IgnoreCase() bool {
+	return strings.EqualFold(r.ConfigValue("core", "ignorecase"), "true")
+}
+
+// PathKey returns the key under which the given path is compared with
+// other paths. If core.ignorecase is set, paths differing only in case
+// have the same key. The key is not meant to be stored; stored paths
+// keep their original case.
+func (r *Repository) PathKey(p string) string {
+	if r.IgnoreCase() {
+		return strings.ToLower(p)
+	}
+	return p
+}
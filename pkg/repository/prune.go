@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// requiredDirs are the directories of the repository layout which are
+// kept even if empty.
+var requiredDirs = [][]string{
+	{"objects"},
+	{"objects", "info"},
+	{"objects", "pack"},
+	{"refs"},
+	{"refs", "heads"},
+	{"refs", "tags"},
+}
+
+// PruneEmptyDirs removes empty directories below objects/ and refs/,
+// keeping the directories required by the repository layout.
+func (r *Repository) PruneEmptyDirs() error {
+	keep := make(map[string]bool)
+	for _, dir := range requiredDirs {
+		keep[r.GitPath(dir...)] = true
+	}
+	for _, root := range []string{r.ObjectDir(), r.GitPath("refs")} {
+		if _, err := r.pruneEmptyDirs(root, keep); err != nil {
+			return errors.Wrap(err, "error pruning empty directories")
+		}
+	}
+	return nil
+}
+
+// pruneEmptyDirs removes empty directories below and including dir,
+// except those in keep. It returns whether dir was removed.
+func (r *Repository) pruneEmptyDirs(dir string, keep map[string]bool) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	empty := true
+	for _, e := range entries {
+		if !e.IsDir() {
+			empty = false
+			continue
+		}
+		removed, err := r.pruneEmptyDirs(filepath.Join(dir, e.Name()), keep)
+		if err != nil {
+			return false, err
+		}
+		empty = empty && removed
+	}
+	if !empty || keep[dir] {
+		return false, nil
+	}
+	if r.dryRun("remove %s", dir) {
+		return true, nil
+	}
+	return true, os.Remove(dir)
+}
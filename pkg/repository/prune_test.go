@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPruneEmptyDirs(t *testing.T) {
+	r := newTestRepo(t)
+	sha := writeBlob(t, r, "a\n")
+	updateRef(t, r, "refs/heads/topic/a", writeCommit(t, r, writeTestTree(t, r), "commit\n"))
+	if err := os.Remove(filepath.Join(r.ObjectDir(), sha[:2], sha[2:])); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(r.GitPath("refs", "heads", "topic", "a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.PruneEmptyDirs(); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{filepath.Join(r.ObjectDir(), sha[:2]), r.GitPath("refs", "heads", "topic")} {
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			t.Errorf("empty directory %s was not removed", dir)
+		}
+	}
+	for _, dir := range []string{r.ObjectDir(), r.GitPath("refs", "heads"), r.GitPath("refs", "tags")} {
+		if _, err := os.Stat(dir); err != nil {
+			t.Errorf("required directory was removed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// ObjectReader reads loose objects, reusing its decompression state and
+// buffers across calls. It is intended for reading many objects in a row.
+type ObjectReader struct {
+	r   *Repository
+	zr  io.ReadCloser
+	br  *bufio.Reader
+	buf bytes.Buffer
+}
+
+// NewObjectReader creates a new object reader.
+func (r *Repository) NewObjectReader() *ObjectReader {
+	return &ObjectReader{r: r}
+}
+
+// Read reads the object with the given SHA. The returned data is only
+// valid until the next call to Read.
+func (or *ObjectReader) Read(sha string) (*ObjectFile, error) {
+	if len(sha) != 40 {
+		return nil, fmt.Errorf("invalid object name %s", sha)
+	}
+	p, ok := or.r.objectPath(sha)
+	if !ok {
+		return or.r.ReadObject(sha)
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error loading object %s", sha)
+	}
+	defer f.Close()
+	if or.zr == nil {
+		or.zr, err = zlib.NewReader(f)
+	} else {
+		err = or.zr.(zlib.Resetter).Reset(f, nil)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "object %s is corrupt", sha)
+	}
+	if or.br == nil {
+		or.br = bufio.NewReader(or.zr)
+	} else {
+		or.br.Reset(or.zr)
+	}
+	ot, size, err := readHeader(or.br)
+	if err != nil {
+		return nil, errors.Wrapf(err, "object %s is corrupt", sha)
+	}
+	or.buf.Reset()
+	if _, err := or.buf.ReadFrom(or.br); err != nil {
+		return nil, errors.Wrapf(err, "object %s is corrupt", sha)
+	}
+	if int64(or.buf.Len()) != size {
+		return nil, fmt.Errorf("object %s is corrupt: len(data) == %d, want %d", sha, or.buf.Len(), size)
+	}
+	return &ObjectFile{ObjectType: ot, Data: or.buf.Bytes()}, nil
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sboehler/got/pkg/object"
+	"github.com/sboehler/got/pkg/timefmt"
+)
+
+// ReflogEntry is an entry in the reflog of a ref.
+type ReflogEntry struct {
+	Old, New string
+	Time     time.Time
+	Message  string
+
+	line string
+}
+
+// ReadReflog reads the reflog of the given ref, oldest entry first.
+func (r *Repository) ReadReflog(name string) ([]ReflogEntry, error) {
+	f, err := os.Open(r.GitPath("logs", name))
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading reflog of %s", name)
+	}
+	defer f.Close()
+	var res []ReflogEntry
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		e, err := parseReflogEntry(s.Text())
+		if err != nil {
+			return nil, errors.Wrapf(err, "reflog of %s is corrupt", name)
+		}
+		res = append(res, e)
+	}
+	return res, errors.Wrapf(s.Err(), "error reading reflog of %s", name)
+}
+
+func parseReflogEntry(line string) (ReflogEntry, error) {
+	e := ReflogEntry{line: line}
+	head := line
+	if i := strings.IndexByte(line, '\t'); i >= 0 {
+		head, e.Message = line[:i], line[i+1:]
+	}
+	fields := strings.Fields(head)
+	if len(fields) < 4 || len(fields[0]) != 40 || len(fields[1]) != 40 {
+		return e, fmt.Errorf("invalid reflog line %q", line)
+	}
+	e.Old, e.New = fields[0], fields[1]
+	t, err := timefmt.ParseRaw(strings.Join(fields[len(fields)-2:], " "))
+	if err != nil {
+		return e, errors.Wrapf(err, "invalid reflog line %q", line)
+	}
+	e.Time = t
+	return e, nil
+}
+
+// Reflogs returns the names of all refs which have a reflog.
+func (r *Repository) Reflogs() ([]string, error) {
+	root := r.GitPath("logs")
+	var res []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		name, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		res = append(res, filepath.ToSlash(name))
+		return nil
+	})
+	return res, errors.Wrap(err, "error reading reflogs")
+}
+
+// ExpireReflog removes the entries of the reflog of the given ref which
+// are older than expire, and those older than expireUnreachable whose
+// commit is not reachable from the current value of the ref. The most
+// recent entry is always kept. It returns the number of removed entries.
+func (r *Repository) ExpireReflog(name string, expire, expireUnreachable time.Time) (int, error) {
+	entries, err := r.ReadReflog(name)
+	if err != nil || len(entries) == 0 {
+		return 0, err
+	}
+	var reachable map[string]bool
+	if expireUnreachable.After(expire) {
+		tip, err := r.ResolveRef(name)
+		if err != nil {
+			return 0, err
+		}
+		if reachable, err = r.reachableCommits(tip); err != nil {
+			return 0, err
+		}
+	}
+	var (
+		buf     bytes.Buffer
+		removed int
+		last    = len(entries) - 1
+	)
+	for i, e := range entries {
+		if i < last && (e.Time.Before(expire) || reachable != nil && !reachable[e.New] && e.Time.Before(expireUnreachable)) {
+			removed++
+			continue
+		}
+		buf.WriteString(e.line)
+		buf.WriteByte('\n')
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+	err = r.writeFile(r.GitPath("logs", name), &buf)
+	return removed, errors.Wrapf(err, "error writing reflog of %s", name)
+}
+
+// IsAncestor returns whether commit a is an ancestor of, or equal to,
+// commit b.
+func (r *Repository) IsAncestor(a, b string) (bool, error) {
+	reachable, err := r.reachableCommits(b)
+	if err != nil {
+		return false, err
+	}
+	return reachable[a], nil
+}
+
+// reachableCommits returns the set of commits reachable from the given
+// commit.
+func (r *Repository) reachableCommits(sha string) (map[string]bool, error) {
+	var (
+		res   = make(map[string]bool)
+		stack = []string{sha}
+	)
+	for len(stack) > 0 {
+		sha := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if res[sha] {
+			continue
+		}
+		of, err := r.ReadObject(sha)
+		if err != nil {
+			return nil, err
+		}
+		if of.ObjectType != "commit" {
+			continue
+		}
+		var c object.Commit
+		if err := c.Deserialize(of.Data); err != nil {
+			return nil, errors.Wrapf(err, "object %s is corrupt", sha)
+		}
+		res[sha] = true
+		stack = append(stack, c.Parents()...)
+	}
+	return res, nil
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeReflog writes a reflog for the given ref with an entry for each
+// commit, at the given Unix times.
+func writeReflog(t *testing.T, r *Repository, name string, shas []string, times []int64) {
+	t.Helper()
+	var b strings.Builder
+	old := ZeroSHA
+	for i, sha := range shas {
+		fmt.Fprintf(&b, "%s %s A U Thor <author@example.com> %d +0000\tentry %d\n", old, sha, times[i], i)
+		old = sha
+	}
+	p := r.GitPath("logs", name)
+	if err := os.MkdirAll(filepath.Dir(p), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// reflogMessages returns the messages of the reflog of the given ref.
+func reflogMessages(t *testing.T, r *Repository, name string) []string {
+	t.Helper()
+	entries, err := r.ReadReflog(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res []string
+	for _, e := range entries {
+		res = append(res, e.Message)
+	}
+	return res
+}
+
+func TestExpireReflog(t *testing.T) {
+	r := newTestRepo(t)
+	tree := writeTestTree(t, r)
+	c1 := writeCommit(t, r, tree, "one\n")
+	c2 := writeCommit(t, r, tree, "two\n", c1)
+	c3 := writeCommit(t, r, tree, "three\n", c2)
+	side := writeCommit(t, r, tree, "side\n", c1)
+	updateRef(t, r, "refs/heads/master", c3)
+	const name = "refs/heads/master"
+	shas := []string{c1, side, c2, c3}
+	times := []int64{1000, 2000, 3000, 4000}
+
+	tests := []struct {
+		name                      string
+		expire, expireUnreachable int64
+		removed                   int
+		want                      string
+	}{
+		{"nothing old", 500, 500, 0, "entry 0,entry 1,entry 2,entry 3"},
+		{"old entries", 2500, 2500, 2, "entry 2,entry 3"},
+		{"keeps most recent", 5000, 5000, 3, "entry 3"},
+		{"unreachable", 500, 2500, 1, "entry 0,entry 2,entry 3"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			writeReflog(t, r, name, shas, times)
+			removed, err := r.ExpireReflog(name, time.Unix(test.expire, 0), time.Unix(test.expireUnreachable, 0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(reflogMessages(t, r, name), ","); removed != test.removed || got != test.want {
+				t.Errorf("removed %d entries leaving %s, want %d leaving %s", removed, got, test.removed, test.want)
+			}
+		})
+	}
+}
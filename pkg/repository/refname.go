@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidateRefName checks the given ref name against git's ref name rules,
+// as implemented by git check-ref-format.
+func ValidateRefName(name string) error {
+	switch {
+	case name == "" || name == "@":
+		return fmt.Errorf("invalid ref name %q", name)
+	case strings.HasPrefix(name, "-"):
+		return fmt.Errorf("invalid ref name %q: must not start with a dash", name)
+	case strings.HasSuffix(name, "/") || strings.HasSuffix(name, "."):
+		return fmt.Errorf("invalid ref name %q: must not end with a slash or dot", name)
+	case strings.Contains(name, ".."):
+		return fmt.Errorf("invalid ref name %q: must not contain ..", name)
+	case strings.Contains(name, "@{"):
+		return fmt.Errorf("invalid ref name %q: must not contain @{", name)
+	}
+	for _, c := range name {
+		if c < 0x20 || c == 0x7f || strings.ContainsRune(" ~^:?*[\\", c) {
+			return fmt.Errorf("invalid ref name %q: must not contain %q", name, c)
+		}
+	}
+	for _, comp := range strings.Split(name, "/") {
+		switch {
+		case comp == "":
+			return fmt.Errorf("invalid ref name %q: must not contain empty components", name)
+		case strings.HasPrefix(comp, "."):
+			return fmt.Errorf("invalid ref name %q: components must not start with a dot", name)
+		case strings.HasSuffix(comp, ".lock"):
+			return fmt.Errorf("invalid ref name %q: components must not end with .lock", name)
+		}
+	}
+	return nil
+}
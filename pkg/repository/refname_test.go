@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateRefName(t *testing.T) {
+	tests := []struct {
+		name, err string
+	}{
+		{name: "refs/heads/master"},
+		{name: "refs/heads/feature/x-1"},
+		{name: "refs/tags/v1.0"},
+		{name: "HEAD"},
+		{name: "refs/heads/a@b"},
+		{name: "refs/heads/café"},
+		{name: "", err: "invalid ref name"},
+		{name: "@", err: "invalid ref name"},
+		{name: "-refs/heads/x", err: "must not start with a dash"},
+		{name: "refs/heads/x/", err: "must not end with a slash or dot"},
+		{name: "refs/heads/x.", err: "must not end with a slash or dot"},
+		{name: "refs/heads/a..b", err: "must not contain .."},
+		{name: "refs/heads/a@{1}", err: "must not contain @{"},
+		{name: "refs/heads/a b", err: "must not contain ' '"},
+		{name: "refs/heads/a~1", err: "must not contain '~'"},
+		{name: "refs/heads/a^", err: "must not contain '^'"},
+		{name: "refs/heads/a:b", err: "must not contain ':'"},
+		{name: "refs/heads/a?", err: "must not contain '?'"},
+		{name: "refs/heads/a*", err: "must not contain '*'"},
+		{name: "refs/heads/a[b", err: "must not contain '['"},
+		{name: "refs/heads/a\\b", err: "must not contain '\\\\'"},
+		{name: "refs/heads/a\tb", err: "must not contain '\\t'"},
+		{name: "refs/heads/a\x7fb", err: "must not contain '\\x7f'"},
+		{name: "refs//heads", err: "must not contain empty components"},
+		{name: "refs/heads/.hidden", err: "components must not start with a dot"},
+		{name: "refs/heads/x.lock", err: "components must not end with .lock"},
+		{name: "refs/heads/x.lock/y", err: "components must not end with .lock"},
+	}
+	for _, test := range tests {
+		err := ValidateRefName(test.name)
+		if test.err == "" {
+			if err != nil {
+				t.Errorf("ValidateRefName(%q): %v", test.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("ValidateRefName(%q): got error %v, want %q", test.name, err, test.err)
+		}
+	}
+}
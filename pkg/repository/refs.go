@@ -0,0 +1,266 @@
+package repository
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrRefNotFound is returned when a ref does not exist.
+var ErrRefNotFound = errors.New("ref not found")
+
+// ErrNotSymbolicRef is returned when a ref holds a SHA where a symbolic
+// ref is expected, as is the case for a detached HEAD.
+var ErrNotSymbolicRef = errors.New("not a symbolic ref")
+
+const symrefPrefix = "ref: "
+
+// ReadRef reads the raw content of the ref with the given name. The result
+// is either a SHA or a symbolic reference of the form "ref: <name>".
+func (r *Repository) ReadRef(name string) (string, error) {
+	bs, err := os.ReadFile(r.GitPath(name))
+	if err == nil {
+		return strings.TrimSpace(string(bs)), nil
+	}
+	if !os.IsNotExist(err) {
+		return "", errors.Wrapf(err, "error reading ref %s", name)
+	}
+	packed, err := r.PackedRefs()
+	if err != nil {
+		return "", err
+	}
+	if sha, ok := packed[name]; ok {
+		return sha, nil
+	}
+	return "", errors.Wrapf(ErrRefNotFound, "%s", name)
+}
+
+// ResolveRef resolves the ref with the given name to a SHA, following
+// symbolic refs.
+func (r *Repository) ResolveRef(name string) (string, error) {
+	for i := 0; i < 10; i++ {
+		content, err := r.ReadRef(name)
+		if err != nil {
+			return "", err
+		}
+		if !strings.HasPrefix(content, symrefPrefix) {
+			return content, nil
+		}
+		name = strings.TrimPrefix(content, symrefPrefix)
+	}
+	return "", fmt.Errorf("too many levels of symbolic refs at %s", name)
+}
+
+// SymbolicRef returns the name of the ref the symbolic ref with the given
+// name points to.
+func (r *Repository) SymbolicRef(name string) (string, error) {
+	content, err := r.ReadRef(name)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(content, symrefPrefix) {
+		return "", errors.Wrapf(ErrNotSymbolicRef, "%s", name)
+	}
+	return strings.TrimPrefix(content, symrefPrefix), nil
+}
+
+// UpdateRef sets the ref with the given name to the given SHA. The name
+// must be a valid ref name.
+func (r *Repository) UpdateRef(name string, sha string) error {
+	if err := ValidateRefName(name); err != nil {
+		return err
+	}
+	if r.dryRun("update %s to %s", name, sha) {
+		return nil
+	}
+	p := r.GitPath(name)
+	if err := os.MkdirAll(filepath.Dir(p), dirperms); err != nil {
+		return errors.Wrapf(err, "error updating ref %s", name)
+	}
+	err := r.writeFile(p, strings.NewReader(sha+"\n"))
+	return errors.Wrapf(err, "error updating ref %s", name)
+}
+
+// SetSymbolicRef makes the ref with the given name a symbolic ref
+// pointing to target. If msg is not empty, the change of the value of
+// the ref is recorded in its reflog.
+func (r *Repository) SetSymbolicRef(name, target, msg string) error {
+	for _, n := range []string{name, target} {
+		if err := ValidateRefName(n); err != nil {
+			return err
+		}
+	}
+	if r.dryRun("point %s to %s", name, target) {
+		return nil
+	}
+	old, _ := r.ResolveRef(name)
+	err := r.writeFile(r.GitPath(name), strings.NewReader(symrefPrefix+target+"\n"))
+	if err != nil {
+		return errors.Wrapf(err, "error updating ref %s", name)
+	}
+	if new, err := r.ResolveRef(target); msg != "" && err == nil {
+		return r.appendReflog(name, old, new, msg)
+	}
+	return nil
+}
+
+// PackedRefs reads the refs stored in the packed-refs file.
+func (r *Repository) PackedRefs() (map[string]string, error) {
+	res := make(map[string]string)
+	f, err := os.Open(r.GitPath("packed-refs"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return res, nil
+		}
+		return nil, errors.Wrap(err, "error reading packed-refs")
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if len(line) == 0 || line[0] == '#' || line[0] == '^' {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid packed-refs line %q", line)
+		}
+		res[fields[1]] = fields[0]
+	}
+	return res, errors.Wrap(s.Err(), "error reading packed-refs")
+}
+
+// RenameRef renames the ref oldName to newName, which must not exist,
+// and moves its reflog. Symbolic refs pointing to oldName, such as HEAD,
+// are not updated.
+func (r *Repository) RenameRef(oldName, newName, msg string) error {
+	sha, err := r.ResolveRef(oldName)
+	if err != nil {
+		return err
+	}
+	log, err := os.ReadFile(r.GitPath("logs", oldName))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "error reading reflog of %s", oldName)
+	}
+	tx := r.NewRefTransaction()
+	tx.Message = msg
+	if err := tx.Delete(oldName, sha); err != nil {
+		return err
+	}
+	if err := tx.Update(newName, sha, ZeroSHA); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if len(log) == 0 || r.ReadOnly {
+		return nil
+	}
+	p := r.GitPath("logs", newName)
+	entry, err := os.ReadFile(p)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "error reading reflog of %s", newName)
+	}
+	err = r.writeFile(p, bytes.NewReader(append(log, entry...)))
+	return errors.Wrapf(err, "error writing reflog of %s", newName)
+}
+
+// writePackedRefsWithout writes the packed-refs file to p, leaving out
+// the given refs and their peeled values.
+func (r *Repository) writePackedRefsWithout(p string, drop map[string]bool) error {
+	bs, err := os.ReadFile(r.GitPath("packed-refs"))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(err, "error reading packed-refs")
+	}
+	var (
+		buf     bytes.Buffer
+		dropped bool
+	)
+	for _, line := range strings.SplitAfter(string(bs), "\n") {
+		if line == "" {
+			continue
+		}
+		if line[0] == '^' && dropped {
+			continue
+		}
+		dropped = false
+		if fields := strings.SplitN(strings.TrimSuffix(line, "\n"), " ", 2); len(fields) == 2 && drop[fields[1]] {
+			dropped = true
+			continue
+		}
+		buf.WriteString(line)
+	}
+	return errors.Wrap(os.WriteFile(p, buf.Bytes(), 0666), "error writing packed-refs")
+}
+
+// Ref is a named reference to an object.
+type Ref struct {
+	Name string
+	SHA  string
+}
+
+// Refs returns all refs below refs/, loose and packed, sorted by name.
+// Symbolic refs are resolved.
+func (r *Repository) Refs() ([]Ref, error) {
+	shas, err := r.PackedRefs()
+	if err != nil {
+		return nil, err
+	}
+	root := r.GitPath()
+	err = filepath.WalkDir(r.GitPath("refs"), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasSuffix(d.Name(), ".lock") {
+			return nil
+		}
+		name, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		name = filepath.ToSlash(name)
+		sha, err := r.ResolveRef(name)
+		if err != nil {
+			return err
+		}
+		shas[name] = sha
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading refs")
+	}
+	res := make([]Ref, 0, len(shas))
+	for name, sha := range shas {
+		res = append(res, Ref{Name: name, SHA: sha})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res, nil
+}
+
+// RefsWithPrefix returns all refs whose name starts with the given
+// prefix, e.g. refs/heads/, sorted by name.
+func (r *Repository) RefsWithPrefix(prefix string) ([]Ref, error) {
+	refs, err := r.Refs()
+	if err != nil {
+		return nil, err
+	}
+	var res []Ref
+	for _, ref := range refs {
+		if strings.HasPrefix(ref.Name, prefix) {
+			res = append(res, ref)
+		}
+	}
+	return res, nil
+}
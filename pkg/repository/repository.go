@@ -12,12 +12,14 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/natefinch/atomic"
 	"github.com/pkg/errors"
 	"github.com/sboehler/got/pkg/object"
+	"github.com/sboehler/got/pkg/pack"
 
 	"gopkg.in/ini.v1"
 )
@@ -27,17 +29,99 @@ type Repository struct {
 	Worktree string
 	GitDir   string
 	Config   *ini.File
+	Bare     bool
+	// ReadOnly makes operations which modify the repository or the
+	// worktree report what they would do on stderr instead.
+	ReadOnly bool
+
+	packs    []*pack.Index
+	open     map[string]*pack.Pack
+	filters  []filterRule
+	useBloom bool
+	bloom    *bloomFilter
+	// trees caches the trees read by path lookups, up to maxTrees
+	trees    map[string]*object.Tree
+	maxTrees int
+	// unwritten holds the objects which were not written in read-only
+	// mode, so that they can be read back
+	unwritten map[string]*ObjectFile
 }
 
-// GitPath returns the path to a file in the repository.
+// GitPath returns the path to a file in the repository. Paths in the
+// object store honor the GIT_OBJECT_DIRECTORY environment variable.
 func (r *Repository) GitPath(ss ...string) string {
-	return repoPath(r.Worktree, ss...)
+	if len(ss) > 0 && ss[0] == "objects" {
+		if dir := os.Getenv("GIT_OBJECT_DIRECTORY"); dir != "" {
+			return filepath.Join(append([]string{dir}, ss[1:]...)...)
+		}
+	}
+	return filepath.Join(append([]string{r.GitDir}, ss...)...)
+}
+
+// ObjectDir returns the path to the object store.
+func (r *Repository) ObjectDir() string {
+	return r.GitPath("objects")
+}
+
+// objectDirs returns the object store, followed by the alternate
+// object stores listed in GIT_ALTERNATE_OBJECT_DIRECTORIES.
+func (r *Repository) objectDirs() []string {
+	dirs := []string{r.ObjectDir()}
+	for _, dir := range filepath.SplitList(os.Getenv("GIT_ALTERNATE_OBJECT_DIRECTORIES")) {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// objectPath returns the path of the loose object with the given SHA,
+// searching the object store and its alternates.
+func (r *Repository) objectPath(sha string) (string, bool) {
+	for _, dir := range r.objectDirs() {
+		p := filepath.Join(dir, sha[:2], sha[2:])
+		if _, err := os.Stat(p); err == nil {
+			return p, true
+		}
+	}
+	return r.GitPath("objects", sha[:2], sha[2:]), false
 }
 
 const dirperms = 0775
 
+// DefaultBranch is the initial branch of new repositories unless
+// configured otherwise.
+const DefaultBranch = "master"
+
+// InitOptions are options for creating a repository.
+type InitOptions struct {
+	// Bare creates a repository without a worktree.
+	Bare bool
+	// InitialBranch is the branch HEAD points to. It defaults to
+	// DefaultBranch.
+	InitialBranch string
+}
+
 // Init initializes a new got repository.
 func Init(path string) (*Repository, error) {
+	return InitWith(path, InitOptions{})
+}
+
+// InitBare initializes a new bare got repository, which has no worktree
+// and keeps the git files directly in path.
+func InitBare(path string) (*Repository, error) {
+	return InitWith(path, InitOptions{Bare: true})
+}
+
+// InitWith initializes a new got repository with the given options.
+func InitWith(path string, opts InitOptions) (*Repository, error) {
+	bare, branch := opts.Bare, opts.InitialBranch
+	if branch == "" {
+		branch = DefaultBranch
+	}
+	if err := ValidateRefName("refs/heads/" + branch); err != nil {
+		return nil, errors.Wrapf(err, "invalid initial branch %s", branch)
+	}
 	path, err := filepath.Abs(path)
 	if err != nil {
 		return nil, errors.Wrap(err, "invalid path")
@@ -56,6 +140,14 @@ func Init(path string) (*Repository, error) {
 			return nil, errors.Wrap(err, "could not create repository")
 		}
 	}
+	r := &Repository{
+		Worktree: path,
+		GitDir:   filepath.Join(path, ".git"),
+		Bare:     bare,
+	}
+	if bare {
+		r.Worktree, r.GitDir = "", path
+	}
 	// path exists and is empty
 	for _, subdir := range [][]string{
 		{"branches"},
@@ -63,55 +155,80 @@ func Init(path string) (*Repository, error) {
 		{"refs", "tags"},
 		{"refs", "heads"},
 	} {
-		if err := os.MkdirAll(repoPath(path, subdir...), dirperms); err != nil {
+		if err := os.MkdirAll(filepath.Join(append([]string{r.GitDir}, subdir...)...), dirperms); err != nil {
 			return nil, err
 		}
 	}
 
-	err = atomic.WriteFile(repoPath(path, "description"), strings.NewReader("Unnamed repository; edit this file 'description' to name the repository.\n"))
+	err = atomic.WriteFile(filepath.Join(r.GitDir, "description"), strings.NewReader("Unnamed repository; edit this file 'description' to name the repository.\n"))
 	if err != nil {
-		return nil, errors.Wrapf(err, "error writing %s", repoPath(path, "description"))
+		return nil, errors.Wrapf(err, "error writing %s", filepath.Join(r.GitDir, "description"))
 	}
 
-	err = atomic.WriteFile(repoPath(path, "HEAD"), strings.NewReader("ref: refs/heads/master\n"))
+	err = atomic.WriteFile(filepath.Join(r.GitDir, "HEAD"), strings.NewReader(symrefPrefix+"refs/heads/"+branch+"\n"))
 	if err != nil {
-		return nil, errors.Wrapf(err, "error writing %s", repoPath(path, "HEAD"))
+		return nil, errors.Wrapf(err, "error writing %s", filepath.Join(r.GitDir, "HEAD"))
 	}
 
-	config := defaultConfig()
-	var cb bytes.Buffer
-	config.WriteTo(&cb)
-	err = atomic.WriteFile(repoPath(path, "config"), &cb)
-	if err != nil {
-		return nil, errors.Wrapf(err, "error writing %s", repoPath(path, "config"))
+	r.Config = defaultConfig()
+	if bare {
+		r.Config.Section("core").Key("bare").SetValue("true")
 	}
-
-	return &Repository{
-		Worktree: path,
-		GitDir:   repoPath(path),
-		Config:   config,
-	}, nil
+	if probeIgnoreCase(r.GitDir) {
+		r.Config.Section("core").Key("ignorecase").SetValue("true")
+	}
+	if err := r.WriteConfig(); err != nil {
+		return nil, err
+	}
+	return r, nil
 }
 
-// Load loads the repository at path.
+// Load loads the repository at path, which is either a worktree
+// containing a .git directory or a bare repository.
 func Load(path string) (*Repository, error) {
 	path, err := filepath.Abs(path)
 	if err != nil {
 		return nil, errors.Wrap(err, "invalid path")
 	}
-	config, err := ini.Load(repoPath(path, "config"))
+	if s, err := os.Stat(filepath.Join(path, ".git")); err == nil && s.IsDir() {
+		return load(path, filepath.Join(path, ".git"))
+	}
+	if isBare(path) {
+		return load("", path)
+	}
+	return nil, fmt.Errorf("%s is not a git repository", path)
+}
+
+func load(worktree, gitDir string) (*Repository, error) {
+	config, err := ini.Load(filepath.Join(gitDir, "config"))
 	if err != nil {
 		return nil, err
 	}
-	return &Repository{
-		Worktree: path,
-		GitDir:   repoPath(path),
+	r := &Repository{
+		Worktree: worktree,
+		GitDir:   gitDir,
 		Config:   config,
-	}, nil
+	}
+	r.Bare = strings.EqualFold(r.ConfigValue("core", "bare"), "true")
+	if r.Bare {
+		r.Worktree = ""
+	}
+	return r, nil
 }
 
-func repoPath(path string, segments ...string) string {
-	return filepath.Join(append([]string{path, ".git"}, segments...)...)
+// isBare returns whether path is a bare repository, i.e. it contains
+// HEAD, objects and refs directly and is configured with core.bare.
+func isBare(path string) bool {
+	for _, name := range []string{"HEAD", "objects", "refs"} {
+		if _, err := os.Stat(filepath.Join(path, name)); err != nil {
+			return false
+		}
+	}
+	config, err := ini.Load(filepath.Join(path, "config"))
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(config.Section("core").Key("bare").String(), "true")
 }
 
 // Find loads the repository at path or any of its parent directories.
@@ -120,17 +237,11 @@ func Find(path string) (*Repository, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "invalid path")
 	}
-	gitPath := filepath.Join(path, ".git")
-	if s, err := os.Stat(gitPath); err == nil && s.IsDir() {
-		config, err := ini.Load(repoPath(path, "config"))
-		if err != nil {
-			return nil, err
-		}
-		return &Repository{
-			Worktree: path,
-			GitDir:   repoPath(path),
-			Config:   config,
-		}, nil
+	if s, err := os.Stat(filepath.Join(path, ".git")); err == nil && s.IsDir() {
+		return load(path, filepath.Join(path, ".git"))
+	}
+	if isBare(path) {
+		return load("", path)
 	}
 	parent, err := filepath.Abs(filepath.Join(path, ".."))
 	if err != nil {
@@ -142,6 +253,53 @@ func Find(path string) (*Repository, error) {
 	return Find(parent)
 }
 
+// ErrBare is returned by operations which require a worktree when
+// invoked on a bare repository.
+var ErrBare = errors.New("this operation must be run in a work tree")
+
+// RequireWorktree returns ErrBare if the repository is bare.
+func (r *Repository) RequireWorktree() error {
+	if r.Bare {
+		return ErrBare
+	}
+	return nil
+}
+
+// ConfigValue returns the value of the given configuration key, or the
+// empty string if it is not set.
+func (r *Repository) ConfigValue(section, key string) string {
+	if r.Config == nil {
+		return ""
+	}
+	sec, err := r.Config.GetSection(section)
+	if err != nil {
+		return ""
+	}
+	k, err := sec.GetKey(key)
+	if err != nil {
+		return ""
+	}
+	return k.String()
+}
+
+// WriteConfig writes the repository's configuration to disk.
+func (r *Repository) WriteConfig() error {
+	var cb bytes.Buffer
+	if _, err := r.Config.WriteTo(&cb); err != nil {
+		return err
+	}
+	err := r.writeFile(r.GitPath("config"), &cb)
+	return errors.Wrapf(err, "error writing %s", r.GitPath("config"))
+}
+
+// probeIgnoreCase returns whether the file system at gitDir is case
+// insensitive, by checking whether the description file can be found
+// under a different case.
+func probeIgnoreCase(gitDir string) bool {
+	_, err := os.Stat(filepath.Join(gitDir, "DeScRiPtIoN"))
+	return err == nil
+}
+
 func defaultConfig() *ini.File {
 	f := ini.Empty()
 	core := f.Section("core")
@@ -152,48 +310,408 @@ func defaultConfig() *ini.File {
 }
 
 // Object represents an object.
-type Object interface {
-	Serialize() []byte
-	Deserialize([]byte) error
-}
+type Object = object.Object
 
 // LoadObject loads an object from the repository.
 func (r *Repository) LoadObject(sha string, objectType string) (Object, error) {
-	f, err := os.Open(r.GitPath("objects", sha[:2], sha[2:]))
+	of, err := r.ReadObject(sha)
+	if err != nil {
+		return nil, err
+	}
+	if of.ObjectType != objectType {
+		return nil, fmt.Errorf("wrong object type %s, want %s", of.ObjectType, objectType)
+	}
+	o, err := object.Parse(of.ObjectType, of.Data)
+	return o, errors.Wrapf(err, "error loading object %s", sha)
+}
+
+// ReadCommit reads the commit with the given SHA.
+func (r *Repository) ReadCommit(sha string) (*object.Commit, error) {
+	o, err := r.LoadObject(sha, "commit")
+	if err != nil {
+		return nil, err
+	}
+	return o.(*object.Commit), nil
+}
+
+// ReadTree reads the tree with the given SHA.
+func (r *Repository) ReadTree(sha string) (*object.Tree, error) {
+	o, err := r.LoadObject(sha, "tree")
+	if err != nil {
+		return nil, err
+	}
+	return o.(*object.Tree), nil
+}
+
+// ReadObject reads the object file with the given SHA from the repository.
+func (r *Repository) ReadObject(sha string) (*ObjectFile, error) {
+	if len(sha) != 40 {
+		return nil, fmt.Errorf("invalid object name %s", sha)
+	}
+	if of, ok := r.unwritten[sha]; ok {
+		return of, nil
+	}
+	p, ok := r.objectPath(sha)
+	if !ok {
+		if of, err := r.readPacked(sha); err != nil || of != nil {
+			return of, err
+		}
+	}
+	f, err := os.Open(p)
 	if err != nil {
 		return nil, errors.Wrapf(err, "error loading object %s", sha)
 	}
 	defer f.Close()
 	zr, err := zlib.NewReader(f)
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrapf(err, "object %s is corrupt", sha)
 	}
+	defer zr.Close()
 	of, err := ReadObjectFile(bufio.NewReader(zr))
-	if of.ObjectType != objectType {
-		return nil, fmt.Errorf("wrong object type %s, want %s", of.ObjectType, objectType)
+	if err != nil {
+		return nil, errors.Wrapf(err, "object %s is corrupt", sha)
 	}
-	switch of.ObjectType {
-	case "blob":
-		return object.NewBlob(of.Data), nil
-	default:
-		return nil, fmt.Errorf("unsupported object type %s", of.ObjectType)
+	return of, nil
+}
+
+// ReadObjectHeader reads the type and size of the object with the given
+// SHA, without decompressing the object's data. For deltified objects in
+// packs, only the header of the delta is decompressed.
+func (r *Repository) ReadObjectHeader(sha string) (string, int64, error) {
+	if len(sha) != 40 {
+		return "", 0, fmt.Errorf("invalid object name %s", sha)
 	}
+	if of, ok := r.unwritten[sha]; ok {
+		return of.ObjectType, int64(len(of.Data)), nil
+	}
+	p, ok := r.objectPath(sha)
+	if !ok {
+		idx, offset, err := r.findPacked(sha)
+		if err != nil {
+			return "", 0, err
+		}
+		if idx != nil {
+			pk, err := r.openPack(idx)
+			if err != nil {
+				return "", 0, err
+			}
+			ot, size, err := pk.ReadHeader(offset)
+			return ot, size, errors.Wrapf(err, "error loading object %s", sha)
+		}
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return "", 0, errors.Wrapf(err, "error loading object %s", sha)
+	}
+	defer f.Close()
+	zr, err := zlib.NewReader(f)
+	if err != nil {
+		return "", 0, errors.Wrapf(err, "object %s is corrupt", sha)
+	}
+	defer zr.Close()
+	ot, size, err := readHeader(bufio.NewReader(zr))
+	if err != nil {
+		return "", 0, errors.Wrapf(err, "object %s is corrupt", sha)
+	}
+	return ot, size, nil
+}
+
+// ObjectType returns the type of the object with the given SHA, reading
+// only its header.
+func (r *Repository) ObjectType(sha string) (string, error) {
+	typ, _, err := r.ReadObjectHeader(sha)
+	return typ, err
+}
+
+// ForEachObject calls fn for each object in the object store, in sorted
+// order. Objects whose header cannot be read are passed with an empty
+// type, so that callers can report them and continue. It stops at the
+// first error returned by fn.
+func (r *Repository) ForEachObject(fn func(sha string, typ string) error) error {
+	shas, err := r.Objects()
+	if err != nil {
+		return err
+	}
+	for _, sha := range shas {
+		typ, _, err := r.ReadObjectHeader(sha)
+		if err != nil {
+			typ = ""
+		}
+		if err := fn(sha, typ); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ForEachObjectOfType calls fn for each object of the given type in the
+// object store, in sorted order. Objects are classified by their header,
+// without decompressing their data.
+func (r *Repository) ForEachObjectOfType(typ string, fn func(sha string) error) error {
+	return r.ForEachObject(func(sha string, t string) error {
+		if t != typ {
+			return nil
+		}
+		return fn(sha)
+	})
 }
 
-// WriteObject writes the given object to the repository.
+// HasObject returns whether the object with the given SHA exists.
+func (r *Repository) HasObject(sha string) bool {
+	if len(sha) != 40 {
+		return false
+	}
+	if _, ok := r.unwritten[sha]; ok {
+		return true
+	}
+	if r.useBloom {
+		if b := r.bloomFilter(); b != nil && !b.mayContain(sha) {
+			return false
+		}
+	}
+	if _, ok := r.objectPath(sha); ok {
+		return true
+	}
+	idx, _, _ := r.findPacked(sha)
+	return idx != nil
+}
+
+// PackIndexes returns the indexes of all packs in the object store. The
+// indexes are loaded once and cached on the repository.
+func (r *Repository) PackIndexes() ([]*pack.Index, error) {
+	if r.packs != nil {
+		return r.packs, nil
+	}
+	paths, err := filepath.Glob(filepath.Join(r.ObjectDir(), "pack", "*.idx"))
+	if err != nil {
+		return nil, err
+	}
+	packs := make([]*pack.Index, 0, len(paths))
+	for _, p := range paths {
+		idx, err := pack.LoadIndex(p)
+		if err != nil {
+			return nil, err
+		}
+		packs = append(packs, idx)
+	}
+	r.packs = packs
+	return packs, nil
+}
+
+// ReloadPacks drops the cached pack indexes, so that packs added to the
+// object store after they were loaded are found.
+func (r *Repository) ReloadPacks() {
+	r.packs = nil
+	r.bloom = nil
+}
+
+// Close releases the resources held by the repository, such as cached
+// pack indexes and open packs. The caches are rebuilt on demand if the
+// repository is used afterwards.
+func (r *Repository) Close() error {
+	var err error
+	for _, p := range r.open {
+		if cerr := p.Close(); err == nil {
+			err = cerr
+		}
+	}
+	r.packs = nil
+	r.open = nil
+	r.bloom = nil
+	return err
+}
+
+// openPack returns the pack belonging to the given index, opening it on
+// first use.
+func (r *Repository) openPack(idx *pack.Index) (*pack.Pack, error) {
+	if p, ok := r.open[idx.Path]; ok {
+		return p, nil
+	}
+	p, err := pack.Open(idx)
+	if err != nil {
+		return nil, err
+	}
+	if r.open == nil {
+		r.open = make(map[string]*pack.Pack)
+	}
+	r.open[idx.Path] = p
+	return p, nil
+}
+
+// readPacked reads the object with the given SHA from a pack. It returns
+// nil if the object is not packed.
+func (r *Repository) readPacked(sha string) (*ObjectFile, error) {
+	idx, offset, err := r.findPacked(sha)
+	if err != nil || idx == nil {
+		return nil, err
+	}
+	p, err := r.openPack(idx)
+	if err != nil {
+		return nil, err
+	}
+	ot, data, err := p.ReadAt(offset)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error loading object %s", sha)
+	}
+	return &ObjectFile{ObjectType: ot, Data: data}, nil
+}
+
+// findPacked returns the pack index containing the object with the given
+// SHA and the object's offset in the pack.
+func (r *Repository) findPacked(sha string) (*pack.Index, int64, error) {
+	packs, err := r.PackIndexes()
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, idx := range packs {
+		if offset, ok := idx.Lookup(sha); ok {
+			return idx, offset, nil
+		}
+	}
+	return nil, 0, nil
+}
+
+// WriteObject writes the given object to the repository. The object is
+// hashed first, and only compressed and written if it does not exist yet.
 func (r *Repository) WriteObject(of *ObjectFile) (string, error) {
-	var (
-		buf bytes.Buffer
-		w   = zlib.NewWriter(&buf)
-	)
-	if _, err := of.Write(w); err != nil {
+	hash, err := object.HashStream(of.ObjectType, int64(len(of.Data)), bytes.NewReader(of.Data))
+	if err != nil {
+		return "", err
+	}
+	if r.HasObject(hash) {
+		// objects are content-addressed, so the existing object is identical
+		return hash, nil
+	}
+	if r.ReadOnly {
+		if r.unwritten == nil {
+			r.unwritten = make(map[string]*ObjectFile)
+		}
+		r.unwritten[hash] = of
+	}
+	buf, err := compress(of, r.CompressionLevel())
+	if err != nil {
 		return "", err
 	}
-	w.Close()
-	hash := Hash(of)
+	return hash, r.writeLoose(hash, buf)
+}
+
+// compress compresses the object at the given zlib level.
+func compress(of *ObjectFile, level int) (*bytes.Buffer, error) {
+	var buf bytes.Buffer
+	w, err := zlib.NewWriterLevel(&buf, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := of.Write(w); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return &buf, nil
+}
+
+// writeLoose writes compressed object data to the object store.
+func (r *Repository) writeLoose(hash string, buf *bytes.Buffer) error {
+	if r.dryRun("write object %s", hash) {
+		return nil
+	}
+	if err := os.MkdirAll(r.GitPath("objects", hash[:2]), dirperms); err != nil {
+		return errors.Wrapf(err, "error writing object %s", hash)
+	}
 	f := r.GitPath("objects", hash[:2], hash[2:])
-	err := atomic.WriteFile(f, &buf)
-	return hash, errors.Wrapf(err, "error writing object %s", hash)
+	if err := r.writeFile(f, buf); err != nil {
+		return errors.Wrapf(err, "error writing object %s", hash)
+	}
+	if r.bloom != nil {
+		r.bloom.add(hash)
+	}
+	return nil
+}
+
+// CompressionLevel returns the zlib compression level for loose objects,
+// configured by core.loosecompression or core.compression.
+func (r *Repository) CompressionLevel() int {
+	for _, key := range []string{"loosecompression", "compression"} {
+		if level, err := strconv.Atoi(r.ConfigValue("core", key)); err == nil && level >= -1 && level <= 9 {
+			return level
+		}
+	}
+	return zlib.DefaultCompression
+}
+
+// RecompressObject rewrites the loose object with the given SHA at the
+// given compression level. It returns the object's size on disk before
+// and after.
+func (r *Repository) RecompressObject(sha string, level int) (int64, int64, error) {
+	fi, err := os.Stat(r.GitPath("objects", sha[:2], sha[2:]))
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "error recompressing object %s", sha)
+	}
+	of, err := r.ReadObject(sha)
+	if err != nil {
+		return 0, 0, err
+	}
+	if hash := Hash(of); hash != sha {
+		return 0, 0, fmt.Errorf("object %s is corrupt: hash mismatch, got %s", sha, hash)
+	}
+	buf, err := compress(of, level)
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "error recompressing object %s", sha)
+	}
+	size := int64(buf.Len())
+	return fi.Size(), size, r.writeLoose(sha, buf)
+}
+
+// LooseObjects returns the SHAs of all loose objects in the object store,
+// in sorted order.
+func (r *Repository) LooseObjects() ([]string, error) {
+	dirs, err := os.ReadDir(r.ObjectDir())
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading object store")
+	}
+	var res []string
+	for _, dir := range dirs {
+		if !dir.IsDir() || len(dir.Name()) != 2 || !isHex(dir.Name()) {
+			continue
+		}
+		fs, err := os.ReadDir(filepath.Join(r.ObjectDir(), dir.Name()))
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading object store")
+		}
+		for _, f := range fs {
+			if sha := dir.Name() + f.Name(); len(sha) == 40 && isHex(sha) {
+				res = append(res, sha)
+			}
+		}
+	}
+	return res, nil
+}
+
+// Objects returns the SHAs of all loose and packed objects in the object
+// store, sorted and without duplicates.
+func (r *Repository) Objects() ([]string, error) {
+	shas, err := r.LooseObjects()
+	if err != nil {
+		return nil, err
+	}
+	packs, err := r.PackIndexes()
+	if err != nil {
+		return nil, err
+	}
+	for _, idx := range packs {
+		for i := 0; i < idx.Len(); i++ {
+			shas = append(shas, idx.SHA(i))
+		}
+	}
+	sort.Strings(shas)
+	res := shas[:0]
+	for i, sha := range shas {
+		if i == 0 || sha != shas[i-1] {
+			res = append(res, sha)
+		}
+	}
+	return res, nil
 }
 
 // Hash hashes the object.
@@ -203,9 +721,26 @@ func Hash(of *ObjectFile) string {
 	return hex.EncodeToString(hasher.Sum(nil))
 }
 
-// Find resolves the given object reference.
-func (r *Repository) Find(name string, ot string, follow bool) string {
-	return name
+// Find resolves the given object reference, which is a revision as
+// accepted by Resolve, to the SHA of an object of type ot. If
+// follow is set, tags and commits are peeled until an object of type ot
+// is found; otherwise the named object must have type ot. An empty ot
+// accepts objects of any type.
+func (r *Repository) Find(name string, ot string, follow bool) (string, error) {
+	sha, err := r.Resolve(name)
+	if err != nil {
+		return "", err
+	}
+	if ot == "" {
+		return sha, nil
+	}
+	if follow {
+		return r.Peel(sha, ot)
+	}
+	if err := r.checkType(sha, ot); err != nil {
+		return "", err
+	}
+	return sha, nil
 }
 
 // ObjectFile defines the wire format for storing objects in the repository.
@@ -215,26 +750,23 @@ type ObjectFile struct {
 }
 
 var validObjectType = map[string]struct{}{
-	"blob": {},
+	"blob":   {},
+	"tree":   {},
+	"commit": {},
+	"tag":    {},
+}
+
+// IsValidObjectType returns whether the given object type is supported.
+func IsValidObjectType(ot string) bool {
+	_, ok := validObjectType[ot]
+	return ok
 }
 
 // ReadObjectFile reads an object file from a reader.
 func ReadObjectFile(r *bufio.Reader) (*ObjectFile, error) {
-	bs, err := r.ReadBytes(0x20)
-	if err != nil {
-		return nil, errors.Wrap(err, "couldn't read object type")
-	}
-	ot := string(bs[:len(bs)-1])
-	if _, ok := validObjectType[ot]; !ok {
-		return nil, fmt.Errorf("invalid object type %s", ot)
-	}
-	bs, err = r.ReadBytes(0x00)
+	ot, size, err := readHeader(r)
 	if err != nil {
-		return nil, errors.Wrap(err, "couldn't read object size")
-	}
-	size, err := strconv.ParseInt(string(bs[:len(bs)-1]), 10, 64)
-	if err != nil {
-		return nil, errors.Wrap(err, "invalid size")
+		return nil, err
 	}
 	data, err := io.ReadAll(r)
 	if err != nil {
@@ -249,6 +781,24 @@ func ReadObjectFile(r *bufio.Reader) (*ObjectFile, error) {
 	}, nil
 }
 
+// readHeader reads the type and size of an object file.
+func readHeader(r *bufio.Reader) (string, int64, error) {
+	bs, err := r.ReadBytes(0x20)
+	if err != nil {
+		return "", 0, errors.Wrap(err, "couldn't read object type")
+	}
+	ot := string(bs[:len(bs)-1])
+	bs, err = r.ReadBytes(0x00)
+	if err != nil {
+		return "", 0, errors.Wrap(err, "couldn't read object size")
+	}
+	size, err := strconv.ParseInt(string(bs[:len(bs)-1]), 10, 64)
+	if err != nil {
+		return "", 0, errors.Wrap(err, "invalid size")
+	}
+	return ot, size, nil
+}
+
 func (of *ObjectFile) Write(w io.Writer) (int64, error) {
 	var (
 		total int64
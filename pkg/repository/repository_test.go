@@ -0,0 +1,304 @@
+package repository
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/sboehler/got/pkg/object"
+)
+
+// newTestRepo creates a repository in a temporary directory, with a
+// fixed identity and fixed commit dates.
+func newTestRepo(t testing.TB) *Repository {
+	t.Helper()
+	r, err := Init(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Config.Section("user").Key("name").SetValue("A U Thor")
+	r.Config.Section("user").Key("email").SetValue("author@example.com")
+	if err := r.WriteConfig(); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GIT_AUTHOR_DATE", "1600000000 +0200")
+	t.Setenv("GIT_COMMITTER_DATE", "1600000000 +0200")
+	return r
+}
+
+func writeBlob(t testing.TB, r *Repository, content string) string {
+	t.Helper()
+	sha, err := r.WriteObject(&ObjectFile{ObjectType: "blob", Data: []byte(content)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sha
+}
+
+func writeTestTree(t *testing.T, r *Repository, entries ...object.TreeEntry) string {
+	t.Helper()
+	sha, err := r.WriteObject(&ObjectFile{ObjectType: "tree", Data: object.NewTree(entries).Serialize()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sha
+}
+
+func writeCommit(t *testing.T, r *Repository, tree, message string, parents ...string) string {
+	t.Helper()
+	sha, err := r.CommitTree(tree, parents, message, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sha
+}
+
+func updateRef(t *testing.T, r *Repository, name, sha string) {
+	t.Helper()
+	if err := r.UpdateRef(name, sha); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadObjectCorrupt(t *testing.T) {
+	r := newTestRepo(t)
+	sha := "0123456789abcdef0123456789abcdef01234567"
+	p := filepath.Join(r.ObjectDir(), sha[:2], sha[2:])
+	if err := os.MkdirAll(filepath.Dir(p), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte("this is not zlib data"), 0444); err != nil {
+		t.Fatal(err)
+	}
+	for name, read := range map[string]func() error{
+		"ReadObject": func() error {
+			_, err := r.ReadObject(sha)
+			return err
+		},
+		"ReadObjectHeader": func() error {
+			_, _, err := r.ReadObjectHeader(sha)
+			return err
+		},
+		"LoadObject": func() error {
+			_, err := r.LoadObject(sha, "blob")
+			return err
+		},
+	} {
+		err := read()
+		if err == nil {
+			t.Errorf("%s: got no error for a corrupt object", name)
+			continue
+		}
+		if want := "object " + sha + " is corrupt: "; !strings.HasPrefix(err.Error(), want) {
+			t.Errorf("%s: got error %q, want prefix %q", name, err, want)
+		}
+	}
+}
+
+func TestWriteObjectRoundTrip(t *testing.T) {
+	r := newTestRepo(t)
+	sha := writeBlob(t, r, "hello\n")
+	if want := "ce013625030ba8dba906f756967f9e9ca394464a"; sha != want {
+		t.Fatalf("got SHA %s, want %s", sha, want)
+	}
+	of, err := r.ReadObject(sha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if of.ObjectType != "blob" || string(of.Data) != "hello\n" {
+		t.Errorf("got %s %q, want blob %q", of.ObjectType, of.Data, "hello\n")
+	}
+}
+
+func TestWriteObjectExisting(t *testing.T) {
+	r := newTestRepo(t)
+	sha := writeBlob(t, r, "hello\n")
+	// Replace the object with a marker: writing the blob again must find
+	// the existing file and leave it alone.
+	p := filepath.Join(r.ObjectDir(), sha[:2], sha[2:])
+	if err := os.Chmod(p, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte("marker"), 0444); err != nil {
+		t.Fatal(err)
+	}
+	if got := writeBlob(t, r, "hello\n"); got != sha {
+		t.Fatalf("got SHA %s, want %s", got, sha)
+	}
+	if data, err := os.ReadFile(p); err != nil || string(data) != "marker" {
+		t.Errorf("existing object was rewritten: %q, %v", data, err)
+	}
+}
+
+func TestRecompressObject(t *testing.T) {
+	r := newTestRepo(t)
+	content := strings.Repeat("compressible content\n", 1000)
+	sha := writeBlob(t, r, content)
+	p := filepath.Join(r.ObjectDir(), sha[:2], sha[2:])
+	size := func() int64 {
+		t.Helper()
+		fi, err := os.Stat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fi.Size()
+	}
+	written := size()
+	before, after, err := r.RecompressObject(sha, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before != written || after != size() || after <= before {
+		t.Errorf("got sizes %d -> %d on disk %d, want growth from %d when storing uncompressed", before, after, size(), written)
+	}
+	if before, after, err = r.RecompressObject(sha, 9); err != nil {
+		t.Fatal(err)
+	}
+	if after >= before || after != size() {
+		t.Errorf("got sizes %d -> %d on disk %d, want shrinking at level 9", before, after, size())
+	}
+	of, err := r.ReadObject(sha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if of.ObjectType != "blob" || string(of.Data) != content || Hash(of) != sha {
+		t.Errorf("recompressed object %s changed its content", sha)
+	}
+}
+
+func BenchmarkWriteObjectExisting(b *testing.B) {
+	r, err := Init(b.TempDir())
+	if err != nil {
+		b.Fatal(err)
+	}
+	of := &ObjectFile{ObjectType: "blob", Data: bytes.Repeat([]byte("data\n"), 10000)}
+	if _, err := r.WriteObject(of); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := r.WriteObject(of); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestBareRepository(t *testing.T) {
+	dir := t.TempDir()
+	bare, err := InitBare(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ".git")); !os.IsNotExist(err) {
+		t.Errorf("bare repository has a .git directory")
+	}
+	sha := writeBlob(t, bare, "bare\n")
+	if _, err := os.Stat(filepath.Join(dir, "objects", sha[:2], sha[2:])); err != nil {
+		t.Errorf("object was not written to the repository directory: %v", err)
+	}
+	for name, load := range map[string]func(string) (*Repository, error){"Load": Load, "Find": Find} {
+		path := dir
+		if name == "Find" {
+			path = filepath.Join(dir, "refs", "heads")
+		}
+		r, err := load(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !r.Bare || r.GitDir != dir || r.Worktree != "" {
+			t.Errorf("%s: got bare %v, git dir %s and worktree %q, want a bare repository at %s", name, r.Bare, r.GitDir, r.Worktree, dir)
+		}
+		if of, err := r.ReadObject(sha); err != nil || of.ObjectType != "blob" || string(of.Data) != "bare\n" {
+			t.Errorf("%s: ReadObject(%s) = %v, %v", name, sha, of, err)
+		}
+		if err := r.RequireWorktree(); errors.Cause(err) != ErrBare {
+			t.Errorf("%s: got error %v, want %v", name, err, ErrBare)
+		}
+	}
+}
+
+func TestObjectDirectoryOverride(t *testing.T) {
+	r := newTestRepo(t)
+	dir := t.TempDir()
+	t.Setenv("GIT_OBJECT_DIRECTORY", dir)
+	if got := r.ObjectDir(); got != dir {
+		t.Fatalf("ObjectDir() = %s, want %s", got, dir)
+	}
+	sha := writeBlob(t, r, "relocated\n")
+	if _, err := os.Stat(filepath.Join(dir, sha[:2], sha[2:])); err != nil {
+		t.Errorf("object was not written to GIT_OBJECT_DIRECTORY: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(r.GitDir, "objects", sha[:2], sha[2:])); !os.IsNotExist(err) {
+		t.Errorf("object was written to .git/objects")
+	}
+	if of, err := r.ReadObject(sha); err != nil || string(of.Data) != "relocated\n" {
+		t.Errorf("ReadObject(%s) = %v, %v", sha, of, err)
+	}
+}
+
+func TestTempDir(t *testing.T) {
+	r := newTestRepo(t)
+	r.Config.Section("core").Key("tempdir").SetValue("tmp")
+	if err := os.Mkdir(filepath.Join(r.GitDir, "tmp"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	sha := writeBlob(t, r, "via tempdir\n")
+	if of, err := r.ReadObject(sha); err != nil || string(of.Data) != "via tempdir\n" {
+		t.Errorf("ReadObject(%s) = %v, %v", sha, of, err)
+	}
+	if fs, _ := os.ReadDir(filepath.Join(r.GitDir, "tmp")); len(fs) != 0 {
+		t.Errorf("temporary files were left behind: %v", fs)
+	}
+	if fs, _ := os.ReadDir(filepath.Join(r.ObjectDir(), sha[:2])); len(fs) != 1 {
+		t.Errorf("got files %v next to the object, want only the object", fs)
+	}
+
+	// A temp dir on another filesystem cannot be renamed from.
+	other, err := os.MkdirTemp("/dev/shm", "got-test")
+	if err != nil {
+		t.Skip("no other filesystem available")
+	}
+	t.Cleanup(func() { os.RemoveAll(other) })
+	probe := filepath.Join(other, "probe")
+	if err := os.WriteFile(probe, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(probe, filepath.Join(r.GitDir, "probe")); err == nil {
+		t.Skipf("%s is on the same filesystem as the repository", other)
+	}
+	os.Remove(probe)
+	r.Config.Section("core").Key("tempdir").SetValue(other)
+	of := &ObjectFile{ObjectType: "blob", Data: []byte("cross-device\n")}
+	if _, err := r.WriteObject(of); err == nil || !strings.Contains(err.Error(), "is not on the same filesystem") {
+		t.Errorf("got error %v for a cross-device temp dir", err)
+	}
+	if r.HasObject(Hash(of)) {
+		t.Errorf("object was written despite the error")
+	}
+	if fs, _ := os.ReadDir(other); len(fs) != 0 {
+		t.Errorf("temporary files were left behind: %v", fs)
+	}
+}
+
+func TestAlternateObjectDirectories(t *testing.T) {
+	r := newTestRepo(t)
+	other := newTestRepo(t)
+	sha := writeBlob(t, other, "borrowed\n")
+	if r.HasObject(sha) {
+		t.Fatalf("object %s exists before alternates are set", sha)
+	}
+	t.Setenv("GIT_ALTERNATE_OBJECT_DIRECTORIES", t.TempDir()+string(os.PathListSeparator)+other.ObjectDir())
+	if !r.HasObject(sha) {
+		t.Errorf("object %s in the alternate store is not found", sha)
+	}
+	if of, err := r.ReadObject(sha); err != nil || string(of.Data) != "borrowed\n" {
+		t.Errorf("ReadObject(%s) = %v, %v", sha, of, err)
+	}
+	own := writeBlob(t, r, "own\n")
+	if _, err := os.Stat(filepath.Join(r.GitDir, "objects", own[:2], own[2:])); err != nil {
+		t.Errorf("object was not written to the primary store: %v", err)
+	}
+}
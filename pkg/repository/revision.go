@@ -0,0 +1,380 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sboehler/got/pkg/index"
+	"github.com/sboehler/got/pkg/object"
+)
+
+// Resolve resolves a revision to the SHA of the object it names. The
+// following forms are supported:
+//
+//	<sha>           a full or abbreviated (at least 4 hex digits) SHA
+//	<refname>       a ref, e.g. HEAD, master, heads/master, refs/heads/master
+//	@               shorthand for HEAD
+//	<ref>@{<n>}     the n-th prior value of a ref, read from its reflog
+//	<rev>~<n>       the n-th generation first-parent ancestor of a commit
+//	<rev>^<n>       the n-th parent of a commit
+//	<rev>^{<type>}  the object, peeled until it has the given type
+//	<rev>^{}        the object, with tags peeled
+//	<rev>:<path>    the blob or tree at the given path in the tree of rev
+//	:<path>         the blob at the given path in the index
+//
+// Suffixes can be chained, e.g. HEAD~2^2^{tree}.
+func (r *Repository) Resolve(rev string) (string, error) {
+	if i := strings.IndexByte(rev, ':'); i >= 0 {
+		sha, _, err := r.ResolvePath(rev[:i], rev[i+1:])
+		return sha, err
+	}
+	i := strings.IndexAny(rev, "~^")
+	if i < 0 {
+		i = len(rev)
+	}
+	sha, err := r.resolveBase(rev[:i])
+	if err != nil {
+		return "", err
+	}
+	suffix := rev[i:]
+	for len(suffix) > 0 {
+		op := suffix[0]
+		suffix = suffix[1:]
+		if op == '^' && strings.HasPrefix(suffix, "{") {
+			end := strings.IndexByte(suffix, '}')
+			if end < 0 {
+				return "", fmt.Errorf("invalid revision %s: missing '}'", rev)
+			}
+			if sha, err = r.Peel(sha, suffix[1:end]); err != nil {
+				return "", err
+			}
+			suffix = suffix[end+1:]
+			continue
+		}
+		j := 0
+		for j < len(suffix) && suffix[j] >= '0' && suffix[j] <= '9' {
+			j++
+		}
+		n := 1
+		if j > 0 {
+			if n, err = strconv.Atoi(suffix[:j]); err != nil {
+				return "", errors.Wrapf(err, "invalid revision %s", rev)
+			}
+		}
+		suffix = suffix[j:]
+		switch op {
+		case '~':
+			for ; n > 0; n-- {
+				if sha, err = r.parent(sha, 1); err != nil {
+					return "", err
+				}
+			}
+		case '^':
+			if n == 0 {
+				if sha, err = r.Peel(sha, "commit"); err != nil {
+					return "", err
+				}
+				continue
+			}
+			if sha, err = r.parent(sha, n); err != nil {
+				return "", err
+			}
+		default:
+			return "", fmt.Errorf("invalid revision %s", rev)
+		}
+	}
+	return sha, nil
+}
+
+// ResolvePath resolves the object at the given path in the tree of rev,
+// or in the index if rev is empty. It returns the SHA and type of the
+// object, which is taken from its mode.
+func (r *Repository) ResolvePath(rev, p string) (string, string, error) {
+	if rev == "" {
+		idx, err := r.ReadIndex()
+		if err != nil {
+			return "", "", err
+		}
+		e, ok := idx.Entry(p, 0)
+		if !ok {
+			return "", "", fmt.Errorf("path '%s' does not exist in the index", p)
+		}
+		if e.Mode == index.ModeSubmodule {
+			return e.SHA, "commit", nil
+		}
+		return e.SHA, "blob", nil
+	}
+	sha, err := r.Find(rev, "tree", true)
+	if err != nil {
+		return "", "", err
+	}
+	e, ok, err := r.lookupPath(sha, p)
+	if err != nil {
+		return "", "", err
+	}
+	if !ok {
+		return "", "", fmt.Errorf("path '%s' does not exist in '%s'", p, rev)
+	}
+	return e.SHA, e.Type(), nil
+}
+
+// maxSymlinks is the number of symbolic links FollowSymlinks follows
+// before it gives up, as in git.
+const maxSymlinks = 40
+
+// FollowSymlinks resolves the object at the given path in the tree of rev
+// like ResolvePath, but follows symbolic links in the tree, in the path's
+// directories as well as at its end. Links which are absolute or leave
+// the tree are an error.
+func (r *Repository) FollowSymlinks(rev, p string) (string, string, error) {
+	root, err := r.Find(rev, "tree", true)
+	if err != nil {
+		return "", "", err
+	}
+	var (
+		// entries are the directories walked so far, starting at the root
+		entries = []object.TreeEntry{{Mode: object.ModeTree, SHA: root}}
+		names   = strings.Split(p, "/")
+		links   int
+	)
+	for len(names) > 0 {
+		name := names[0]
+		names = names[1:]
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			if len(entries) == 1 {
+				return "", "", fmt.Errorf("path '%s' leaves the tree of '%s'", p, rev)
+			}
+			entries = entries[:len(entries)-1]
+			continue
+		}
+		dir := entries[len(entries)-1]
+		if dir.Mode != object.ModeTree {
+			return "", "", fmt.Errorf("path '%s' does not exist in '%s'", p, rev)
+		}
+		t, err := r.readPathTree(dir.SHA)
+		if err != nil {
+			return "", "", err
+		}
+		e, ok := t.Entry(name)
+		if !ok {
+			return "", "", fmt.Errorf("path '%s' does not exist in '%s'", p, rev)
+		}
+		if e.Mode != object.ModeSymlink {
+			entries = append(entries, e)
+			continue
+		}
+		if links++; links > maxSymlinks {
+			return "", "", fmt.Errorf("path '%s' has too many levels of symbolic links", p)
+		}
+		of, err := r.ReadObject(e.SHA)
+		if err != nil {
+			return "", "", err
+		}
+		target := string(of.Data)
+		if strings.HasPrefix(target, "/") {
+			return "", "", fmt.Errorf("path '%s' leaves the tree of '%s'", p, rev)
+		}
+		names = append(strings.Split(target, "/"), names...)
+	}
+	e := entries[len(entries)-1]
+	return e.SHA, e.Type(), nil
+}
+
+// resolveBase resolves a revision without ~ and ^ suffixes.
+func (r *Repository) resolveBase(rev string) (string, error) {
+	if i := strings.Index(rev, "@{"); i >= 0 {
+		if !strings.HasSuffix(rev, "}") {
+			return "", fmt.Errorf("invalid revision %s: missing '}'", rev)
+		}
+		n, err := strconv.Atoi(rev[i+2 : len(rev)-1])
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("invalid reflog index in %s", rev)
+		}
+		name := "HEAD"
+		if i > 0 {
+			if name, err = r.expandRef(rev[:i]); err != nil {
+				return "", err
+			}
+		}
+		return r.reflogEntry(name, n)
+	}
+	if rev == "@" {
+		rev = "HEAD"
+	}
+	if len(rev) == 40 && isHex(rev) {
+		return strings.ToLower(rev), nil
+	}
+	name, err := r.expandRef(rev)
+	if err == nil {
+		return r.ResolveRef(name)
+	}
+	if errors.Cause(err) != ErrRefNotFound {
+		return "", err
+	}
+	if len(rev) >= 4 && isHex(rev) {
+		return r.resolveAbbrev(rev)
+	}
+	return "", fmt.Errorf("unknown revision %s", rev)
+}
+
+// expandRef returns the full name of the ref designated by the given
+// short name, using the same lookup rules as git.
+func (r *Repository) expandRef(name string) (string, error) {
+	for _, pattern := range []string{
+		"%s",
+		"refs/%s",
+		"refs/tags/%s",
+		"refs/heads/%s",
+		"refs/remotes/%s",
+		"refs/remotes/%s/HEAD",
+	} {
+		full := fmt.Sprintf(pattern, name)
+		if _, err := r.ReadRef(full); err == nil {
+			return full, nil
+		} else if errors.Cause(err) != ErrRefNotFound {
+			return "", err
+		}
+	}
+	return "", errors.Wrapf(ErrRefNotFound, "%s", name)
+}
+
+// resolveAbbrev resolves an abbreviated SHA to a full SHA.
+func (r *Repository) resolveAbbrev(prefix string) (string, error) {
+	prefix = strings.ToLower(prefix)
+	candidates, err := r.abbrevCandidates(prefix)
+	if err != nil {
+		return "", err
+	}
+	switch len(candidates) {
+	case 0:
+		return "", fmt.Errorf("unknown revision %s", prefix)
+	case 1:
+		return candidates[0], nil
+	default:
+		return "", fmt.Errorf("short SHA %s is ambiguous, candidates are: %s", prefix, strings.Join(candidates, ", "))
+	}
+}
+
+// abbrevCandidates returns the SHAs of the loose and packed objects which
+// start with the given lowercase prefix, in sorted order.
+func (r *Repository) abbrevCandidates(prefix string) ([]string, error) {
+	seen := make(map[string]bool)
+	var candidates []string
+	add := func(sha string) {
+		if !seen[sha] {
+			seen[sha] = true
+			candidates = append(candidates, sha)
+		}
+	}
+	for _, dir := range r.objectDirs() {
+		fs, err := os.ReadDir(filepath.Join(dir, prefix[:2]))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, errors.Wrapf(err, "error resolving %s", prefix)
+		}
+		for _, f := range fs {
+			if strings.HasPrefix(f.Name(), prefix[2:]) {
+				add(prefix[:2] + f.Name())
+			}
+		}
+	}
+	packs, err := r.PackIndexes()
+	if err != nil {
+		return nil, errors.Wrapf(err, "error resolving %s", prefix)
+	}
+	for _, idx := range packs {
+		for _, sha := range idx.Prefix(prefix) {
+			add(sha)
+		}
+	}
+	sort.Strings(candidates)
+	return candidates, nil
+}
+
+// Abbreviate returns the shortest prefix of the given SHA, but at least
+// seven digits, which unambiguously names an object in the repository.
+func (r *Repository) Abbreviate(sha string) (string, error) {
+	for n := 7; n < len(sha); n++ {
+		candidates, err := r.abbrevCandidates(sha[:n])
+		if err != nil {
+			return "", err
+		}
+		if len(candidates) <= 1 {
+			return sha[:n], nil
+		}
+	}
+	return sha, nil
+}
+
+// reflogEntry returns the n-th prior value of the given ref.
+func (r *Repository) reflogEntry(name string, n int) (string, error) {
+	entries, err := r.ReadReflog(name)
+	if err != nil {
+		return "", err
+	}
+	if n >= len(entries) {
+		return "", fmt.Errorf("reflog of %s has only %d entries", name, len(entries))
+	}
+	return entries[len(entries)-1-n].New, nil
+}
+
+// parent returns the n-th parent of the given commit.
+func (r *Repository) parent(sha string, n int) (string, error) {
+	c, err := r.ReadCommit(sha)
+	if err != nil {
+		return "", err
+	}
+	parents := c.Parents()
+	if n > len(parents) {
+		return "", fmt.Errorf("commit %s has no parent %d", sha, n)
+	}
+	return parents[n-1], nil
+}
+
+// Peel follows the given object until an object of the given type is
+// found. Tags are peeled to their target and commits to their tree. If
+// wantType is empty, tags are peeled until a non-tag object is found.
+func (r *Repository) Peel(sha string, wantType string) (string, error) {
+	for {
+		of, err := r.ReadObject(sha)
+		if err != nil {
+			return "", err
+		}
+		if of.ObjectType == wantType || wantType == "" && of.ObjectType != "tag" {
+			return sha, nil
+		}
+		o, err := object.Parse(of.ObjectType, of.Data)
+		if err != nil {
+			return "", errors.Wrapf(err, "object %s is corrupt", sha)
+		}
+		var next string
+		switch o := o.(type) {
+		case *object.Tag:
+			next = o.Object()
+		case *object.Commit:
+			if wantType == "tree" {
+				next = o.Tree()
+			}
+		}
+		if next == "" {
+			return "", fmt.Errorf("object %s is a %s, cannot peel to %s", sha, of.ObjectType, wantType)
+		}
+		sha = next
+	}
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,372 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sboehler/got/pkg/index"
+	"github.com/sboehler/got/pkg/object"
+)
+
+func TestResolve(t *testing.T) {
+	r := newTestRepo(t)
+	blobA := writeBlob(t, r, "a\n")
+	blobB := writeBlob(t, r, "b\n")
+	sub := writeTestTree(t, r, object.TreeEntry{Mode: object.ModeFile, Name: "b", SHA: blobB})
+	tree1 := writeTestTree(t, r, object.TreeEntry{Mode: object.ModeFile, Name: "a", SHA: blobA})
+	tree2 := writeTestTree(t, r,
+		object.TreeEntry{Mode: object.ModeFile, Name: "a", SHA: blobA},
+		object.TreeEntry{Mode: object.ModeTree, Name: "dir", SHA: sub},
+	)
+	c1 := writeCommit(t, r, tree1, "first\n")
+	c2 := writeCommit(t, r, tree2, "second\n", c1)
+	side := writeCommit(t, r, tree1, "side\n", c1)
+	merge := writeCommit(t, r, tree2, "merge\n", c2, side)
+	for _, sha := range []string{c1, c2, merge} {
+		tx := r.NewRefTransaction()
+		tx.Message = "test"
+		if err := tx.Update("HEAD", sha, ""); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	updateRef(t, r, "refs/heads/side", side)
+	tag, err := r.WriteTag("v1", c2, "version 1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	updateRef(t, r, "refs/tags/v1", tag)
+	idx := index.New()
+	idx.Add(index.Entry{Path: "staged", SHA: blobB, Mode: 0100644})
+	if err := r.WriteIndex(idx); err != nil {
+		t.Fatal(err)
+	}
+	// Two fake objects sharing a prefix make the prefix ambiguous.
+	for _, name := range []string{"abcd000000000000000000000000000000000001", "abcd000000000000000000000000000000000002"} {
+		p := filepath.Join(r.ObjectDir(), name[:2], name[2:])
+		if err := os.MkdirAll(filepath.Dir(p), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0444); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		rev, want, err string
+	}{
+		{rev: merge, want: merge},
+		{rev: strings.ToUpper(merge), want: merge},
+		{rev: merge[:7], want: merge},
+		{rev: "HEAD", want: merge},
+		{rev: "@", want: merge},
+		{rev: "master", want: merge},
+		{rev: "heads/master", want: merge},
+		{rev: "refs/heads/master", want: merge},
+		{rev: "side", want: side},
+		{rev: "@{0}", want: merge},
+		{rev: "@{1}", want: c2},
+		{rev: "master@{2}", want: c1},
+		{rev: "HEAD~", want: c2},
+		{rev: "HEAD~1", want: c2},
+		{rev: "HEAD~2", want: c1},
+		{rev: "HEAD^", want: c2},
+		{rev: "HEAD^1", want: c2},
+		{rev: "HEAD^2", want: side},
+		{rev: "HEAD^2~1", want: c1},
+		{rev: "HEAD^^", want: c1},
+		{rev: "HEAD^0", want: merge},
+		{rev: "v1", want: tag},
+		{rev: "v1^0", want: c2},
+		{rev: "v1^{}", want: c2},
+		{rev: "v1^{commit}", want: c2},
+		{rev: "v1^{tree}", want: tree2},
+		{rev: "HEAD^{tree}", want: tree2},
+		{rev: "HEAD~2^{tree}", want: tree1},
+		{rev: "HEAD:a", want: blobA},
+		{rev: "HEAD:dir", want: sub},
+		{rev: "HEAD:dir/b", want: blobB},
+		{rev: "HEAD~2:a", want: blobA},
+		{rev: "v1:dir/b", want: blobB},
+		{rev: ":staged", want: blobB},
+		{rev: "abcd", err: "short SHA abcd is ambiguous"},
+		{rev: "nosuchref", err: "unknown revision nosuchref"},
+		{rev: "dead", err: "unknown revision dead"},
+		{rev: "HEAD~3", err: "has no parent 1"},
+		{rev: "HEAD^3", err: "has no parent 3"},
+		{rev: "@{5}", err: "reflog of HEAD has only 3 entries"},
+		{rev: "HEAD^{tree", err: "missing '}'"},
+		{rev: "HEAD^{tree}^{commit}", err: "cannot peel to commit"},
+		{rev: "HEAD:nosuchfile", err: "path 'nosuchfile' does not exist in 'HEAD'"},
+		{rev: "HEAD:a/b", err: "path 'a/b' does not exist in 'HEAD'"},
+		{rev: ":nosuchfile", err: "path 'nosuchfile' does not exist in the index"},
+	}
+	for _, test := range tests {
+		got, err := r.Resolve(test.rev)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("Resolve(%q): got error %v, want %q", test.rev, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Resolve(%q): %v", test.rev, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Resolve(%q) = %s, want %s", test.rev, got, test.want)
+		}
+	}
+}
+
+func TestPeel(t *testing.T) {
+	r := newTestRepo(t)
+	blob := writeBlob(t, r, "a\n")
+	tree := writeTestTree(t, r, object.TreeEntry{Mode: object.ModeFile, Name: "a", SHA: blob})
+	commit := writeCommit(t, r, tree, "first\n")
+	tag, err := r.WriteTag("v1", commit, "version 1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tagOfTag, err := r.WriteTag("v1-signed", tag, "tag of a tag\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		sha, typ, want string
+	}{
+		{tagOfTag, "commit", commit},
+		{tagOfTag, "tree", tree},
+		{tagOfTag, "tag", tagOfTag},
+		{tagOfTag, "", commit},
+		{commit, "tree", tree},
+		{blob, "blob", blob},
+	}
+	for _, test := range tests {
+		got, err := r.Peel(test.sha, test.typ)
+		if err != nil || got != test.want {
+			t.Errorf("Peel(%s, %q) = %s, %v, want %s", test.sha, test.typ, got, err, test.want)
+		}
+	}
+	for _, typ := range []string{"commit", "tree"} {
+		if got, err := r.Peel(blob, typ); err == nil {
+			t.Errorf("Peel(blob, %s) = %s, want an error", typ, got)
+		}
+	}
+	if got, err := r.Peel(tree, "commit"); err == nil {
+		t.Errorf("Peel(tree, commit) = %s, want an error", got)
+	}
+}
+
+func TestResolvePath(t *testing.T) {
+	r := newTestRepo(t)
+	blob := writeBlob(t, r, "b\n")
+	dir := writeTestTree(t, r, object.TreeEntry{Mode: object.ModeFile, Name: "file", SHA: blob})
+	tree := writeTestTree(t, r, object.TreeEntry{Mode: object.ModeTree, Name: "dir", SHA: dir})
+	updateRef(t, r, "HEAD", writeCommit(t, r, tree, "commit\n"))
+	idx := index.New()
+	idx.Add(index.Entry{Path: "dir/file", SHA: blob, Mode: 0100644})
+	if err := r.WriteIndex(idx); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		rev, path, sha, typ, err string
+	}{
+		{rev: "HEAD", path: "", sha: tree, typ: "tree"},
+		{rev: "HEAD", path: "dir", sha: dir, typ: "tree"},
+		{rev: "HEAD", path: "dir/file", sha: blob, typ: "blob"},
+		{rev: "", path: "dir/file", sha: blob, typ: "blob"},
+		{rev: "HEAD", path: "dir/missing", err: "path 'dir/missing' does not exist in 'HEAD'"},
+		{rev: "HEAD", path: "dir/file/below", err: "path 'dir/file/below' does not exist in 'HEAD'"},
+		{rev: "", path: "dir", err: "path 'dir' does not exist in the index"},
+	}
+	for _, test := range tests {
+		sha, typ, err := r.ResolvePath(test.rev, test.path)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("ResolvePath(%q, %q): got error %v, want %q", test.rev, test.path, err, test.err)
+			}
+			continue
+		}
+		if err != nil || sha != test.sha || typ != test.typ {
+			t.Errorf("ResolvePath(%q, %q) = %s %s, %v, want %s %s", test.rev, test.path, sha, typ, err, test.sha, test.typ)
+		}
+	}
+}
+
+func TestFollowSymlinks(t *testing.T) {
+	r := newTestRepo(t)
+	blob := writeBlob(t, r, "target\n")
+	link := func(name, target string) object.TreeEntry {
+		return object.TreeEntry{Mode: object.ModeSymlink, Name: name, SHA: writeBlob(t, r, target)}
+	}
+	dir := writeTestTree(t, r,
+		link("escape", "../../outside"),
+		link("link", "../file"),
+		link("loop", "loop"),
+	)
+	tree := writeTestTree(t, r,
+		link("abs", "/etc/passwd"),
+		object.TreeEntry{Mode: object.ModeTree, Name: "dir", SHA: dir},
+		link("dirlink", "dir"),
+		object.TreeEntry{Mode: object.ModeFile, Name: "file", SHA: blob},
+		link("chain", "dir/link"),
+	)
+	updateRef(t, r, "HEAD", writeCommit(t, r, tree, "commit\n"))
+	tests := []struct {
+		path, sha, typ, err string
+	}{
+		{path: "file", sha: blob, typ: "blob"},
+		{path: "dir/link", sha: blob, typ: "blob"},
+		{path: "chain", sha: blob, typ: "blob"},
+		{path: "dirlink/link", sha: blob, typ: "blob"},
+		{path: "dirlink", sha: dir, typ: "tree"},
+		{path: "dir/escape", err: "path 'dir/escape' leaves the tree of 'HEAD'"},
+		{path: "abs", err: "path 'abs' leaves the tree of 'HEAD'"},
+		{path: "dir/loop", err: "path 'dir/loop' has too many levels of symbolic links"},
+		{path: "dir/missing", err: "path 'dir/missing' does not exist in 'HEAD'"},
+	}
+	for _, test := range tests {
+		sha, typ, err := r.FollowSymlinks("HEAD", test.path)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("FollowSymlinks(%q): got error %v, want %q", test.path, err, test.err)
+			}
+			continue
+		}
+		if err != nil || sha != test.sha || typ != test.typ {
+			t.Errorf("FollowSymlinks(%q) = %s %s, %v, want %s %s", test.path, sha, typ, err, test.sha, test.typ)
+		}
+	}
+	// without following links, the link itself is found
+	if sha, _, err := r.ResolvePath("HEAD", "chain"); err != nil || sha == blob {
+		t.Errorf("ResolvePath(chain) = %s, %v, want the link", sha, err)
+	}
+}
+
+// deepTree writes a commit of a tree with the given number of files in a
+// directory at the given depth, and returns the paths of the files.
+func deepTree(t testing.TB, r *Repository, depth, files int) []string {
+	t.Helper()
+	write := func(typ string, data []byte) string {
+		sha, err := r.WriteObject(&ObjectFile{ObjectType: typ, Data: data})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sha
+	}
+	var entries []object.TreeEntry
+	for i := 0; i < files; i++ {
+		sha := write("blob", []byte(fmt.Sprintf("file %d\n", i)))
+		entries = append(entries, object.TreeEntry{Mode: object.ModeFile, Name: fmt.Sprintf("f%03d", i), SHA: sha})
+	}
+	dir := ""
+	for i := depth; i > 0; i-- {
+		sha := write("tree", object.NewTree(entries).Serialize())
+		name := fmt.Sprintf("d%d", i)
+		entries = []object.TreeEntry{{Mode: object.ModeTree, Name: name, SHA: sha}}
+		dir = name + "/" + dir
+	}
+	tree := write("tree", object.NewTree(entries).Serialize())
+	commit, err := r.CommitTree(tree, nil, "commit\n", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.UpdateRef("HEAD", commit); err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for i := 0; i < files; i++ {
+		paths = append(paths, fmt.Sprintf("%sf%03d", dir, i))
+	}
+	return paths
+}
+
+func TestTreeCache(t *testing.T) {
+	r := newTestRepo(t)
+	paths := deepTree(t, r, 5, 20)
+	want := make(map[string]string)
+	for _, p := range paths {
+		sha, err := r.Resolve("HEAD:" + p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want[p] = sha
+	}
+	for _, size := range []int{100, 3} {
+		r.UseTreeCache(size)
+		for _, p := range append(paths, paths...) {
+			if sha, err := r.Resolve("HEAD:" + p); err != nil || sha != want[p] {
+				t.Errorf("size %d: Resolve(HEAD:%s) = %s, %v, want %s", size, p, sha, err, want[p])
+			}
+			if sha, _, err := r.FollowSymlinks("HEAD", p); err != nil || sha != want[p] {
+				t.Errorf("size %d: FollowSymlinks(HEAD, %s) = %s, %v, want %s", size, p, sha, err, want[p])
+			}
+			if len(r.trees) > size {
+				t.Fatalf("size %d: cache holds %d trees", size, len(r.trees))
+			}
+		}
+		// the root and the five directories are read once for all paths
+		if size == 100 && len(r.trees) != 6 {
+			t.Errorf("cache holds %d trees, want 6", len(r.trees))
+		}
+	}
+}
+
+func BenchmarkResolvePath(b *testing.B) {
+	for _, cached := range []bool{false, true} {
+		b.Run(fmt.Sprintf("cached=%v", cached), func(b *testing.B) {
+			r := newTestRepo(b)
+			paths := deepTree(b, r, 10, 100)
+			if cached {
+				r.UseTreeCache(1024)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for _, p := range paths {
+					if _, _, err := r.ResolvePath("HEAD", p); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestAbbreviateLooseAndPacked(t *testing.T) {
+	r := newTestRepo(t)
+	// the SHAs of these blobs share their first seven digits, 2c232cb
+	loose := writeBlob(t, r, "blob 654\n")
+	packed := writeBlob(t, r, "blob 10034\n")
+	if loose[:7] != packed[:7] || loose[:8] == packed[:8] {
+		t.Fatalf("%s and %s do not share exactly seven digits", loose, packed)
+	}
+	packLooseObjects(t, r, packed)
+	other := writeBlob(t, r, "other\n")
+
+	_, err := r.Resolve(loose[:7])
+	if want := "short SHA " + loose[:7] + " is ambiguous"; err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("got error %v, want %q", err, want)
+	} else if !strings.Contains(err.Error(), loose) || !strings.Contains(err.Error(), packed) {
+		t.Errorf("error %q does not list both candidates", err)
+	}
+	for _, sha := range []string{loose, packed} {
+		abbrev, err := r.Abbreviate(sha)
+		if err != nil || abbrev != sha[:8] {
+			t.Errorf("Abbreviate(%s) = %s, %v, want %s", sha, abbrev, err, sha[:8])
+		}
+		if got, err := r.Resolve(abbrev); err != nil || got != sha {
+			t.Errorf("Resolve(%s) = %s, %v, want %s", abbrev, got, err, sha)
+		}
+	}
+	// unambiguous SHAs are abbreviated to the minimum length
+	if abbrev, err := r.Abbreviate(other); err != nil || abbrev != other[:7] {
+		t.Errorf("Abbreviate(%s) = %s, %v, want %s", other, abbrev, err, other[:7])
+	}
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Signer creates armored detached signatures for commit payloads.
+type Signer interface {
+	Sign(payload []byte) ([]byte, error)
+}
+
+// GPGSigner signs payloads by running gpg.
+type GPGSigner struct {
+	// Program is the gpg executable.
+	Program string
+	// Key is the ID of the signing key.
+	Key string
+}
+
+// Sign implements Signer.
+func (s GPGSigner) Sign(payload []byte) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(s.Program, "--status-fd=2", "-bsau", s.Key)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("gpg failed to sign the data: %v\n%s", err, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "[GNUPG:] SIG_CREATED ") {
+		return nil, fmt.Errorf("gpg failed to sign the data:\n%s", stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+// Signer returns the signer configured by gpg.program and
+// user.signingKey. Without a signing key, the committer identity is used
+// to select the key.
+func (r *Repository) Signer() (Signer, error) {
+	program := r.ConfigValue("gpg", "program")
+	if program == "" {
+		program = "gpg"
+	}
+	if _, err := exec.LookPath(program); err != nil {
+		return nil, errors.Wrap(err, "cannot sign commit")
+	}
+	key := r.ConfigValue("user", "signingkey")
+	if key == "" {
+		name, email, err := r.Identity("committer")
+		if err != nil {
+			return nil, err
+		}
+		key = fmt.Sprintf("%s <%s>", name, email)
+	}
+	return GPGSigner{Program: program, Key: key}, nil
+}
+
+// SignByDefault returns whether commits are signed without being asked
+// to, as configured by commit.gpgSign.
+func (r *Repository) SignByDefault() bool {
+	return strings.EqualFold(r.ConfigValue("commit", "gpgsign"), "true")
+}
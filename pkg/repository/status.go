@@ -0,0 +1,368 @@
+package repository
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sboehler/got/pkg/index"
+	"github.com/sboehler/got/pkg/object"
+)
+
+// FileStatus is the status of a path, in the format of git status
+// --short: Staged describes the difference between HEAD and the index,
+// Unstaged the difference between the index and the worktree. Both are
+// one of ' ' (unmodified), 'A' (added), 'M' (modified), 'D' (deleted),
+// 'U' (unmerged) or '?' (untracked).
+type FileStatus struct {
+	Path             string
+	Staged, Unstaged byte
+}
+
+// TreeFiles returns the files of the given tree and its subtrees, keyed
+// by their path. The names of the returned entries are full paths.
+func (r *Repository) TreeFiles(sha string) (map[string]object.TreeEntry, error) {
+	res := make(map[string]object.TreeEntry)
+	return res, r.treeFiles(sha, "", res)
+}
+
+func (r *Repository) treeFiles(sha string, dir string, res map[string]object.TreeEntry) error {
+	t, err := r.ReadTree(sha)
+	if err != nil {
+		return err
+	}
+	for _, e := range t.Entries() {
+		if err := object.CheckEntryName(e.Name); err != nil {
+			return errors.Wrapf(err, "tree %s", sha)
+		}
+		e.Name = path.Join(dir, e.Name)
+		if e.Mode == object.ModeTree {
+			if err := r.treeFiles(e.SHA, e.Name, res); err != nil {
+				return err
+			}
+			continue
+		}
+		res[e.Name] = e
+	}
+	return nil
+}
+
+// HeadTree returns the SHA of the tree of the commit HEAD points to, or
+// the empty string if the current branch is unborn.
+func (r *Repository) HeadTree() (string, error) {
+	sha, err := r.ResolveRef("HEAD")
+	if errors.Cause(err) == ErrRefNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	c, err := r.ReadCommit(sha)
+	if err != nil {
+		return "", err
+	}
+	return c.Tree(), nil
+}
+
+// Status compares HEAD, the index and the worktree and returns the status
+// of all paths at or below the given paths which differ, sorted by path
+// with untracked paths last. Untracked directories are reported once,
+// with a trailing slash. If no
+// paths are given, the whole worktree is compared.
+func (r *Repository) Status(idx *index.Index, paths ...string) ([]FileStatus, error) {
+	if len(paths) == 0 {
+		paths = []string{""}
+	}
+	inPaths := func(p string) bool {
+		for _, dir := range paths {
+			if InPath(p, dir) {
+				return true
+			}
+		}
+		return false
+	}
+	head := make(map[string]object.TreeEntry)
+	tree, err := r.HeadTree()
+	if err != nil {
+		return nil, err
+	}
+	if tree != "" {
+		if head, err = r.TreeFiles(tree); err != nil {
+			return nil, err
+		}
+	}
+	// Paths are compared by their keys, so that paths differing only in
+	// case match if core.ignorecase is set.
+	headKeys := make(map[string]string, len(head))
+	for p := range head {
+		headKeys[r.PathKey(p)] = p
+	}
+	var files []string
+	worktree := make(map[string]string)
+	for _, dir := range paths {
+		found, err := r.WorktreeFiles(dir, idx)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range found {
+			worktree[r.PathKey(f)] = f
+		}
+		files = append(files, found...)
+	}
+	indexTime := r.indexTime()
+	res := make(map[string]*FileStatus)
+	status := func(p string) *FileStatus {
+		if res[p] == nil {
+			res[p] = &FileStatus{Path: p, Staged: ' ', Unstaged: ' '}
+		}
+		return res[p]
+	}
+	tracked := make(map[string]bool)
+	for _, e := range idx.Entries {
+		if !inPaths(e.Path) {
+			continue
+		}
+		key := r.PathKey(e.Path)
+		tracked[key] = true
+		if e.Stage != 0 {
+			s := status(e.Path)
+			s.Staged, s.Unstaged = 'U', 'U'
+			continue
+		}
+		if h, ok := head[headKeys[key]]; !ok {
+			status(e.Path).Staged = 'A'
+		} else if h.SHA != e.SHA || h.Mode != modeString(e.Mode) {
+			status(e.Path).Staged = 'M'
+		}
+		p, ok := worktree[key]
+		if !ok {
+			p = e.Path
+		}
+		if c, err := r.worktreeChange(e, p, indexTime); err != nil {
+			return nil, err
+		} else if c != ' ' {
+			status(e.Path).Unstaged = c
+		}
+	}
+	for p := range head {
+		if !tracked[r.PathKey(p)] && inPaths(p) {
+			status(p).Staged = 'D'
+		}
+	}
+	for _, f := range files {
+		if tracked[r.PathKey(f)] {
+			continue
+		}
+		p := untrackedDir(f, idx)
+		if _, ok := res[p]; !ok {
+			res[p] = &FileStatus{Path: p, Staged: '?', Unstaged: '?'}
+		}
+	}
+	list := make([]FileStatus, 0, len(res))
+	for _, s := range res {
+		list = append(list, *s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if ui, uj := list[i].Staged == '?', list[j].Staged == '?'; ui != uj {
+			return uj
+		}
+		return list[i].Path < list[j].Path
+	})
+	return list, nil
+}
+
+// WritePorcelain writes the statuses in the format of git status
+// --porcelain: one "XY path" entry per line, where X is the staged and Y
+// the unstaged status. Paths with special characters are quoted as git
+// does. If nul is set, entries are terminated with NUL bytes instead and
+// paths are written verbatim.
+func WritePorcelain(w io.Writer, statuses []FileStatus, nul bool) error {
+	for _, s := range statuses {
+		var err error
+		if nul {
+			_, err = fmt.Fprintf(w, "%c%c %s\x00", s.Staged, s.Unstaged, s.Path)
+		} else {
+			_, err = fmt.Fprintf(w, "%c%c %s\n", s.Staged, s.Unstaged, quotePath(s.Path))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// quotePath quotes a path in double quotes if it contains control
+// characters, quotes, backslashes or non-ASCII bytes, escaping them as C
+// does.
+func quotePath(p string) string {
+	needsQuote := false
+	for i := 0; i < len(p); i++ {
+		if c := p[i]; c < 0x20 || c >= 0x7f || c == '"' || c == '\\' {
+			needsQuote = true
+			break
+		}
+	}
+	if !needsQuote {
+		return p
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(p); i++ {
+		switch c := p[i]; c {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		default:
+			if c < 0x20 || c >= 0x7f {
+				fmt.Fprintf(&b, "\\%03o", c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// worktreeChange compares the index entry with the worktree file at path
+// p, which differs from the path of the entry only in case. Files whose
+// stat data matches the entry are assumed unchanged, unless they were
+// modified in the same instant as the index was written.
+func (r *Repository) worktreeChange(e index.Entry, p string, indexTime int64) (byte, error) {
+	if e.Mode == index.ModeSubmodule {
+		return ' ', nil
+	}
+	abs := filepath.Join(r.Worktree, filepath.FromSlash(p))
+	info, err := os.Lstat(abs)
+	if os.IsNotExist(err) || err == nil && info.IsDir() {
+		return 'D', nil
+	}
+	if err != nil {
+		return 0, errors.Wrapf(err, "error reading %s", p)
+	}
+	racy := e.MTime.UnixNano() >= indexTime
+	if !e.Stale(info) && !racy {
+		return ' ', nil
+	}
+	if index.ModeOf(info) != e.Mode {
+		return 'M', nil
+	}
+	var data []byte
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(abs)
+		if err != nil {
+			return 0, errors.Wrapf(err, "error reading %s", p)
+		}
+		data = []byte(filepath.ToSlash(target))
+	} else {
+		if data, err = os.ReadFile(abs); err != nil {
+			return 0, errors.Wrapf(err, "error reading %s", p)
+		}
+		if data, err = r.CleanContent(e.Path, data); err != nil {
+			return 0, err
+		}
+	}
+	if Hash(&ObjectFile{ObjectType: "blob", Data: data}) != e.SHA {
+		return 'M', nil
+	}
+	return ' ', nil
+}
+
+// untrackedDir returns the outermost directory containing the untracked
+// file p which has no tracked files, with a trailing slash, or p itself
+// if its directory has tracked files.
+func untrackedDir(p string, idx *index.Index) string {
+	for i := 0; ; i++ {
+		j := strings.IndexByte(p[i:], '/')
+		if j < 0 {
+			return p
+		}
+		i += j
+		if !idx.HasDir(p[:i]) {
+			return p[:i+1]
+		}
+	}
+}
+
+// modeString formats an index mode as in tree entries.
+func modeString(mode uint32) string {
+	return strings.TrimLeft(strconv.FormatUint(uint64(mode), 8), "0")
+}
+
+// IsClean returns whether the index and the worktree match HEAD, and the
+// tracked paths which differ, sorted by path. Untracked files are not
+// considered. The comparison stops after limit differences, so callers
+// which only need to know whether the worktree is clean pass 1. Zero
+// means no limit.
+func (r *Repository) IsClean(idx *index.Index, limit int) (bool, []string, error) {
+	head := make(map[string]object.TreeEntry)
+	tree, err := r.HeadTree()
+	if err != nil {
+		return false, nil, err
+	}
+	if tree != "" {
+		if head, err = r.TreeFiles(tree); err != nil {
+			return false, nil, err
+		}
+	}
+	headKeys := make(map[string]string, len(head))
+	for p := range head {
+		headKeys[r.PathKey(p)] = p
+	}
+	var (
+		dirty     []string
+		indexTime = r.indexTime()
+		tracked   = make(map[string]bool)
+	)
+	full := func(p string) bool {
+		dirty = append(dirty, p)
+		return limit > 0 && len(dirty) >= limit
+	}
+	for _, e := range idx.Entries {
+		key := r.PathKey(e.Path)
+		if tracked[key] {
+			// further stages of a conflicted path
+			continue
+		}
+		tracked[key] = true
+		changed := e.Stage != 0
+		if !changed {
+			h, ok := head[headKeys[key]]
+			changed = !ok || h.SHA != e.SHA || h.Mode != modeString(e.Mode)
+		}
+		if !changed {
+			c, err := r.worktreeChange(e, e.Path, indexTime)
+			if err != nil {
+				return false, nil, err
+			}
+			changed = c != ' '
+		}
+		if changed && full(e.Path) {
+			return false, dirty, nil
+		}
+	}
+	deleted := make([]string, 0, len(head))
+	for p := range head {
+		if !tracked[r.PathKey(p)] {
+			deleted = append(deleted, p)
+		}
+	}
+	sort.Strings(deleted)
+	for _, p := range deleted {
+		if full(p) {
+			break
+		}
+	}
+	sort.Strings(dirty)
+	return len(dirty) == 0, dirty, nil
+}
@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sboehler/got/pkg/index"
+)
+
+// writeWorktreeFile writes a file to the worktree of r.
+func writeWorktreeFile(t *testing.T, r *Repository, p, content string) {
+	t.Helper()
+	abs := filepath.Join(r.Worktree, filepath.FromSlash(p))
+	if err := os.MkdirAll(filepath.Dir(abs), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(abs, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// commitWorktree stages the given paths and commits the index to the
+// current branch. It returns the index.
+func commitWorktree(t *testing.T, r *Repository, paths ...string) *index.Index {
+	t.Helper()
+	idx, err := r.ReadIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range paths {
+		if _, err := r.StageFile(idx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.WriteIndex(idx); err != nil {
+		t.Fatal(err)
+	}
+	tree, err := r.WriteTree(idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updateRef(t, r, "refs/heads/master", writeCommit(t, r, tree, "commit\n"))
+	return idx
+}
+
+func TestStatusPorcelain(t *testing.T) {
+	r := newTestRepo(t)
+	writeWorktreeFile(t, r, "modified", "a\n")
+	writeWorktreeFile(t, r, "deleted", "a\n")
+	writeWorktreeFile(t, r, "both", "a\n")
+	idx := commitWorktree(t, r, "modified", "deleted", "both")
+
+	writeWorktreeFile(t, r, "modified", "changed\n")
+	if err := os.Remove(filepath.Join(r.Worktree, "deleted")); err != nil {
+		t.Fatal(err)
+	}
+	writeWorktreeFile(t, r, "both", "staged\n")
+	writeWorktreeFile(t, r, "added", "new\n")
+	for _, p := range []string{"both", "added"} {
+		if _, err := r.StageFile(idx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeWorktreeFile(t, r, "both", "unstaged\n")
+	writeWorktreeFile(t, r, "untracked", "?\n")
+	writeWorktreeFile(t, r, "dir/untracked", "?\n")
+
+	statuses, err := r.Status(idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WritePorcelain(&buf, statuses, false); err != nil {
+		t.Fatal(err)
+	}
+	want := "A  added\n" +
+		"MM both\n" +
+		" D deleted\n" +
+		" M modified\n" +
+		"?? dir/\n" +
+		"?? untracked\n"
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := WritePorcelain(&buf, statuses[:2], true); err != nil {
+		t.Fatal(err)
+	}
+	if want := "A  added\x00MM both\x00"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestWritePorcelainQuoting(t *testing.T) {
+	statuses := []FileStatus{{Path: "a \"b\"\tc\\d\xc3\xa9", Staged: '?', Unstaged: '?'}}
+	var buf bytes.Buffer
+	if err := WritePorcelain(&buf, statuses, false); err != nil {
+		t.Fatal(err)
+	}
+	if want := `?? "a \"b\"\tc\\d\303\251"` + "\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+	buf.Reset()
+	if err := WritePorcelain(&buf, statuses, true); err != nil {
+		t.Fatal(err)
+	}
+	if want := "?? " + statuses[0].Path + "\x00"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+func TestStatusIgnoreCase(t *testing.T) {
+	r := newTestRepo(t)
+	writeWorktreeFile(t, r, "README", "read me\n")
+	idx := commitWorktree(t, r, "README")
+	// A case-only rename, as seen on a case-insensitive file system.
+	if err := os.Rename(filepath.Join(r.Worktree, "README"), filepath.Join(r.Worktree, "readme")); err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := r.Status(idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 2 {
+		t.Errorf("got %+v, want README deleted and readme untracked without core.ignorecase", statuses)
+	}
+
+	r.Config.Section("core").Key("ignorecase").SetValue("true")
+	if statuses, err = r.Status(idx); err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 0 {
+		t.Errorf("got %+v, want no changes with core.ignorecase", statuses)
+	}
+	writeWorktreeFile(t, r, "readme", "changed\n")
+	if statuses, err = r.Status(idx); err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 1 || statuses[0].Path != "README" || statuses[0].Unstaged != 'M' {
+		t.Errorf("got %+v, want README modified", statuses)
+	}
+}
+
+func TestIgnorerIgnoreCase(t *testing.T) {
+	r := newTestRepo(t)
+	writeWorktreeFile(t, r, ".gitignore", "Build/\n*.LOG\n")
+	writeWorktreeFile(t, r, "Docs/.gitignore", "/Draft.txt\n")
+	paths := []struct {
+		p     string
+		isDir bool
+	}{{"build", true}, {"a.log", false}, {"Docs/draft.txt", false}}
+
+	ig := r.NewIgnorer()
+	for _, p := range paths {
+		if ig.Ignored(p.p, p.isDir) {
+			t.Errorf("%s is ignored without core.ignorecase", p.p)
+		}
+	}
+	r.Config.Section("core").Key("ignorecase").SetValue("true")
+	ig = r.NewIgnorer()
+	for _, p := range paths {
+		if !ig.Ignored(p.p, p.isDir) {
+			t.Errorf("%s is not ignored with core.ignorecase", p.p)
+		}
+	}
+	if ig.Ignored("Docs/other.txt", false) {
+		t.Error("Docs/other.txt is ignored")
+	}
+}
+
+func TestIsClean(t *testing.T) {
+	r := newTestRepo(t)
+	for _, p := range []string{"a", "b", "c"} {
+		writeWorktreeFile(t, r, p, p+"\n")
+	}
+	idx := commitWorktree(t, r, "a", "b", "c")
+	writeWorktreeFile(t, r, "untracked", "?\n")
+	if clean, dirty, err := r.IsClean(idx, 0); err != nil || !clean || len(dirty) != 0 {
+		t.Fatalf("IsClean of a fresh commit = %v, %v, %v", clean, dirty, err)
+	}
+
+	writeWorktreeFile(t, r, "a", "changed\n")
+	if err := os.Remove(filepath.Join(r.Worktree, "b")); err != nil {
+		t.Fatal(err)
+	}
+	idx.Remove("c")
+	clean, dirty, err := r.IsClean(idx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a,b,c"; clean || strings.Join(dirty, ",") != want {
+		t.Errorf("IsClean = %v, %v, want false, %s", clean, dirty, want)
+	}
+	// With a limit, the comparison stops at the first difference.
+	clean, dirty, err = r.IsClean(idx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clean || len(dirty) != 1 || dirty[0] != "a" {
+		t.Errorf("IsClean with limit 1 = %v, %v, want false, [a]", clean, dirty)
+	}
+}
@@ -0,0 +1,324 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sboehler/got/pkg/timefmt"
+)
+
+// RefTransaction updates several refs atomically: either all updates are
+// applied, or none.
+type RefTransaction struct {
+	// Message is recorded in the reflogs of the updated refs.
+	Message string
+
+	r       *Repository
+	updates []refUpdate
+	locks   []string
+	// packedLock is the lock of packed-refs, which is taken when refs
+	// are deleted.
+	packedLock string
+	done       bool
+}
+
+type refUpdate struct {
+	name, new, old string
+	// symref is the symbolic ref through which name was updated, if any.
+	symref  string
+	noDeref bool
+	delete  bool
+}
+
+// NewRefTransaction starts a new ref transaction.
+func (r *Repository) NewRefTransaction() *RefTransaction {
+	return &RefTransaction{r: r}
+}
+
+// Update adds an update of the ref with the given name to newSHA. If
+// oldSHA is not empty, the update only succeeds if the ref currently has
+// this value; the zero SHA requires the ref not to exist. Symbolic refs
+// are followed, so updating HEAD updates the current branch.
+func (tx *RefTransaction) Update(name, newSHA, oldSHA string) error {
+	return tx.update(refUpdate{name: name, new: newSHA, old: oldSHA})
+}
+
+// UpdateNoDeref is like Update, but replaces a symbolic ref instead of
+// following it. Updating HEAD this way detaches it.
+func (tx *RefTransaction) UpdateNoDeref(name, newSHA, oldSHA string) error {
+	return tx.update(refUpdate{name: name, new: newSHA, old: oldSHA, noDeref: true})
+}
+
+// Delete adds the deletion of the ref with the given name, including its
+// reflog. If oldSHA is not empty, the ref must currently have this value.
+// Symbolic refs are not followed.
+func (tx *RefTransaction) Delete(name, oldSHA string) error {
+	return tx.update(refUpdate{name: name, old: oldSHA, noDeref: true, delete: true})
+}
+
+func (tx *RefTransaction) update(u refUpdate) error {
+	if tx.done {
+		return fmt.Errorf("ref transaction is already closed")
+	}
+	if err := ValidateRefName(u.name); err != nil {
+		return err
+	}
+	if !u.delete && (len(u.new) != 40 || !isHex(u.new)) {
+		return fmt.Errorf("invalid SHA %s for ref %s", u.new, u.name)
+	}
+	tx.updates = append(tx.updates, u)
+	return nil
+}
+
+// Commit locks all refs of the transaction, checks their old values and
+// applies the updates. If a check fails, all locks are released and no
+// ref is changed. Failures while applying the updates are not rolled
+// back: the refs updated before the failure keep their new values, and
+// the locks of the others are released.
+func (tx *RefTransaction) Commit() error {
+	if tx.done {
+		return fmt.Errorf("ref transaction is already closed")
+	}
+	if tx.r.ReadOnly {
+		for _, u := range tx.updates {
+			if u.delete {
+				tx.r.dryRun("delete %s", u.name)
+			} else {
+				tx.r.dryRun("update %s to %s", u.name, u.new)
+			}
+		}
+		tx.done = true
+		return nil
+	}
+	olds, err := tx.prepare()
+	if err != nil {
+		tx.Abort()
+		return err
+	}
+	tx.done = true
+	if tx.packedLock != "" {
+		if err := os.Rename(tx.packedLock, tx.r.GitPath("packed-refs")); err != nil {
+			for _, l := range tx.locks {
+				os.Remove(l)
+			}
+			return errors.Wrap(err, "error writing packed-refs")
+		}
+	}
+	for i, u := range tx.updates {
+		if u.delete {
+			if err := tx.r.deleteLooseRef(u.name, tx.locks[i]); err != nil {
+				for _, l := range tx.locks[i+1:] {
+					os.Remove(l)
+				}
+				return err
+			}
+			continue
+		}
+		if err := os.Rename(tx.locks[i], tx.r.GitPath(u.name)); err != nil {
+			for _, l := range tx.locks[i:] {
+				os.Remove(l)
+			}
+			return errors.Wrapf(err, "error updating ref %s", u.name)
+		}
+	}
+	for i, u := range tx.updates {
+		if u.delete {
+			continue
+		}
+		if err := tx.r.appendReflog(u.name, olds[i], u.new, tx.Message); err != nil {
+			return err
+		}
+		if u.symref == "" {
+			continue
+		}
+		if err := tx.r.appendReflog(u.symref, olds[i], u.new, tx.Message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prepare resolves symbolic refs, locks all refs, checks their old values
+// and writes the new values to the lock files. It returns the current
+// values of the refs.
+func (tx *RefTransaction) prepare() ([]string, error) {
+	var (
+		seen    = make(map[string]bool)
+		deleted = make(map[string]bool)
+		olds    = make([]string, len(tx.updates))
+	)
+	for i := range tx.updates {
+		u := &tx.updates[i]
+		name := u.name
+		if !u.noDeref {
+			var err error
+			if name, err = tx.r.derefName(u.name); err != nil {
+				return nil, err
+			}
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("multiple updates for ref %s", name)
+		}
+		seen[name] = true
+		if name != u.name {
+			u.symref, u.name = u.name, name
+		}
+		lock, err := tx.r.lockRef(name)
+		if err != nil {
+			return nil, err
+		}
+		tx.locks = append(tx.locks, lock)
+		cur, err := tx.r.ResolveRef(name)
+		if err != nil && errors.Cause(err) != ErrRefNotFound {
+			return nil, err
+		}
+		if u.old == ZeroSHA && cur != "" {
+			return nil, fmt.Errorf("cannot create ref %s: it already exists", name)
+		}
+		if u.old != "" && u.old != ZeroSHA && u.old != cur {
+			return nil, fmt.Errorf("cannot update ref %s: expected %s, found %s", name, u.old, cur)
+		}
+		olds[i] = cur
+		if u.delete {
+			if cur == "" {
+				return nil, fmt.Errorf("cannot delete ref %s: it does not exist", name)
+			}
+			deleted[name] = true
+			continue
+		}
+		if err := os.WriteFile(lock, []byte(u.new+"\n"), 0666); err != nil {
+			return nil, errors.Wrapf(err, "error updating ref %s", name)
+		}
+	}
+	packed, err := tx.r.PackedRefs()
+	if err != nil {
+		return nil, err
+	}
+	var repack bool
+	for name := range deleted {
+		_, ok := packed[name]
+		repack = repack || ok
+	}
+	if repack {
+		lock, err := tx.r.lockRef("packed-refs")
+		if err != nil {
+			return nil, err
+		}
+		tx.packedLock = lock
+		if err := tx.r.writePackedRefsWithout(lock, deleted); err != nil {
+			return nil, err
+		}
+	}
+	return olds, nil
+}
+
+// Abort releases all locks without changing any ref.
+func (tx *RefTransaction) Abort() {
+	if tx.done {
+		return
+	}
+	for _, l := range append(tx.locks, tx.packedLock) {
+		if l != "" {
+			os.Remove(l)
+		}
+	}
+	tx.locks, tx.packedLock = nil, ""
+	tx.done = true
+}
+
+// deleteLooseRef removes the loose ref with the given name, if any, its
+// lock and its reflog.
+func (r *Repository) deleteLooseRef(name, lock string) error {
+	defer os.Remove(lock)
+	if err := os.Remove(r.GitPath(name)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "error deleting ref %s", name)
+	}
+	if err := os.Remove(r.GitPath("logs", name)); err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "error deleting reflog of %s", name)
+	}
+	return nil
+}
+
+// derefName follows symbolic refs from the given name and returns the
+// name of the ref which holds a SHA, or would hold it if it existed.
+func (r *Repository) derefName(name string) (string, error) {
+	for i := 0; i < 10; i++ {
+		content, err := r.ReadRef(name)
+		if errors.Cause(err) == ErrRefNotFound {
+			return name, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if !strings.HasPrefix(content, symrefPrefix) {
+			return name, nil
+		}
+		name = strings.TrimPrefix(content, symrefPrefix)
+	}
+	return "", fmt.Errorf("too many levels of symbolic refs at %s", name)
+}
+
+// lockRef creates the lock file of the given ref and returns its path.
+func (r *Repository) lockRef(name string) (string, error) {
+	lock := r.GitPath(name) + ".lock"
+	if err := os.MkdirAll(filepath.Dir(lock), dirperms); err != nil {
+		return "", errors.Wrapf(err, "error locking ref %s", name)
+	}
+	f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+	if err != nil {
+		if os.IsExist(err) {
+			return "", fmt.Errorf("cannot lock ref %s: %s exists", name, lock)
+		}
+		return "", errors.Wrapf(err, "error locking ref %s", name)
+	}
+	return lock, f.Close()
+}
+
+// appendReflog records an update of the given ref in its reflog. Reflogs
+// are kept for branches, remote-tracking refs and HEAD in repositories
+// with a worktree, and for every ref which already has a reflog.
+func (r *Repository) appendReflog(name, old, new, msg string) error {
+	p := r.GitPath("logs", name)
+	if _, err := os.Stat(p); err != nil {
+		logged := name == "HEAD" || strings.HasPrefix(name, "refs/heads/") || strings.HasPrefix(name, "refs/remotes/")
+		if r.Bare || !logged {
+			return nil
+		}
+	}
+	if old == "" {
+		old = ZeroSHA
+	}
+	if err := os.MkdirAll(filepath.Dir(p), dirperms); err != nil {
+		return errors.Wrapf(err, "error writing reflog of %s", name)
+	}
+	f, err := os.OpenFile(p, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		return errors.Wrapf(err, "error writing reflog of %s", name)
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "%s %s %s %s\t%s\n", old, new, r.reflogIdentity(), timefmt.Format(time.Now()), msg)
+	if err != nil {
+		return errors.Wrapf(err, "error writing reflog of %s", name)
+	}
+	return errors.Wrapf(f.Close(), "error writing reflog of %s", name)
+}
+
+// reflogIdentity returns the committer identity for reflog entries,
+// falling back to the current user if none is configured.
+func (r *Repository) reflogIdentity() string {
+	name, email, err := r.Identity("committer")
+	if err != nil {
+		name = "unknown"
+		if u, err := user.Current(); err == nil {
+			name = u.Username
+		}
+		host, _ := os.Hostname()
+		email = name + "@" + host
+	}
+	return fmt.Sprintf("%s <%s>", name, email)
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+// lockFiles returns the lock files in the repository's git directory.
+func lockFiles(t *testing.T, r *Repository) []string {
+	t.Helper()
+	var res []string
+	err := filepath.WalkDir(r.GitPath(), func(p string, d fs.DirEntry, err error) error {
+		if err == nil && strings.HasSuffix(p, ".lock") {
+			res = append(res, p)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestRefTransactionStale(t *testing.T) {
+	r := newTestRepo(t)
+	shas := linearHistory(t, r, 2)
+	updateRef(t, r, "refs/heads/a", shas[0])
+	updateRef(t, r, "refs/heads/b", shas[0])
+
+	tx := r.NewRefTransaction()
+	if err := tx.Update("refs/heads/a", shas[1], shas[0]); err != nil {
+		t.Fatal(err)
+	}
+	// b has moved on since it was read
+	if err := tx.Update("refs/heads/b", shas[1], shas[1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err == nil || !strings.Contains(err.Error(), "expected") {
+		t.Fatalf("got error %v, want a stale value", err)
+	}
+	for _, name := range []string{"refs/heads/a", "refs/heads/b"} {
+		if sha, err := r.ResolveRef(name); err != nil || sha != shas[0] {
+			t.Errorf("%s: got %s, %v, want %s", name, sha, err, shas[0])
+		}
+	}
+	if locks := lockFiles(t, r); len(locks) > 0 {
+		t.Errorf("got lock files %v", locks)
+	}
+}
+
+func TestRefTransactionDuplicate(t *testing.T) {
+	r := newTestRepo(t)
+	shas := linearHistory(t, r, 2)
+	_, branch, err := r.CheckHead()
+	if err != nil {
+		t.Fatal(err)
+	}
+	updateRef(t, r, branch, shas[0])
+
+	// HEAD resolves to the branch, which is updated twice
+	tx := r.NewRefTransaction()
+	if err := tx.Update("HEAD", shas[1], ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Update(branch, shas[1], ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err == nil || !strings.Contains(err.Error(), "multiple updates") {
+		t.Fatalf("got error %v, want multiple updates", err)
+	}
+	if sha, err := r.ResolveRef(branch); err != nil || sha != shas[0] {
+		t.Errorf("got %s, %v, want %s", sha, err, shas[0])
+	}
+	if locks := lockFiles(t, r); len(locks) > 0 {
+		t.Errorf("got lock files %v", locks)
+	}
+}
+
+func TestRefsSkipsLocks(t *testing.T) {
+	r := newTestRepo(t)
+	shas := linearHistory(t, r, 1)
+	updateRef(t, r, "refs/heads/a", shas[0])
+	if _, err := r.lockRef("refs/heads/b"); err != nil {
+		t.Fatal(err)
+	}
+	refs, err := r.Refs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refs) != 1 || refs[0].Name != "refs/heads/a" {
+		t.Errorf("got refs %v, want only refs/heads/a", refs)
+	}
+	if _, err := r.ResolveRef("refs/heads/b"); errors.Cause(err) != ErrRefNotFound {
+		t.Errorf("got error %v for a locked new ref", err)
+	}
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sboehler/got/pkg/index"
+	"github.com/sboehler/got/pkg/object"
+)
+
+// EmptyTree is the SHA of the tree without entries.
+const EmptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// WriteTree writes the tree objects for the given index and returns the
+// SHA of the root tree. Directories become subtrees. The index must not
+// have unmerged entries, and all its blobs must exist. Trees are recorded
+// in the index's cache tree, and directories whose cached tree is still
+// valid are not rewritten; the index must be written to persist the cache.
+func (r *Repository) WriteTree(idx *index.Index) (string, error) {
+	if idx.Conflicted() {
+		return "", fmt.Errorf("cannot write tree: the index has unmerged entries")
+	}
+	if idx.Tree == nil {
+		idx.Tree = &index.CacheTree{Entries: -1}
+	}
+	return r.writeTree(idx.Entries, "", idx.Tree)
+}
+
+// writeTree writes the tree of the directory prefix, which is empty or
+// ends with a slash, from the sorted entries below it, and updates its
+// cache tree.
+func (r *Repository) writeTree(entries []index.Entry, prefix string, ct *index.CacheTree) (string, error) {
+	if ct.Valid() && ct.Entries == len(entries) && r.HasObject(ct.SHA) {
+		return ct.SHA, nil
+	}
+	var (
+		res      []object.TreeEntry
+		subtrees []*index.CacheTree
+		valid    = true
+	)
+	for i := 0; i < len(entries); {
+		e := entries[i]
+		name := e.Path[len(prefix):]
+		if j := strings.IndexByte(name, '/'); j >= 0 {
+			name = name[:j]
+			if err := object.CheckEntryName(name); err != nil {
+				return "", errors.Wrapf(err, "index entry %s", e.Path)
+			}
+			dir := prefix + name + "/"
+			k := i + 1
+			for k < len(entries) && strings.HasPrefix(entries[k].Path, dir) {
+				k++
+			}
+			sub := ct.Subtree(name)
+			if sub == nil {
+				sub = &index.CacheTree{Name: name, Entries: -1}
+			}
+			sha, err := r.writeTree(entries[i:k], dir, sub)
+			if err != nil {
+				return "", err
+			}
+			valid = valid && sub.Valid()
+			subtrees = append(subtrees, sub)
+			res = append(res, object.TreeEntry{Mode: object.ModeTree, Name: name, SHA: sha})
+			i = k
+			continue
+		}
+		i++
+		if e.IntentToAdd {
+			valid = false
+			continue
+		}
+		if err := object.CheckEntryName(name); err != nil {
+			return "", errors.Wrapf(err, "index entry %s", e.Path)
+		}
+		if e.Mode != index.ModeSubmodule && !r.HasObject(e.SHA) {
+			return "", fmt.Errorf("invalid object %s for %s", e.SHA, e.Path)
+		}
+		res = append(res, object.TreeEntry{Mode: modeString(e.Mode), Name: name, SHA: e.SHA})
+	}
+	sha, err := r.WriteObject(&ObjectFile{ObjectType: "tree", Data: object.NewTree(res).Serialize()})
+	if err != nil {
+		return "", err
+	}
+	ct.SHA, ct.Entries, ct.Subtrees = sha, len(entries), subtrees
+	if !valid {
+		// trees with intent-to-add entries must be rebuilt once they are added
+		ct.Entries = -1
+	}
+	return sha, nil
+}
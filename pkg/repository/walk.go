@@ -0,0 +1,291 @@
+package repository
+
+import (
+	"container/heap"
+	"time"
+
+	"github.com/sboehler/got/pkg/object"
+)
+
+// Order is the order in which a walk returns commits.
+type Order int
+
+const (
+	// DateOrder returns the newest commit first, by committer date.
+	DateOrder Order = iota
+	// Topological returns a commit only after all its children, and the
+	// newest ready commit first.
+	Topological
+)
+
+// CommitWalker iterates over the commits reachable from a set of start
+// commits, excluding those reachable from hidden commits. Like git log,
+// it returns the newest commit first, ordered by committer date, and each
+// commit only once.
+type CommitWalker struct {
+	// Order is the order of the walk. A topological walk reads all
+	// commits before it returns the first one.
+	Order Order
+	// Depth limits the walk to the given number of commits along each
+	// path from a start commit. The parents of commits at the limit are
+	// not read. Zero means no limit.
+	Depth int
+	// FirstParent follows only the first parent of merge commits.
+	FirstParent bool
+	// Paths limits the commits returned to those which change one of the
+	// given paths, or files below them, compared to their first parent.
+	// The history of the other commits is still walked.
+	Paths []string
+
+	r        *Repository
+	queue    commitQueue
+	seen     map[string]bool
+	hidden   map[string]bool
+	depths   map[string]int
+	boundary map[string]bool
+	n        int
+
+	// the state of a topological walk: the commits whose children were
+	// all returned, and the number of unreturned children of the others
+	sorted   bool
+	ready    commitQueue
+	children map[string]int
+	pending  map[string]queuedCommit
+}
+
+// NewCommitWalker creates a walker without start commits.
+func (r *Repository) NewCommitWalker() *CommitWalker {
+	return &CommitWalker{
+		r:        r,
+		seen:     make(map[string]bool),
+		hidden:   make(map[string]bool),
+		depths:   make(map[string]int),
+		boundary: make(map[string]bool),
+	}
+}
+
+// Push adds a start commit to the walk.
+func (w *CommitWalker) Push(sha string) error {
+	return w.push(sha, 1)
+}
+
+// push queues a commit which is depth commits away from a start commit.
+// A commit reached on a shorter path before it is returned takes the
+// shorter depth.
+func (w *CommitWalker) push(sha string, depth int) error {
+	if w.hidden[sha] {
+		return nil
+	}
+	if w.seen[sha] {
+		if d, ok := w.depths[sha]; ok && depth < d {
+			w.depths[sha] = depth
+		}
+		return nil
+	}
+	c, err := w.r.ReadCommit(sha)
+	if err != nil {
+		return err
+	}
+	w.seen[sha] = true
+	w.depths[sha] = depth
+	heap.Push(&w.queue, queuedCommit{sha: sha, commit: c, when: c.Committer().When, seq: w.n})
+	w.n++
+	return nil
+}
+
+// IsBoundary returns whether the given commit, which was returned by the
+// walk, is at the depth limit and has parents which were not walked.
+func (w *CommitWalker) IsBoundary(sha string) bool {
+	return w.boundary[sha]
+}
+
+// Parents returns the parents of a commit returned by the walk which the
+// walk follows: hidden parents are left out, as are all but the first
+// parent with FirstParent and all parents of boundary commits.
+func (w *CommitWalker) Parents(sha string, c *object.Commit) []string {
+	if w.boundary[sha] {
+		return nil
+	}
+	parents := c.Parents()
+	if w.FirstParent && len(parents) > 1 {
+		parents = parents[:1]
+	}
+	var res []string
+	for _, p := range parents {
+		if !w.hidden[p] {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// Hide excludes the given commit and its ancestors from the walk. Commits
+// already returned are not affected.
+func (w *CommitWalker) Hide(sha string) error {
+	if w.hidden[sha] {
+		return nil
+	}
+	reachable, err := w.r.reachableCommits(sha)
+	if err != nil {
+		return err
+	}
+	for c := range reachable {
+		w.hidden[c] = true
+	}
+	return nil
+}
+
+// Next returns the next commit of the walk and queues its parents. At the
+// end of the walk, it returns an empty SHA.
+func (w *CommitWalker) Next() (string, *object.Commit, error) {
+	for {
+		var (
+			qc  queuedCommit
+			err error
+		)
+		if w.Order == Topological {
+			qc, err = w.nextTopological()
+		} else {
+			qc, err = w.nextByDate()
+		}
+		if err != nil || qc.sha == "" {
+			return "", nil, err
+		}
+		if len(w.Paths) > 0 {
+			changed, err := w.r.changesPaths(qc.commit, w.Paths)
+			if err != nil {
+				return "", nil, err
+			}
+			if !changed {
+				continue
+			}
+		}
+		return qc.sha, qc.commit, nil
+	}
+}
+
+// nextByDate returns the newest queued commit and queues its parents.
+func (w *CommitWalker) nextByDate() (queuedCommit, error) {
+	for w.queue.Len() > 0 {
+		qc := heap.Pop(&w.queue).(queuedCommit)
+		if w.hidden[qc.sha] {
+			continue
+		}
+		depth := w.depths[qc.sha]
+		delete(w.depths, qc.sha)
+		parents := qc.commit.Parents()
+		if w.FirstParent && len(parents) > 1 {
+			parents = parents[:1]
+		}
+		if w.Depth > 0 && depth >= w.Depth {
+			w.boundary[qc.sha] = len(parents) > 0
+			parents = nil
+		}
+		for _, p := range parents {
+			if err := w.push(p, depth+1); err != nil {
+				return queuedCommit{}, err
+			}
+		}
+		return qc, nil
+	}
+	return queuedCommit{}, nil
+}
+
+// nextTopological returns the newest commit whose children were all
+// returned. On the first call, it reads the whole walk by date and
+// counts the children of each commit, as in Kahn's algorithm.
+func (w *CommitWalker) nextTopological() (queuedCommit, error) {
+	if !w.sorted {
+		w.sorted = true
+		w.children = make(map[string]int)
+		w.pending = make(map[string]queuedCommit)
+		var commits []queuedCommit
+		for {
+			qc, err := w.nextByDate()
+			if err != nil {
+				return queuedCommit{}, err
+			}
+			if qc.sha == "" {
+				break
+			}
+			commits = append(commits, qc)
+			for _, p := range w.Parents(qc.sha, qc.commit) {
+				w.children[p]++
+			}
+		}
+		for _, qc := range commits {
+			if w.children[qc.sha] == 0 {
+				heap.Push(&w.ready, qc)
+			} else {
+				w.pending[qc.sha] = qc
+			}
+		}
+	}
+	if w.ready.Len() == 0 {
+		return queuedCommit{}, nil
+	}
+	qc := heap.Pop(&w.ready).(queuedCommit)
+	for _, p := range w.Parents(qc.sha, qc.commit) {
+		w.children[p]--
+		if pc, ok := w.pending[p]; ok && w.children[p] == 0 {
+			heap.Push(&w.ready, pc)
+			delete(w.pending, p)
+		}
+	}
+	return qc, nil
+}
+
+// WalkCommits visits the commits reachable from the given start commits
+// in the given order, and each commit only once.
+func (r *Repository) WalkCommits(starts []string, order Order, visit func(*object.Commit) error) error {
+	w := r.NewCommitWalker()
+	w.Order = order
+	for _, sha := range starts {
+		if err := w.Push(sha); err != nil {
+			return err
+		}
+	}
+	for {
+		sha, c, err := w.Next()
+		if err != nil {
+			return err
+		}
+		if sha == "" {
+			return nil
+		}
+		if err := visit(c); err != nil {
+			return err
+		}
+	}
+}
+
+type queuedCommit struct {
+	sha    string
+	commit *object.Commit
+	when   time.Time
+	seq    int
+}
+
+// commitQueue is a heap of commits, newest first. Commits with the same
+// date are returned in the order they were queued.
+type commitQueue []queuedCommit
+
+func (q commitQueue) Len() int { return len(q) }
+
+func (q commitQueue) Less(i, j int) bool {
+	if q[i].when.Equal(q[j].when) {
+		return q[i].seq < q[j].seq
+	}
+	return q[i].when.After(q[j].when)
+}
+
+func (q commitQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *commitQueue) Push(x interface{}) { *q = append(*q, x.(queuedCommit)) }
+
+func (q *commitQueue) Pop() interface{} {
+	old := *q
+	x := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return x
+}
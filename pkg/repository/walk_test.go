@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sboehler/got/pkg/object"
+)
+
+// linearHistory writes n commits, each the parent of the next, and
+// returns their SHAs, oldest first.
+func linearHistory(t *testing.T, r *Repository, n int) []string {
+	t.Helper()
+	var shas []string
+	for i := 0; i < n; i++ {
+		blob := writeBlob(t, r, fmt.Sprintf("version %d\n", i))
+		tree := writeTestTree(t, r, object.TreeEntry{Mode: object.ModeFile, Name: "f", SHA: blob})
+		var parents []string
+		if i > 0 {
+			parents = shas[i-1:]
+		}
+		t.Setenv("GIT_COMMITTER_DATE", fmt.Sprintf("%d +0000", 1600000000+i))
+		shas = append(shas, writeCommit(t, r, tree, "commit\n", parents...))
+	}
+	return shas
+}
+
+func walkAll(t *testing.T, w *CommitWalker) []string {
+	t.Helper()
+	var res []string
+	for {
+		sha, _, err := w.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sha == "" {
+			return res
+		}
+		res = append(res, sha)
+	}
+}
+
+func TestCommitWalkerOrder(t *testing.T) {
+	r := newTestRepo(t)
+	shas := linearHistory(t, r, 5)
+	w := r.NewCommitWalker()
+	if err := w.Push(shas[4]); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Hide(shas[1]); err != nil {
+		t.Fatal(err)
+	}
+	got := walkAll(t, w)
+	want := []string{shas[4], shas[3], shas[2]}
+	if len(got) != len(want) {
+		t.Fatalf("got %d commits, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("commit %d: got %s, want %s", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCommitWalkerDepth(t *testing.T) {
+	r := newTestRepo(t)
+	shas := linearHistory(t, r, 10)
+	// The walk must not read beyond the boundary, so removing the
+	// commit behind it must not matter.
+	hidden := shas[6]
+	if err := os.Remove(filepath.Join(r.ObjectDir(), hidden[:2], hidden[2:])); err != nil {
+		t.Fatal(err)
+	}
+	w := r.NewCommitWalker()
+	w.Depth = 3
+	if err := w.Push(shas[9]); err != nil {
+		t.Fatal(err)
+	}
+	got := walkAll(t, w)
+	want := []string{shas[9], shas[8], shas[7]}
+	if len(got) != len(want) {
+		t.Fatalf("got %d commits, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("commit %d: got %s, want %s", i, got[i], want[i])
+		}
+		if boundary := w.IsBoundary(got[i]); boundary != (i == 2) {
+			t.Errorf("IsBoundary(commit %d) = %v", i, boundary)
+		}
+	}
+}
+
+func TestCommitWalkerPaths(t *testing.T) {
+	r := newTestRepo(t)
+	a1, a2 := writeBlob(t, r, "a1\n"), writeBlob(t, r, "a2\n")
+	b1, b2 := writeBlob(t, r, "b1\n"), writeBlob(t, r, "b2\n")
+	mkTree := func(entries ...object.TreeEntry) string {
+		return writeTestTree(t, r, entries...)
+	}
+	file := func(name, sha string) object.TreeEntry {
+		return object.TreeEntry{Mode: object.ModeFile, Name: name, SHA: sha}
+	}
+	dir := func(name, sha string) object.TreeEntry {
+		return object.TreeEntry{Mode: object.ModeTree, Name: name, SHA: sha}
+	}
+	var shas []string
+	for i, tree := range []string{
+		mkTree(file("a", a1)),
+		mkTree(file("a", a1), file("b", b1)),
+		mkTree(file("a", a2), file("b", b1)),
+		mkTree(file("a", a2), file("b", b2)),
+		mkTree(file("b", b2), dir("d", mkTree(file("b", b1)))),
+		mkTree(file("b", b2), dir("d", mkTree(file("b", b2)))),
+	} {
+		var parents []string
+		if i > 0 {
+			parents = shas[i-1:]
+		}
+		t.Setenv("GIT_COMMITTER_DATE", fmt.Sprintf("%d +0000", 1600000000+i))
+		shas = append(shas, writeCommit(t, r, tree, fmt.Sprintf("commit %d\n", i), parents...))
+	}
+	tests := []struct {
+		paths []string
+		want  []int
+	}{
+		{[]string{"a"}, []int{4, 2, 0}},
+		{[]string{"b"}, []int{3, 1}},
+		{[]string{"d"}, []int{5, 4}},
+		{[]string{"d/b"}, []int{5, 4}},
+		{[]string{"a", "d"}, []int{5, 4, 2, 0}},
+		{[]string{"nosuchfile"}, nil},
+	}
+	for _, test := range tests {
+		w := r.NewCommitWalker()
+		w.Paths = test.paths
+		if err := w.Push(shas[5]); err != nil {
+			t.Fatal(err)
+		}
+		got := walkAll(t, w)
+		var want []string
+		for _, i := range test.want {
+			want = append(want, shas[i])
+		}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("%v: got %v, want %v", test.paths, got, want)
+		}
+	}
+}
+
+func TestCommitWalkerFirstParent(t *testing.T) {
+	r := newTestRepo(t)
+	shas := linearHistory(t, r, 2)
+	side := writeCommit(t, r, writeTestTree(t, r), "side\n")
+	merge := writeCommit(t, r, writeTestTree(t, r), "merge\n", shas[1], side)
+	w := r.NewCommitWalker()
+	w.FirstParent = true
+	if err := w.Push(merge); err != nil {
+		t.Fatal(err)
+	}
+	got := walkAll(t, w)
+	if want := []string{merge, shas[1], shas[0]}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestWalkCommitsTopological(t *testing.T) {
+	r := newTestRepo(t)
+	// a diamond, in which the left side is dated before the root
+	tree := writeTestTree(t, r)
+	commit := func(msg string, date int, parents ...string) string {
+		t.Setenv("GIT_COMMITTER_DATE", fmt.Sprintf("%d +0000", date))
+		return writeCommit(t, r, tree, msg+"\n", parents...)
+	}
+	root := commit("root", 1600000010)
+	left := commit("left", 1600000000, root)
+	right := commit("right", 1600000020, root)
+	top := commit("top", 1600000030, left, right)
+
+	children := map[string][]string{
+		root:  {left, right},
+		left:  {top},
+		right: {top},
+	}
+	shas := map[string]string{"root": root, "left": left, "right": right, "top": top}
+	for _, order := range []Order{Topological, DateOrder} {
+		visited := make(map[string]bool)
+		var got []string
+		err := r.WalkCommits([]string{top}, order, func(c *object.Commit) error {
+			msg := strings.TrimSpace(c.Message())
+			got = append(got, msg)
+			for _, child := range children[shas[msg]] {
+				if !visited[child] && order == Topological {
+					t.Errorf("%s was visited before its child", msg)
+				}
+			}
+			visited[shas[msg]] = true
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 4 {
+			t.Errorf("order %d: got %v, want 4 commits", order, got)
+		}
+		want := map[Order]string{Topological: "top,right,left,root", DateOrder: "top,right,root,left"}[order]
+		if strings.Join(got, ",") != want {
+			t.Errorf("order %d: got %v, want %s", order, got, want)
+		}
+	}
+}
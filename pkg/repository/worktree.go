@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sboehler/got/pkg/index"
+	"github.com/sboehler/got/pkg/object"
+)
+
+// WorktreeFiles returns the paths of the files in the worktree at or
+// below the given path, relative to the worktree root and in sorted
+// order. Files which are ignored and not tracked in the given index are
+// skipped, as are the .git directory and nested repositories. A missing
+// path yields no files.
+func (r *Repository) WorktreeFiles(p string, idx *index.Index) ([]string, error) {
+	if err := r.RequireWorktree(); err != nil {
+		return nil, err
+	}
+	var (
+		res     []string
+		ig      = r.NewIgnorer()
+		root    = filepath.Join(r.Worktree, filepath.FromSlash(p))
+		tracked = make(map[string]bool)
+	)
+	for _, e := range idx.Entries {
+		tracked[r.PathKey(e.Path)] = true
+	}
+	err := filepath.WalkDir(root, func(abs string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && abs == root {
+				return nil
+			}
+			return err
+		}
+		rel, err := filepath.Rel(r.Worktree, abs)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if abs == r.Worktree {
+				return nil
+			}
+			if d.Name() == ".git" || ig.Ignored(rel, true) && !idx.HasDir(rel) {
+				return filepath.SkipDir
+			}
+			if _, err := os.Lstat(filepath.Join(abs, ".git")); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ig.Ignored(rel, false) && !tracked[r.PathKey(rel)] {
+			return nil
+		}
+		res = append(res, rel)
+		return nil
+	})
+	return res, errors.Wrapf(err, "error reading worktree at %s", p)
+}
+
+// UntrackedFiles returns the untracked files in the worktree, relative to
+// the worktree root and in sorted order. Directories without tracked
+// files are returned once with a trailing slash instead of their files,
+// including empty ones. Ignored files are left out unless ignored is
+// set. Directories which contain files that are left out are never
+// returned, and neither are nested repositories or their parents.
+func (r *Repository) UntrackedFiles(idx *index.Index, ignored bool) ([]string, error) {
+	if err := r.RequireWorktree(); err != nil {
+		return nil, err
+	}
+	var (
+		ig      = r.NewIgnorer()
+		tracked = make(map[string]bool)
+		// kept holds the directories which must not be returned
+		kept  = make(map[string]bool)
+		found []string
+	)
+	keepParents := func(p string) {
+		for d := path.Dir(p); d != "." && !kept[d]; d = path.Dir(d) {
+			kept[d] = true
+		}
+	}
+	for _, e := range idx.Entries {
+		tracked[r.PathKey(e.Path)] = true
+		keepParents(e.Path)
+	}
+	err := filepath.WalkDir(r.Worktree, func(abs string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if abs == r.Worktree {
+			return nil
+		}
+		rel, err := filepath.Rel(r.Worktree, abs)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			if _, err := os.Lstat(filepath.Join(abs, ".git")); err == nil {
+				keepParents(rel + "/.git")
+				return filepath.SkipDir
+			}
+			if !ignored && ig.Ignored(rel, true) && !idx.HasDir(rel) {
+				keepParents(rel)
+				return filepath.SkipDir
+			}
+			found = append(found, rel+"/")
+			return nil
+		}
+		if tracked[r.PathKey(rel)] {
+			return nil
+		}
+		if !ignored && ig.Ignored(rel, false) {
+			keepParents(rel)
+			return nil
+		}
+		found = append(found, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading worktree")
+	}
+	var (
+		res  []string
+		seen = make(map[string]bool)
+	)
+	for _, f := range found {
+		if strings.HasSuffix(f, "/") && kept[strings.TrimSuffix(f, "/")] {
+			continue
+		}
+		// the outermost directory which is not kept
+		p := f
+		for d := path.Dir(strings.TrimSuffix(f, "/")); d != "." && !kept[d]; d = path.Dir(d) {
+			p = d + "/"
+		}
+		if !seen[p] {
+			seen[p] = true
+			res = append(res, p)
+		}
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// InPath returns whether the path p is the given path or below it. The
+// empty path contains all paths.
+func InPath(p, dir string) bool {
+	return dir == "" || p == dir || strings.HasPrefix(p, dir+"/")
+}
+
+// StageFile hashes the worktree file at the given path, writes it to the
+// object store and records it in the index, replacing entries which
+// conflict with it in the directory structure. Files whose stat data
+// matches their index entry are not hashed again, unless the entry is
+// racily clean. It returns whether the content or mode of the file
+// differs from its previous index entry.
+func (r *Repository) StageFile(idx *index.Index, p string) (bool, error) {
+	abs := filepath.Join(r.Worktree, filepath.FromSlash(p))
+	info, err := os.Lstat(abs)
+	if err != nil {
+		return false, errors.Wrapf(err, "error adding %s", p)
+	}
+	old, tracked := idx.Entry(p, 0)
+	if tracked && !old.Stale(info) && old.MTime.UnixNano() < r.indexTime() {
+		return false, nil
+	}
+	var data []byte
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(abs)
+		if err != nil {
+			return false, errors.Wrapf(err, "error adding %s", p)
+		}
+		data = []byte(filepath.ToSlash(target))
+	} else {
+		if data, err = os.ReadFile(abs); err != nil {
+			return false, errors.Wrapf(err, "error adding %s", p)
+		}
+		if data, err = r.CleanContent(p, data); err != nil {
+			return false, err
+		}
+	}
+	sha, err := r.WriteObject(&ObjectFile{ObjectType: "blob", Data: object.NewBlob(data).Serialize()})
+	if err != nil {
+		return false, err
+	}
+	for dir := p; strings.Contains(dir, "/"); {
+		dir = dir[:strings.LastIndexByte(dir, '/')]
+		idx.Remove(dir)
+	}
+	idx.RemoveDir(p)
+	e := index.NewEntry(p, sha, info)
+	idx.Add(e)
+	return !tracked || old.SHA != e.SHA || old.Mode != e.Mode, nil
+}
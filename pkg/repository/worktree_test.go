@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sboehler/got/pkg/object"
+)
+
+func TestStageFileRacilyClean(t *testing.T) {
+	r := newTestRepo(t)
+	p := filepath.Join(r.Worktree, "f")
+	// A file modified after the index is written is racily clean when
+	// it is staged.
+	mtime := time.Now().Add(time.Hour)
+	writeFile := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile("aaaa")
+	idx, err := r.ReadIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.StageFile(idx, "f"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.WriteIndex(idx); err != nil {
+		t.Fatal(err)
+	}
+	if idx, err = r.ReadIndex(); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := idx.Entry("f", 0); e.Size != 0 {
+		t.Errorf("got size %d for a racily clean entry, want it smudged to 0", e.Size)
+	}
+
+	// Same size and mtime, different content.
+	writeFile("bbbb")
+	statuses, err := r.Status(idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 1 || statuses[0].Unstaged != 'M' {
+		t.Errorf("got status %+v, want f modified in the worktree", statuses)
+	}
+	changed, err := r.StageFile(idx, "f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Errorf("StageFile did not detect the change of a racily clean file")
+	}
+}
+
+func TestStageFileUnchanged(t *testing.T) {
+	r := newTestRepo(t)
+	p := filepath.Join(r.Worktree, "f")
+	if err := os.WriteFile(p, []byte("content\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(p, old, old); err != nil {
+		t.Fatal(err)
+	}
+	idx, err := r.ReadIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := r.StageFile(idx, "f"); err != nil || !changed {
+		t.Fatalf("StageFile of a new file = %v, %v", changed, err)
+	}
+	if err := r.WriteIndex(idx); err != nil {
+		t.Fatal(err)
+	}
+	if idx, err = r.ReadIndex(); err != nil {
+		t.Fatal(err)
+	}
+	if e, _ := idx.Entry("f", 0); e.Size != 8 {
+		t.Errorf("got size %d, want 8", e.Size)
+	}
+	if changed, err := r.StageFile(idx, "f"); err != nil || changed {
+		t.Errorf("StageFile of an unchanged file = %v, %v", changed, err)
+	}
+}
+
+func TestAutoCRLF(t *testing.T) {
+	r := newTestRepo(t)
+	r.Config.Section("core").Key("autocrlf").SetValue("true")
+	writeWorktreeFile(t, r, "f", "one\r\ntwo\r\n")
+	idx := commitWorktree(t, r, "f")
+	e, _ := idx.Entry("f", 0)
+	if want := writeBlob(t, r, "one\ntwo\n"); e.SHA != want {
+		t.Errorf("got blob %s, want the LF-normalized blob %s", e.SHA, want)
+	}
+	statuses, err := r.Status(idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 0 {
+		t.Errorf("got status %+v for the staged file, want it clean", statuses)
+	}
+
+	tree, err := r.WriteTree(idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := writeTestTree(t, r, object.TreeEntry{Mode: object.ModeFile, Name: "f", SHA: writeBlob(t, r, "one\ntwo\nthree\n")})
+	if err := r.CheckoutTree(idx, tree, next); err != nil {
+		t.Fatal(err)
+	}
+	bs, err := os.ReadFile(filepath.Join(r.Worktree, "f"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bs) != "one\r\ntwo\r\nthree\r\n" {
+		t.Errorf("checked out %q, want CRLF line endings", bs)
+	}
+}
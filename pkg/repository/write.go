@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/natefinch/atomic"
+	"github.com/pkg/errors"
+)
+
+// tempDir returns the directory configured in core.tempdir for temporary
+// files, relative to the git directory. If it is empty, temporary files
+// are created next to their target.
+func (r *Repository) tempDir() string {
+	dir := r.ConfigValue("core", "tempdir")
+	if dir != "" && !filepath.IsAbs(dir) {
+		dir = filepath.Join(r.GitDir, dir)
+	}
+	return dir
+}
+
+// writeFile atomically writes the contents of rd to the file at path p,
+// using a temporary file in core.tempdir if configured. The temporary
+// directory must be on the same filesystem as p, as the file is moved
+// into place by renaming it.
+func (r *Repository) writeFile(p string, rd io.Reader) (err error) {
+	if r.dryRun("write %s", p) {
+		return nil
+	}
+	dir := r.tempDir()
+	if dir == "" {
+		return atomic.WriteFile(p, rd)
+	}
+	f, err := os.CreateTemp(dir, filepath.Base(p))
+	if err != nil {
+		return errors.Wrap(err, "cannot create temp file")
+	}
+	defer func() {
+		f.Close()
+		if err != nil {
+			os.Remove(f.Name())
+		}
+	}()
+	if _, err := io.Copy(f, rd); err != nil {
+		return errors.Wrapf(err, "cannot write temp file %s", f.Name())
+	}
+	if err := f.Sync(); err != nil {
+		return errors.Wrapf(err, "cannot flush temp file %s", f.Name())
+	}
+	if err := f.Close(); err != nil {
+		return errors.Wrapf(err, "cannot close temp file %s", f.Name())
+	}
+	if info, err := os.Stat(p); err == nil {
+		if err := os.Chmod(f.Name(), info.Mode()); err != nil {
+			return errors.Wrapf(err, "cannot set mode of temp file %s", f.Name())
+		}
+	}
+	if err := atomic.ReplaceFile(f.Name(), p); err != nil {
+		if le, ok := err.(*os.LinkError); ok && le.Err == syscall.EXDEV {
+			return fmt.Errorf("core.tempdir %s is not on the same filesystem as %s", dir, p)
+		}
+		return errors.Wrapf(err, "cannot replace %s", p)
+	}
+	return nil
+}
+
+// dryRun reports the given operation on stderr and returns true if the
+// repository is read-only.
+func (r *Repository) dryRun(format string, args ...interface{}) bool {
+	if r.ReadOnly {
+		fmt.Fprintf(os.Stderr, "would "+format+"\n", args...)
+	}
+	return r.ReadOnly
+}
+
+// WriteWorktreeFile writes data to the file at the given path relative
+// to the worktree root, creating missing directories.
+func (r *Repository) WriteWorktreeFile(p string, data []byte) error {
+	if err := r.RequireWorktree(); err != nil {
+		return err
+	}
+	if r.dryRun("write %s", p) {
+		return nil
+	}
+	abs := filepath.Join(r.Worktree, filepath.FromSlash(p))
+	if err := os.MkdirAll(filepath.Dir(abs), dirperms); err != nil {
+		return errors.Wrapf(err, "error writing %s", p)
+	}
+	return errors.Wrapf(atomic.WriteFile(abs, bytes.NewReader(data)), "error writing %s", p)
+}
+
+// RemoveWorktreeFile removes the file at the given path relative to the
+// worktree root.
+func (r *Repository) RemoveWorktreeFile(p string) error {
+	if err := r.RequireWorktree(); err != nil {
+		return err
+	}
+	if r.dryRun("remove %s", p) {
+		return nil
+	}
+	return errors.Wrapf(os.Remove(filepath.Join(r.Worktree, filepath.FromSlash(p))), "error removing %s", p)
+}
@@ -0,0 +1,95 @@
+// Package timefmt implements git's timestamp format.
+package timefmt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format formats the time as git does in commit and tag headers and
+// reflogs: seconds since the epoch followed by the signed zone offset,
+// for example "1650000000 +0200".
+func Format(t time.Time) string {
+	_, offset := t.Zone()
+	sign := '+'
+	if offset < 0 {
+		sign, offset = '-', -offset
+	}
+	return fmt.Sprintf("%d %c%02d%02d", t.Unix(), sign, offset/3600, offset/60%60)
+}
+
+// Parse parses a date as accepted by git in GIT_AUTHOR_DATE and
+// GIT_COMMITTER_DATE: git's own format as accepted by ParseRaw, seconds
+// since the epoch prefixed with @, which are in UTC, RFC 2822
+// dates such as "Thu, 07 Apr 2005 22:13:13 +0200", and ISO 8601 dates
+// such as "2005-04-07T22:13:13+02:00" or "2005-04-07 22:13:13 +0200".
+// Dates without a zone are in the local time zone.
+func Parse(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := ParseRaw(strings.TrimPrefix(s, "@")); err == nil {
+		return t, nil
+	}
+	if strings.HasPrefix(s, "@") {
+		if unix, err := strconv.ParseInt(s[1:], 10, 64); err == nil {
+			return time.Unix(unix, 0).UTC(), nil
+		}
+	}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+// layouts are the RFC 2822 and ISO 8601 layouts accepted by Parse.
+var layouts = []string{
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 Z07:00",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// ParseRaw parses a timestamp in git's format. The returned time is in a
+// fixed zone with the given offset.
+func ParseRaw(s string) (time.Time, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+	}
+	unix, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+	}
+	offset, err := parseOffset(fields[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q: %v", s, err)
+	}
+	return time.Unix(unix, 0).In(time.FixedZone("", offset)), nil
+}
+
+// parseOffset parses a zone offset of the form ±hhmm into seconds.
+func parseOffset(s string) (int, error) {
+	if len(s) != 5 || s[0] != '+' && s[0] != '-' {
+		return 0, fmt.Errorf("invalid zone offset %q", s)
+	}
+	hh, err1 := strconv.Atoi(s[1:3])
+	mm, err2 := strconv.Atoi(s[3:])
+	if err1 != nil || err2 != nil || mm >= 60 {
+		return 0, fmt.Errorf("invalid zone offset %q", s)
+	}
+	offset := hh*3600 + mm*60
+	if s[0] == '-' {
+		offset = -offset
+	}
+	return offset, nil
+}
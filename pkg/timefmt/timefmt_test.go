@@ -0,0 +1,49 @@
+package timefmt
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		date, want string
+	}{
+		{"1112904793 +0200", "1112904793 +0200"},
+		{"@1112904793 -0130", "1112904793 -0130"},
+		{"@1112904793", "1112904793 +0000"},
+		{"Thu, 07 Apr 2005 22:13:13 +0200", "1112904793 +0200"},
+		{"Thu, 7 Apr 2005 22:13:13 +0200", "1112904793 +0200"},
+		{"7 Apr 2005 22:13:13 -0700", "1112937193 -0700"},
+		{"2005-04-07T22:13:13+02:00", "1112904793 +0200"},
+		{"2005-04-07T20:13:13Z", "1112904793 +0000"},
+		{"2005-04-07T22:13:13+0200", "1112904793 +0200"},
+		{"2005-04-07 22:13:13 +0200", "1112904793 +0200"},
+		{" 2005-04-07 22:13:13 +0200 ", "1112904793 +0200"},
+	}
+	for _, test := range tests {
+		got, err := Parse(test.date)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", test.date, err)
+			continue
+		}
+		if Format(got) != test.want {
+			t.Errorf("Parse(%q) = %s, want %s", test.date, Format(got), test.want)
+		}
+	}
+	for _, date := range []string{"", "yesterday", "1112904793", "1112904793 +02", "2005-13-07T22:13:13Z"} {
+		if _, err := Parse(date); err == nil {
+			t.Errorf("Parse(%q) succeeded", date)
+		}
+	}
+}
+
+func TestParseRaw(t *testing.T) {
+	if _, err := ParseRaw("2005-04-07 22:13:13 +0200"); err == nil {
+		t.Errorf("ParseRaw accepted an ISO 8601 date")
+	}
+	got, err := ParseRaw("1112904793 +0200")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Format(got) != "1112904793 +0200" {
+		t.Errorf("got %s", Format(got))
+	}
+}